@@ -2,27 +2,26 @@ package core
 
 import (
 	"bytes"
-	"encoding/json"
-	"fmt"
+	"errors"
 	"io"
 	"net/http"
 	"reflect"
-	"regexp"
 	"strings"
-
-	"github.com/go-playground/validator"
 )
 
 type optionalParams struct {
-	haveUrlParam bool
-	urlPattern   string
-	urlParamKeys []string
+	urlParams      map[string]string
+	allowedMethods []string
+	// Headers which are added by router before handler, ex: Vary and version headers of versioned api
+	responseHeader http.Header
 }
 
 type Route struct {
-	URL     Url
-	Method  string
-	handler func(writer http.ResponseWriter, request *http.Request, optional optionalParams)
+	URL         Url
+	Method      string
+	handler     func(writer http.ResponseWriter, request *http.Request, optional optionalParams)
+	config      *RouteConfig
+	requestType reflect.Type
 }
 
 type Url struct {
@@ -34,22 +33,14 @@ type ApiMiddleware func(ctx *HttpContext) HttpError
 
 type Handler[T any] func(ctx *HttpContext, request T) (HttpResponse, HttpError)
 
-var urlRegex = regexp.MustCompile(`.*[{].*[}].*`)
-
 /*
-* Register api: register api to routeMap
+* Register api: register api to router
 * @param url: url of api
 * @param handler: handler of api
 * @param middleware: middleware of api
-* @return void
+* @return *RouteConfig: optional settings of api
  */
-func RegisterAPI[T any](url string, method string, handler Handler[T], middlewares ...ApiMiddleware) {
-	var isRegexPath = false
-	var urlParams []string
-	if urlRegex.MatchString(url) {
-		url, urlParams = convertRegexUrl(url)
-		isRegexPath = true
-	}
+func RegisterAPI[T any](url string, method string, handler Handler[T], middlewares ...ApiMiddleware) *RouteConfig {
 	LogInfo("Register api: %s %s", method, url)
 
 	// Check if T is a struct
@@ -57,134 +48,185 @@ func RegisterAPI[T any](url string, method string, handler Handler[T], middlewar
 	if tType.Kind() != reflect.Struct {
 		LogFatal("Handler request parameter must be a struct, got: %s", tType.Kind())
 	}
-	// Create a new handler
-	h := func(writer http.ResponseWriter, request *http.Request, optional optionalParams) {
-		// Create a new context
-		ctx := getHttpContext()
-		defer putHttpContext(ctx)
-		buildContext(ctx, writer, request)
-
-		// Append to common middleware
-		middlewareList := []ApiMiddleware{}
-		middlewareList = append(middlewareList, commonApiMiddlewares...)
-		middlewareList = append(middlewareList, middlewares...)
-
-		// Call middleware of function
-		for _, middleware := range middlewareList {
-			ctx.isRequestEnd = true
-			if err := middleware(ctx); ctx.isRequestEnd {
-				if err != nil {
-					ctx.writeError(err)
-				}
+	config := newRouteConfig()
+	addRoute(url, method, newApiHandler(tType, handler, middlewares, config), config, tType)
+	return config
+}
+
+/*
+* newApiHandler: create handler which builds context, executes middlewares, decodes request and calls handler of api
+* @param tType: type of request struct
+* @param handler: handler of api
+* @param middlewares: middlewares of api, they are executed after common middlewares
+* @param config: optional settings of api
+ */
+func newApiHandler[T any](tType reflect.Type, handler Handler[T], middlewares []ApiMiddleware, config *RouteConfig) func(writer http.ResponseWriter, request *http.Request, optional optionalParams) {
+	binder := newRequestBinder(tType)
+
+	return func(writer http.ResponseWriter, request *http.Request, optional optionalParams) {
+		serveWithTimeout(writer, config.timeout, func(ctx *HttpContext, writer http.ResponseWriter) {
+			var errBuild HttpError
+			if config.streamBody {
+				errBuild = buildStreamContext(ctx, writer, request, config.getMaxBodySize())
+			} else {
+				errBuild = buildContextWithLimit(ctx, writer, request, config.getMaxBodySize())
+			}
+
+			ctx.allowedMethods = optional.allowedMethods
+			if optional.urlParams != nil {
+				ctx.urlParams = optional.urlParams
+			}
+			ctx.addRouterHeaders(optional.responseHeader)
+
+			if errBuild != nil {
+				ctx.writeError(errBuild)
 				return
 			}
-		}
 
-		if optional.haveUrlParam {
-			// Init url params map
-			ctx.urlParams = make(map[string]string)
-			// convert param
-			ctx.convertUrlParams(optional.urlPattern, request.URL.Path, optional.urlParamKeys)
-		}
+			// Append to common middleware
+			middlewareList := []ApiMiddleware{}
+			middlewareList = append(middlewareList, commonApiMiddlewares...)
+			middlewareList = append(middlewareList, middlewares...)
 
-		// Unmarshal json request body to model T
-		req := initRequest[T]()
-		requestContentType := strings.ToLower(ctx.GetRequestHeader(CONTENT_TYPE_KEY))
-		if len(ctx.requestBody) != 0 {
-			if strings.Contains(requestContentType, JSON_CONTENT_TYPE) {
-				if err := json.Unmarshal(ctx.requestBody, &req); err != nil {
-					LogInfo("Unmarshal request body fail. RequestId: %s, Error: %s", ctx.requestID, err.Error())
-					ctx.writeError(NewDefaultHttpError(400, "Bad request (Marshal requeset body)"))
-					return
-				}
-			} else if strings.Contains(requestContentType, FORM_URLENCODED_CONTENT_TYPE) {
-				buffer := bytes.NewBuffer(ctx.requestBody)
-				ctx.request.Body = io.NopCloser(buffer)
-				ctx.request.ParseForm()
+			// Call middleware of function
+			if executeApiMiddlewares(ctx, middlewareList) {
+				return
 			}
-		}
 
-		// Validate go struct with tag
-		errValidate := validate.StructCtx(ctx, req)
-		if errValidate != nil {
-			errMessage := "Request invalid: "
-			for _, err := range errValidate.(validator.ValidationErrors) {
-				errMessage = fmt.Sprintf("%s {Field: %s, Tag: %s, Value: %s}", errMessage, err.Field(), err.Tag(), err.Value())
+			// Unmarshal request body to model T by codec of content type
+			req := initRequest[T]()
+			requestContentType := strings.ToLower(ctx.GetRequestHeader(CONTENT_TYPE_KEY))
+			if len(ctx.requestBody) != 0 {
+				if codec := GetCodec(requestContentType); codec != nil {
+					if err := codec.Unmarshal(ctx.requestBody, &req); err != nil {
+						LogInfo("Unmarshal request body fail. RequestId: %s, Error: %s", ctx.requestID, err.Error())
+						ctx.writeError(NewDefaultHttpError(400, "Bad request (Marshal requeset body)"))
+						return
+					}
+				} else if strings.Contains(requestContentType, FORM_URLENCODED_CONTENT_TYPE) {
+					buffer := bytes.NewBuffer(ctx.requestBody)
+					ctx.request.Body = io.NopCloser(buffer)
+					ctx.request.ParseForm()
+				} else if strings.Contains(requestContentType, MULTIPART_FORM_DATA_CONTENT_TYPE) && binder.hasSource(BINDING_TAG_FORM) {
+					buffer := bytes.NewBuffer(ctx.requestBody)
+					ctx.request.Body = io.NopCloser(buffer)
+					ctx.request.ParseMultipartForm(MAX_UPLOAD_FILE_SIZE)
+				}
 			}
-			ctx.writeError(NewHttpError(http.StatusBadRequest, ERROR_BAD_BODY_REQUEST, errMessage, nil))
-			return
-		}
 
-		// Call handler
-		requestBody := strings.ReplaceAll(string(ctx.requestBody), "\r", "")
-		requestBody = strings.ReplaceAll(requestBody, "\n", "")
+			// Bind query, path, header and form values to model T
+			if err := binder.bind(ctx, reflect.ValueOf(&req).Elem()); err != nil {
+				ctx.writeError(err)
+				return
+			}
 
-		ctx.LogInfo("Request: Url = %s, method = %s, header = %#v, body = %s", request.URL.String(), ctx.Method, ctx.request.Header, requestBody)
-		res, err := handler(ctx, req)
-		if err != nil {
-			ctx.LogError("Response error: Url = %s, body = %s", ctx.URL, err.Error())
-			ctx.writeError(err)
-			return
-		}
+			// Validate go struct with tag
+			if err := validateRequest(ctx, req); err != nil {
+				ctx.writeError(err)
+				return
+			}
 
-		if res != nil {
-			ctx.LogInfo("Response: Url = %s, body = %+v", ctx.URL, res.GetBody())
-			ctx.writeSuccess(res)
-		} else {
-			ctx.writeDefaultSuccess()
-		}
-	}
+			// Call handler
+			requestBody := strings.ReplaceAll(string(ctx.requestBody), "\r", "")
+			requestBody = strings.ReplaceAll(requestBody, "\n", "")
 
-	if !isRegexPath {
-		routeSlice, ok := routeMap[url]
-		if ok {
-			routeSlice = append(routeSlice, Route{
-				Method: method,
-				URL: Url{
-					Path:   url,
-					Params: nil,
-				},
-				handler: h,
-			})
-			routeMap[url] = routeSlice
-		} else {
-			routeMap[url] = []Route{
-				{
-					Method: method,
-					URL: Url{
-						Path:   url,
-						Params: nil,
-					},
-					handler: h,
-				},
+			ctx.LogInfo("Request: Url = %s, method = %s, header = %#v, body = %s", request.URL.String(), ctx.Method, ctx.request.Header, requestBody)
+			res, err := handler(ctx, req)
+			if err != nil {
+				ctx.LogError("Response error: Url = %s, body = %s", ctx.URL, err.Error())
+				ctx.writeError(err)
+				return
 			}
-		}
-	} else {
-		routeSlice, ok := routeRegexMap[url]
-		if ok {
-			routeSlice = append(routeSlice, Route{
-				Method: method,
-				URL: Url{
-					Path:   url,
-					Params: urlParams,
-				},
-
-				handler: h,
-			})
-			routeRegexMap[url] = routeSlice
-		} else {
-			routeRegexMap[url] = []Route{
-				{
-					Method: method,
-					URL: Url{
-						Path:   url,
-						Params: urlParams,
-					},
-					handler: h,
-				},
+
+			if stream, ok := res.(*StreamResponse); ok {
+				ctx.LogInfo("Stream response: Url = %s, content type = %s", ctx.URL, stream.GetResponseContentType())
+				ctx.writeStream(stream)
+			} else if res != nil {
+				ctx.LogInfo("Response: Url = %s, body = %+v", ctx.URL, res.GetBody())
+				ctx.writeSuccess(res)
+			} else {
+				ctx.writeDefaultSuccess()
 			}
+		})
+	}
+}
+
+/*
+* addRoute: add handler of method into entry of url in router
+ */
+func addRoute(url string, method string, handler func(writer http.ResponseWriter, request *http.Request, optional optionalParams), config *RouteConfig, requestType reflect.Type) {
+	entry := router.insert(url)
+	entry.routes = append(entry.routes, Route{
+		Method: method,
+		URL: Url{
+			Path:   url,
+			Params: entry.paramKeys,
+		},
+		handler:     handler,
+		config:      config,
+		requestType: requestType,
+	})
+}
+
+/*
+* buildStreamContext: assign request to context without reading request body
+* Body is read by handler through ctx.GetBodyReader()
+ */
+func buildStreamContext(ctx *HttpContext, writer http.ResponseWriter, request *http.Request, maxBodySize int64) HttpError {
+	ctx.rw = writer
+	ctx.request = request
+	ctx.URL = request.URL
+	ctx.Method = request.Method
+	ctx.requestBody = ctx.requestBody[:0]
+
+	if maxBodySize >= 0 {
+		if request.ContentLength > maxBodySize {
+			LogInfo("Request body too large. RequestId: %s, ContentLength: %d", ctx.requestID, request.ContentLength)
+			return HTTP_ERROR_REQUEST_BODY_TOO_LARGE
 		}
+		request.Body = http.MaxBytesReader(writer, request.Body, maxBodySize)
+	}
+
+	ctx.bodyReader = request.Body
+	return nil
+}
+
+/*
+* handleOptions: answer OPTIONS request of a url which has no OPTIONS route
+* Common middlewares are executed, so cors middleware can answer preflight request
+ */
+func handleOptions(writer http.ResponseWriter, request *http.Request, optional optionalParams) {
+	ctx := getHttpContext()
+	defer putHttpContext(ctx)
+	if err := buildContext(ctx, writer, request); err != nil {
+		ctx.writeError(err)
+		return
+	}
+	ctx.allowedMethods = optional.allowedMethods
+	ctx.rw.Header().Set(ALLOW_KEY, strings.Join(optional.allowedMethods, ", "))
+
+	if executeApiMiddlewares(ctx, commonApiMiddlewares) {
+		return
+	}
+
+	ctx.rw.Header().Set("Request-Id", ctx.requestID)
+	ctx.endResponse(http.StatusNoContent, BLANK)
+}
+
+/*
+* handleMethodNotAllowed: answer 405 with Allow header listing the registered methods of url
+ */
+func handleMethodNotAllowed(writer http.ResponseWriter, request *http.Request, optional optionalParams) {
+	ctx := getHttpContext()
+	defer putHttpContext(ctx)
+	if err := buildContext(ctx, writer, request); err != nil {
+		ctx.writeError(err)
+		return
 	}
+	ctx.SetResponseHeader(ALLOW_KEY, []string{strings.Join(optional.allowedMethods, ", ")})
+
+	ctx.LogInfo("Method not allowed: Url = %s, method = %s", request.URL.String(), request.Method)
+	ctx.writeError(HTTP_ERROR_METHOD_NOT_ALLOWED)
 }
 
 func initRequest[T any]() T {
@@ -194,6 +236,14 @@ func initRequest[T any]() T {
 }
 
 func buildContext(ctx *HttpContext, writer http.ResponseWriter, request *http.Request) HttpError {
+	return buildContextWithLimit(ctx, writer, request, Config.GetMaxBodySize())
+}
+
+/*
+* buildContextWithLimit: assign request to context and read request body which is not bigger than maxBodySize
+* Negative maxBodySize means no limit
+ */
+func buildContextWithLimit(ctx *HttpContext, writer http.ResponseWriter, request *http.Request, maxBodySize int64) HttpError {
 	// Assign response writer and request
 	ctx.rw = writer
 	ctx.request = request
@@ -202,11 +252,25 @@ func buildContext(ctx *HttpContext, writer http.ResponseWriter, request *http.Re
 	ctx.URL = request.URL
 	ctx.Method = request.Method
 
+	if maxBodySize >= 0 {
+		if request.ContentLength > maxBodySize {
+			LogInfo("Request body too large. RequestId: %s, ContentLength: %d", ctx.requestID, request.ContentLength)
+			return HTTP_ERROR_REQUEST_BODY_TOO_LARGE
+		}
+		request.Body = http.MaxBytesReader(writer, request.Body, maxBodySize)
+	}
+
 	// Get request body
 	buffer := bytes.NewBuffer(ctx.requestBody)
 	buffer.Reset()
 
 	if _, err := io.Copy(buffer, request.Body); err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			LogInfo("Request body too large. RequestId: %s, Limit: %d", ctx.requestID, maxBytesError.Limit)
+			return HTTP_ERROR_REQUEST_BODY_TOO_LARGE
+		}
+
 		LogError("Read request body fail. RequestId: %s, Error: %s", ctx.requestID, err.Error())
 		return HTTP_ERROR_READ_BODY_REQUEST_FAIL
 	}
@@ -219,30 +283,3 @@ func buildContext(ctx *HttpContext, writer http.ResponseWriter, request *http.Re
 	ctx.requestBody = buffer.Bytes()
 	return nil
 }
-
-func convertRegexUrl(url string) (string, []string) {
-	params := make([]string, 0)
-	param := BLANK
-	// Convert url
-	newUrl := "^"
-	start := false
-	for _, letter := range url {
-		if letter == '{' && !start {
-			newUrl += REGEX_URL_PATH_ELEMENT
-			start = !start
-			continue
-		} else if letter == '}' && start {
-			start = !start
-			params = append(params, param)
-			param = BLANK
-			continue
-		}
-
-		if !start {
-			newUrl += string(letter)
-		} else {
-			param += string(letter)
-		}
-	}
-	return newUrl + "$", params
-}
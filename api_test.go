@@ -0,0 +1,244 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+type testDispatchRequest struct{}
+
+func testDispatchHandler(ctx *HttpContext, request testDispatchRequest) (HttpResponse, HttpError) {
+	return NewDefaultHttpResponse("ok"), nil
+}
+
+func TestDispatchRequest_MethodNotAllowed(t *testing.T) {
+	RegisterAPI("/test/dispatch/not-allowed", http.MethodGet, testDispatchHandler)
+	RegisterAPI("/test/dispatch/not-allowed", http.MethodPut, testDispatchHandler)
+
+	recorder := httptest.NewRecorder()
+	dispatchRequest(recorder, httptest.NewRequest(http.MethodPost, "/test/dispatch/not-allowed", nil))
+
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", recorder.Code)
+	}
+
+	if allow := recorder.Header().Get(ALLOW_KEY); allow != "GET, PUT, HEAD, OPTIONS" {
+		t.Errorf("Expected Allow = GET, PUT, HEAD, OPTIONS, got %s", allow)
+	}
+}
+
+func TestDispatchRequest_HeadIsServedByGet(t *testing.T) {
+	RegisterAPI("/test/dispatch/head", http.MethodGet, testDispatchHandler)
+
+	recorder := httptest.NewRecorder()
+	dispatchRequest(recorder, httptest.NewRequest(http.MethodHead, "/test/dispatch/head", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", recorder.Code)
+	}
+}
+
+func TestDispatchRequest_AutomaticOptions(t *testing.T) {
+	RegisterAPI("/test/dispatch/options/{id}", http.MethodDelete, testDispatchHandler)
+
+	recorder := httptest.NewRecorder()
+	dispatchRequest(recorder, httptest.NewRequest(http.MethodOptions, "/test/dispatch/options/1", nil))
+
+	if recorder.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", recorder.Code)
+	}
+
+	if allow := recorder.Header().Get(ALLOW_KEY); allow != "DELETE, OPTIONS" {
+		t.Errorf("Expected Allow = DELETE, OPTIONS, got %s", allow)
+	}
+}
+
+func TestDispatchRequest_Timeout(t *testing.T) {
+	finished := make(chan struct{})
+	RegisterAPI("/test/dispatch/timeout", http.MethodGet, func(ctx *HttpContext, request testDispatchRequest) (HttpResponse, HttpError) {
+		defer close(finished)
+		time.Sleep(100 * time.Millisecond)
+		return NewDefaultHttpResponse("late"), nil
+	}).SetTimeout(20 * time.Millisecond)
+
+	recorder := httptest.NewRecorder()
+	dispatchRequest(recorder, httptest.NewRequest(http.MethodGet, "/test/dispatch/timeout", nil))
+
+	if recorder.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected status 504, got %d", recorder.Code)
+	}
+
+	var body responseBody
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil || body.Code != ERROR_CODE_TIMEOUT {
+		t.Errorf("Expected timeout error body, got %s", recorder.Body.String())
+	}
+
+	// Late response of handler is discarded
+	<-finished
+	time.Sleep(10 * time.Millisecond)
+	if strings.Contains(recorder.Body.String(), "late") {
+		t.Errorf("Expected late response is discarded, got %s", recorder.Body.String())
+	}
+}
+
+func TestDispatchRequest_OptionsAndMethodNotAllowedBodyError(t *testing.T) {
+	RegisterAPI("/test/dispatch/body-error", http.MethodDelete, testDispatchHandler)
+
+	// Error of reading body is answered instead of 204 or 405
+	for _, method := range []string{http.MethodOptions, http.MethodPost} {
+		recorder := httptest.NewRecorder()
+		dispatchRequest(recorder, httptest.NewRequest(method, "/test/dispatch/body-error", iotest.ErrReader(errors.New("broken body"))))
+
+		if status := int(HTTP_ERROR_READ_BODY_REQUEST_FAIL.GetStatusCode()); recorder.Code != status {
+			t.Errorf("%s: expected status %d, got %d", method, status, recorder.Code)
+		}
+	}
+}
+
+func TestTimeoutWriter_MergeHeader(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	recorder.Header().Set("Vary", "Accept-Version")
+	recorder.Header().Set(CONTENT_TYPE_KEY, CONTENT_TYPE_TEXT)
+
+	tw := newTimeoutWriter(recorder)
+	tw.Header().Add("Vary", ACCEPT_KEY)
+	tw.Header().Add("Vary", "Accept-Version")
+	tw.Header().Set(CONTENT_TYPE_KEY, JSON_CONTENT_TYPE)
+	tw.WriteHeader(http.StatusOK)
+
+	if vary := recorder.Header().Values("Vary"); strings.Join(vary, ", ") != "Accept-Version, Accept" {
+		t.Errorf("Expected Vary = Accept-Version, Accept, got %v", vary)
+	}
+	if contentType := recorder.Header().Get(CONTENT_TYPE_KEY); contentType != JSON_CONTENT_TYPE {
+		t.Errorf("Expected Content-Type of handler, got %s", contentType)
+	}
+}
+
+func TestDispatchRequest_TimeoutKeepsHeaders(t *testing.T) {
+	finished := make(chan struct{})
+	trace := func(ctx *HttpContext) HttpError {
+		ctx.rw.Header().Set("X-Trace-Id", "trace")
+		ctx.rw.Header().Set("X-Handler", "handler")
+		ctx.keepHeadersOnTimeout("X-Trace-Id")
+		ctx.Next()
+		return nil
+	}
+	RegisterAPI("/test/dispatch/timeout/headers", http.MethodGet, func(ctx *HttpContext, request testDispatchRequest) (HttpResponse, HttpError) {
+		defer close(finished)
+		time.Sleep(100 * time.Millisecond)
+		return NewDefaultHttpResponse("late"), nil
+	}, trace).SetTimeout(20 * time.Millisecond)
+
+	recorder := httptest.NewRecorder()
+	dispatchRequest(recorder, httptest.NewRequest(http.MethodGet, "/test/dispatch/timeout/headers", nil))
+	<-finished
+
+	// Only kept headers are written with timeout error
+	if recorder.Code != http.StatusGatewayTimeout || recorder.Header().Get("X-Trace-Id") != "trace" || recorder.Header().Get("X-Handler") != BLANK {
+		t.Errorf("Expected 504 with kept header only, got %d %v", recorder.Code, recorder.Header())
+	}
+}
+
+func TestDispatchRequest_RecoverPanic(t *testing.T) {
+	RegisterAPI("/test/dispatch/panic", http.MethodGet, func(ctx *HttpContext, request testDispatchRequest) (HttpResponse, HttpError) {
+		panic("unexpected")
+	})
+
+	panicCount := GetMetric(METRIC_HTTP_PANIC)
+	recorder := httptest.NewRecorder()
+	dispatchRequest(recorder, httptest.NewRequest(http.MethodGet, "/test/dispatch/panic", nil))
+
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", recorder.Code)
+	}
+
+	var body responseBody
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil || body.Code != ERROR_CODE_INTERNAL_SERVER_ERROR {
+		t.Errorf("Expected internal server error body, got %s", recorder.Body.String())
+	}
+
+	if GetMetric(METRIC_HTTP_PANIC) != panicCount+1 {
+		t.Errorf("Expected panic metric is increased")
+	}
+}
+
+func TestDispatchRequest_AbortHandler(t *testing.T) {
+	RegisterAPI("/test/dispatch/abort", http.MethodGet, func(ctx *HttpContext, request testDispatchRequest) (HttpResponse, HttpError) {
+		panic(http.ErrAbortHandler)
+	})
+	RegisterPage("/test/dispatch/abort-page", func(ctx *HttpContext, request *PageRequest) (PageResponse, Error) {
+		panic(http.ErrAbortHandler)
+	})
+
+	for _, url := range []string{"/test/dispatch/abort", "/test/dispatch/abort-page"} {
+		panicCount := GetMetric(METRIC_HTTP_PANIC)
+		recorder := httptest.NewRecorder()
+		func() {
+			defer func() {
+				if p := recover(); p != http.ErrAbortHandler {
+					t.Errorf("%s: expected http.ErrAbortHandler to reach net/http, got %v", url, p)
+				}
+			}()
+			dispatchRequest(recorder, httptest.NewRequest(http.MethodGet, url, nil))
+		}()
+
+		if recorder.Body.Len() != 0 || GetMetric(METRIC_HTTP_PANIC) != panicCount {
+			t.Errorf("%s: expected no error response and no panic metric, got %d %s", url, recorder.Code, recorder.Body.String())
+		}
+	}
+}
+
+func TestDispatchRequest_BodyTooLarge(t *testing.T) {
+	RegisterAPI("/test/dispatch/body-limit", http.MethodPost, testDispatchHandler).SetMaxBodySize(8)
+
+	request := httptest.NewRequest(http.MethodPost, "/test/dispatch/body-limit", strings.NewReader(`{"name":"too large"}`))
+	request.Header.Set(CONTENT_TYPE_KEY, JSON_CONTENT_TYPE)
+	recorder := httptest.NewRecorder()
+	dispatchRequest(recorder, request)
+
+	if recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413, got %d", recorder.Code)
+	}
+
+	// Body without content length is stopped while reading
+	request = httptest.NewRequest(http.MethodPost, "/test/dispatch/body-limit", strings.NewReader(`{"name":"too large"}`))
+	request.ContentLength = -1
+	recorder = httptest.NewRecorder()
+	dispatchRequest(recorder, request)
+
+	if recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413 when content length is unknown, got %d", recorder.Code)
+	}
+}
+
+func TestDispatchRequest_StreamBody(t *testing.T) {
+	var lines []string
+	RegisterAPI("/test/dispatch/stream-body", http.MethodPost, func(ctx *HttpContext, request testDispatchRequest) (HttpResponse, HttpError) {
+		data, err := io.ReadAll(ctx.GetBodyReader())
+		if err != nil {
+			return nil, HTTP_ERROR_READ_BODY_REQUEST_FAIL
+		}
+		lines = strings.Split(strings.TrimSpace(string(data)), "\n")
+		return nil, nil
+	}).SetStreamBody()
+
+	request := httptest.NewRequest(http.MethodPost, "/test/dispatch/stream-body", strings.NewReader("{\"id\":1}\n{\"id\":2}\n"))
+	request.Header.Set(CONTENT_TYPE_KEY, "application/x-ndjson")
+	recorder := httptest.NewRecorder()
+	dispatchRequest(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", recorder.Code)
+	}
+
+	if len(lines) != 2 {
+		t.Errorf("Expected 2 lines, got %v", lines)
+	}
+}
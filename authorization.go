@@ -0,0 +1,310 @@
+package core
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Key of role id in temp data, authentication middleware which does not use JwtAuth sets it
+const ROLE_ID_KEY = "core.role_id"
+
+// Permission which grants all permissions
+const PERMISSION_ALL = "*"
+
+// Permissions of role are loaded from provider again after this time
+const DEFAULT_PERMISSION_CACHE_TIME = 60
+
+/*
+* PermissionProvider: source of permissions and role hierarchy
+* Role has its own permissions and permissions of all parent roles
+ */
+type PermissionProvider interface {
+	// GetPermissions: permissions which are granted directly to role
+	GetPermissions(ctx Context, roleID int64) ([]string, Error)
+	// GetParentRoles: roles which role inherits permissions from
+	GetParentRoles(ctx Context, roleID int64) ([]int64, Error)
+}
+
+/*
+* rolePermissions: permissions of role and its ancestors
+ */
+type rolePermissions struct {
+	permissions map[string]bool
+	loadedAt    time.Time
+}
+
+/*
+* permissionCache: resolved permissions by role id
+ */
+type permissionCache struct {
+	mu       sync.RWMutex
+	provider PermissionProvider
+	roles    map[int64]rolePermissions
+}
+
+var permissionStore = &permissionCache{
+	roles: make(map[int64]rolePermissions),
+}
+
+/*
+* SetPermissionProvider: replace the database permission provider
+* Cached permissions are cleared
+ */
+func SetPermissionProvider(provider PermissionProvider) {
+	permissionStore.mu.Lock()
+	defer permissionStore.mu.Unlock()
+	permissionStore.provider = provider
+	permissionStore.roles = make(map[int64]rolePermissions)
+}
+
+/*
+* InvalidatePermissionCache: load permissions from provider at next check
+* It is called after permissions or role hierarchy are changed
+ */
+func InvalidatePermissionCache() {
+	permissionStore.mu.Lock()
+	defer permissionStore.mu.Unlock()
+	permissionStore.roles = make(map[int64]rolePermissions)
+}
+
+/*
+* RequirePermissions: api middleware which answers 403 if role of request lacks any permission
+* Role is taken from ROLE_ID_KEY temp data or jwt claims, so it is placed after authentication middleware
+* Ex: RegisterAPI("/users", http.MethodPost, createUser, jwtAuth.Middleware(), RequirePermissions("user.create"))
+* @param required: permissions, "user.*" grants all permissions which start with "user."
+* @return ApiMiddleware
+ */
+func RequirePermissions(required ...string) ApiMiddleware {
+	return func(ctx *HttpContext) HttpError {
+		roleID, ok := getRoleID(ctx)
+		if !ok {
+			ctx.LogInfo("Request has no role: url = %s", ctx.URL)
+			return HTTP_ERROR_UNAUTHORIZED
+		}
+
+		if err := checkPermissions(ctx, roleID, required); err != nil {
+			return err
+		}
+		ctx.Next()
+		return nil
+	}
+}
+
+/*
+* RequirePagePermissions: page middleware which checks permissions of RoleID in page request
+* Page is redirected to forbidden_url of authorization config, or answered with 403 if it is not set
+* Ex: RegisterPage("/admin", adminPage, jwtAuth.PageMiddleware(), RequirePagePermissions("admin.view"))
+* @param required: permissions of page
+* @return PageMiddleware
+ */
+func RequirePagePermissions(required ...string) PageMiddleware {
+	return func(ctx *HttpContext, request *PageRequest) Error {
+		roleID := request.RoleID
+		if roleID == 0 {
+			roleID, _ = getRoleID(ctx)
+		}
+
+		var err HttpError = HTTP_ERROR_FORBIDDEN
+		if roleID != 0 {
+			err = checkPermissions(ctx, roleID, required)
+		}
+		if err == nil {
+			return nil
+		}
+
+		if Config.Authorization.ForbiddenUrl != BLANK && err.GetStatusCode() == http.StatusForbidden {
+			ctx.RedirectURL(Config.Authorization.ForbiddenUrl)
+		} else {
+			ctx.writeError(err)
+		}
+		return NewError(err.GetCode(), err.GetMessage())
+	}
+}
+
+/*
+* HasPermission: check permission of role of request in handler
+* @return bool: false if request has no role or role lacks permission
+ */
+func HasPermission(ctx *HttpContext, permission string) bool {
+	roleID, ok := getRoleID(ctx)
+	return ok && checkPermissions(ctx, roleID, []string{permission}) == nil
+}
+
+/*
+* GetRolePermissions: permissions of role including permissions of its ancestors
+* @return []string, Error: error of provider
+ */
+func GetRolePermissions(ctx Context, roleID int64) ([]string, Error) {
+	granted, err := permissionStore.get(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]string, 0, len(granted))
+	for permission := range granted {
+		result = append(result, permission)
+	}
+	return result, nil
+}
+
+/*
+* getRoleID: role of request which is set by authentication middleware
+ */
+func getRoleID(ctx *HttpContext) (int64, bool) {
+	if roleID, ok := ctx.GetTempData(ROLE_ID_KEY).(int64); ok {
+		return roleID, true
+	}
+	if account, ok := ctx.GetTempData(JWT_CLAIMS_KEY).(jwtAccountClaims); ok {
+		return account.GetRoleID(), true
+	}
+	return 0, false
+}
+
+/*
+* checkPermissions: 403 if role lacks any required permission, 500 if permissions cannot be loaded
+ */
+func checkPermissions(ctx *HttpContext, roleID int64, required []string) HttpError {
+	granted, err := permissionStore.get(ctx, roleID)
+	if err != nil {
+		ctx.LogError("Load permissions of role %d fail: %v", roleID, err)
+		return HTTP_ERROR_INTERNAL_SERVER_ERROR
+	}
+
+	for _, permission := range required {
+		if !isPermissionGranted(granted, permission) {
+			ctx.LogInfo("Role %d lacks permission %s: url = %s", roleID, permission, ctx.URL)
+			return HTTP_ERROR_FORBIDDEN
+		}
+	}
+	return nil
+}
+
+/*
+* isPermissionGranted: permission is granted by itself, "*" or wildcard of its prefix ("user.*" grants "user.create")
+ */
+func isPermissionGranted(granted map[string]bool, permission string) bool {
+	if granted[permission] || granted[PERMISSION_ALL] {
+		return true
+	}
+	for index := strings.LastIndex(permission, "."); index > 0; index = strings.LastIndex(permission[:index], ".") {
+		if granted[permission[:index]+".*"] {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+* get: permissions of role from cache or provider
+ */
+func (cache *permissionCache) get(ctx Context, roleID int64) (map[string]bool, Error) {
+	cache.mu.RLock()
+	role, ok := cache.roles[roleID]
+	provider := cache.provider
+	cache.mu.RUnlock()
+	if ok && time.Since(role.loadedAt) < Config.Authorization.GetCacheTime() {
+		return role.permissions, nil
+	}
+
+	if provider == nil {
+		provider = dbPermissionProvider{}
+	}
+	granted, err := resolvePermissions(ctx, provider, roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.roles[roleID] = rolePermissions{permissions: granted, loadedAt: time.Now()}
+	return granted, nil
+}
+
+/*
+* resolvePermissions: collect permissions of role and its ancestors
+* Each role is visited once, so cycle in hierarchy does not loop forever
+ */
+func resolvePermissions(ctx Context, provider PermissionProvider, roleID int64) (map[string]bool, Error) {
+	granted := make(map[string]bool)
+	visited := map[int64]bool{roleID: true}
+	queue := []int64{roleID}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		own, err := provider.GetPermissions(ctx, current)
+		if err != nil {
+			return nil, err
+		}
+		for _, permission := range own {
+			granted[permission] = true
+		}
+
+		parents, err := provider.GetParentRoles(ctx, current)
+		if err != nil {
+			return nil, err
+		}
+		for _, parent := range parents {
+			if !visited[parent] {
+				visited[parent] = true
+				queue = append(queue, parent)
+			}
+		}
+	}
+	return granted, nil
+}
+
+/*
+* dbPermissionProvider: permissions in role_permissions table and hierarchy in parent_id of roles table
+ */
+type dbPermissionProvider struct{}
+
+func (dbPermissionProvider) GetPermissions(ctx Context, roleID int64) ([]string, Error) {
+	if DBSession() == nil {
+		return nil, ERROR_DB_ERROR
+	}
+
+	rows, err := DBSession().QueryContext(ctx, "SELECT permission FROM role_permissions WHERE role_id = $1", roleID)
+	if err != nil {
+		ctx.LogError("Select permissions of role %d fail: %v", roleID, err)
+		return nil, ERROR_DB_ERROR
+	}
+	defer rows.Close()
+
+	result := []string{}
+	for rows.Next() {
+		var permission string
+		if err := rows.Scan(&permission); err != nil {
+			ctx.LogError("Scan permission of role %d fail: %v", roleID, err)
+			return nil, ERROR_DB_ERROR
+		}
+		result = append(result, permission)
+	}
+	return result, nil
+}
+
+func (dbPermissionProvider) GetParentRoles(ctx Context, roleID int64) ([]int64, Error) {
+	if DBSession() == nil {
+		return nil, ERROR_DB_ERROR
+	}
+
+	rows, err := DBSession().QueryContext(ctx, "SELECT parent_id FROM roles WHERE id = $1 AND parent_id IS NOT NULL", roleID)
+	if err != nil {
+		ctx.LogError("Select parent of role %d fail: %v", roleID, err)
+		return nil, ERROR_DB_ERROR
+	}
+	defer rows.Close()
+
+	result := []int64{}
+	for rows.Next() {
+		var parentID int64
+		if err := rows.Scan(&parentID); err != nil {
+			ctx.LogError("Scan parent of role %d fail: %v", roleID, err)
+			return nil, ERROR_DB_ERROR
+		}
+		result = append(result, parentID)
+	}
+	return result, nil
+}
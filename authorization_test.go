@@ -0,0 +1,118 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+type testPermissionProvider struct {
+	permissions map[int64][]string
+	parents     map[int64][]int64
+	calls       int
+}
+
+func (provider *testPermissionProvider) GetPermissions(ctx Context, roleID int64) ([]string, Error) {
+	provider.calls++
+	return provider.permissions[roleID], nil
+}
+
+func (provider *testPermissionProvider) GetParentRoles(ctx Context, roleID int64) ([]int64, Error) {
+	return provider.parents[roleID], nil
+}
+
+func newTestPermissionProvider() *testPermissionProvider {
+	// viewer(1) <- editor(2) <- admin(3), role 4 and 5 form a cycle to check termination
+	return &testPermissionProvider{
+		permissions: map[int64][]string{
+			1: {"article.view"},
+			2: {"article.create", "comment.*"},
+			3: {"*"},
+			5: {"report.view"},
+		},
+		parents: map[int64][]int64{
+			2: {1},
+			3: {2},
+			4: {5},
+			5: {4},
+		},
+	}
+}
+
+func TestRequirePermissions(t *testing.T) {
+	provider := newTestPermissionProvider()
+	SetPermissionProvider(provider)
+	defer SetPermissionProvider(nil)
+
+	setRole := func(ctx *HttpContext) HttpError {
+		if roleID, err := strconv.ParseInt(ctx.GetRequestHeader("Role-Id"), 10, 64); err == nil {
+			ctx.SetTempData(ROLE_ID_KEY, roleID)
+		}
+		ctx.Next()
+		return nil
+	}
+	RegisterAPI("/test/authorization/articles", http.MethodPost, testDispatchHandler, setRole, RequirePermissions("article.create", "article.view"))
+	RegisterAPI("/test/authorization/comments", http.MethodDelete, testDispatchHandler, setRole, RequirePermissions("comment.delete"))
+
+	testCases := []struct {
+		method string
+		url    string
+		role   string
+		status int
+	}{
+		{http.MethodPost, "/test/authorization/articles", "1", http.StatusForbidden},
+		{http.MethodPost, "/test/authorization/articles", "2", http.StatusOK},
+		{http.MethodPost, "/test/authorization/articles", "3", http.StatusOK},
+		{http.MethodPost, "/test/authorization/articles", "", http.StatusUnauthorized},
+		{http.MethodDelete, "/test/authorization/comments", "2", http.StatusOK},
+		{http.MethodDelete, "/test/authorization/comments", "4", http.StatusForbidden},
+	}
+
+	for _, testCase := range testCases {
+		request := httptest.NewRequest(testCase.method, testCase.url, nil)
+		if testCase.role != "" {
+			request.Header.Set("Role-Id", testCase.role)
+		}
+		recorder := httptest.NewRecorder()
+		dispatchRequest(recorder, request)
+
+		if recorder.Code != testCase.status {
+			t.Errorf("%s %s role %s: expected status %d, got %d", testCase.method, testCase.url, testCase.role, testCase.status, recorder.Code)
+		}
+	}
+
+	// Permissions are cached until cache is invalidated
+	calls := provider.calls
+	GetRolePermissions(coreContext, 2)
+	if provider.calls != calls {
+		t.Errorf("Expected cached permissions, got %d provider calls", provider.calls-calls)
+	}
+	InvalidatePermissionCache()
+	if granted, _ := GetRolePermissions(coreContext, 2); provider.calls == calls || len(granted) != 3 {
+		t.Errorf("Expected permissions to be loaded again, got %v", granted)
+	}
+}
+
+func TestRequirePagePermissions(t *testing.T) {
+	SetPermissionProvider(newTestPermissionProvider())
+	defer SetPermissionProvider(nil)
+	forbiddenUrl := Config.Authorization.ForbiddenUrl
+	Config.Authorization.ForbiddenUrl = "/forbidden"
+	defer func() { Config.Authorization.ForbiddenUrl = forbiddenUrl }()
+
+	ctx := getHttpContext()
+	defer putHttpContext(ctx)
+	middleware := RequirePagePermissions("article.create")
+
+	buildContext(ctx, httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test/authorization/page", nil))
+	if err := middleware(ctx, &PageRequest{RoleID: 2}); err != nil || ctx.isResponseEnd {
+		t.Errorf("Expected editor to see page, got %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	buildContext(ctx, recorder, httptest.NewRequest(http.MethodGet, "/test/authorization/page", nil))
+	if err := middleware(ctx, &PageRequest{RoleID: 1}); err == nil || recorder.Code != http.StatusSeeOther || recorder.Header().Get("Location") != "/forbidden" {
+		t.Errorf("Expected viewer to be redirected, got %d %v", recorder.Code, recorder.Header())
+	}
+}
@@ -26,10 +26,24 @@ func seq(start int, end int) []int {
 	return result
 }
 
+/*
+* csrfToken, csrfField: blank when csrf is not used, page handler replaces them by token of request
+* Ex: <form method="post">{{ csrfField }}...</form>
+ */
+func csrfToken() string {
+	return BLANK
+}
+
+func csrfField() template.HTML {
+	return template.HTML(BLANK)
+}
+
 var basicFunctionMap = template.FuncMap{
-	"add":      add,
-	"subtract": subtract,
-	"seq":      seq,
-	"multiply": multiply,
-	"divide":   divide,
+	"add":       add,
+	"subtract":  subtract,
+	"seq":       seq,
+	"multiply":  multiply,
+	"divide":    divide,
+	"csrfToken": csrfToken,
+	"csrfField": csrfField,
 }
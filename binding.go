@@ -0,0 +1,296 @@
+package core
+
+import (
+	"encoding"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Struct tags which are used to bind request data into request struct
+const (
+	BINDING_TAG_QUERY       = "query"
+	BINDING_TAG_PATH        = "path"
+	BINDING_TAG_HEADER      = "header"
+	BINDING_TAG_FORM        = "form"
+	BINDING_TAG_TIME_FORMAT = "time_format"
+)
+
+// time_format:"unix" binds unix seconds into time.Time
+const BINDING_TIME_FORMAT_UNIX = "unix"
+
+var bindingTags = []string{BINDING_TAG_PATH, BINDING_TAG_QUERY, BINDING_TAG_HEADER, BINDING_TAG_FORM}
+
+var timeType = reflect.TypeOf(time.Time{})
+var durationType = reflect.TypeOf(time.Duration(0))
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+/*
+* requestBinder: bind query, path, header and form values into fields of request struct
+* Fields are resolved once when api is registered
+ */
+type requestBinder struct {
+	fields []bindingField
+}
+
+type bindingField struct {
+	index      []int
+	source     string
+	name       string
+	timeFormat string
+	// omitempty option of tag, zero value is not sent by internal client. Ex: query:"page,omitempty"
+	omitEmpty bool
+}
+
+/*
+* newRequestBinder: collect all fields which have binding tag
+* Fields of nested struct without binding tag are also collected
+* @param t: type of request struct
+* @return *requestBinder
+ */
+func newRequestBinder(t reflect.Type) *requestBinder {
+	binder := &requestBinder{}
+	binder.collectFields(t, nil)
+	return binder
+}
+
+func (binder *requestBinder) collectFields(t reflect.Type, parentIndex []int) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		index := append(append([]int{}, parentIndex...), i)
+		hasTag := false
+		for _, tag := range bindingTags {
+			value, ok := field.Tag.Lookup(tag)
+			if !ok || value == "-" {
+				continue
+			}
+
+			name, options, _ := strings.Cut(value, ",")
+			hasTag = true
+			binder.fields = append(binder.fields, bindingField{
+				index:      index,
+				source:     tag,
+				name:       name,
+				timeFormat: field.Tag.Get(BINDING_TAG_TIME_FORMAT),
+				omitEmpty:  options == "omitempty",
+			})
+		}
+
+		if !hasTag && field.Type.Kind() == reflect.Struct && field.Type != timeType {
+			binder.collectFields(field.Type, index)
+		}
+	}
+}
+
+/*
+* hasSource: check if request struct has any field of source
+ */
+func (binder *requestBinder) hasSource(source string) bool {
+	for _, field := range binder.fields {
+		if field.source == source {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+* bind: set values from request into fields of target
+* Field which has no value in request is kept, so value from json body or default value is not overwritten
+* @param ctx: http context which holds request and url params
+* @param target: addressable value of request struct
+* @return HttpError: bad request error if a value cannot be converted
+ */
+func (binder *requestBinder) bind(ctx *HttpContext, target reflect.Value) HttpError {
+	var query map[string][]string
+	for _, field := range binder.fields {
+		var values []string
+		switch field.source {
+		case BINDING_TAG_PATH:
+			if value, ok := ctx.urlParams[field.name]; ok {
+				values = []string{value}
+			}
+		case BINDING_TAG_QUERY:
+			if query == nil {
+				query = ctx.request.URL.Query()
+			}
+			values = query[field.name]
+		case BINDING_TAG_HEADER:
+			values = ctx.request.Header.Values(field.name)
+		case BINDING_TAG_FORM:
+			values = ctx.request.PostForm[field.name]
+		}
+
+		if len(values) == 0 {
+			continue
+		}
+
+		fieldValue := target.FieldByIndex(field.index)
+		if err := setBindingValue(fieldValue, values, field.timeFormat); err != nil {
+			ctx.LogInfo("Bind %s param fail: name = %s, values = %v, error = %v", field.source, field.name, values, err)
+			message := fmt.Sprintf("Invalid %s param: %s", field.source, field.name)
+			return NewHttpError(http.StatusBadRequest, ERROR_CODE_INVALID_REQUEST_PARAM, message, nil)
+		}
+	}
+
+	return nil
+}
+
+/*
+* setBindingValue: convert string values into value of field
+* Slice takes all values, a single value is split by comma
+ */
+func setBindingValue(value reflect.Value, values []string, timeFormat string) error {
+	if value.Kind() == reflect.Pointer {
+		elem := reflect.New(value.Type().Elem())
+		if err := setBindingValue(elem.Elem(), values, timeFormat); err != nil {
+			return err
+		}
+		value.Set(elem)
+		return nil
+	}
+
+	if value.Kind() == reflect.Slice && value.Type().Elem().Kind() != reflect.Uint8 {
+		if len(values) == 1 {
+			values = strings.Split(values[0], ",")
+		}
+
+		slice := reflect.MakeSlice(value.Type(), len(values), len(values))
+		for i, item := range values {
+			if err := setBindingValue(slice.Index(i), []string{strings.TrimSpace(item)}, timeFormat); err != nil {
+				return err
+			}
+		}
+		value.Set(slice)
+		return nil
+	}
+
+	return setBindingString(value, values[0], timeFormat)
+}
+
+func setBindingString(value reflect.Value, str string, timeFormat string) error {
+	switch value.Type() {
+	case timeType:
+		t, err := parseBindingTime(str, timeFormat)
+		if err != nil {
+			return err
+		}
+		value.Set(reflect.ValueOf(t))
+		return nil
+	case durationType:
+		duration, err := time.ParseDuration(str)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(duration))
+		return nil
+	}
+
+	if reflect.PointerTo(value.Type()).Implements(textUnmarshalerType) {
+		return value.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(str))
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(str)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(str)
+		if err != nil {
+			return err
+		}
+		value.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(str, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(str, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(str, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type: %s", value.Type())
+	}
+
+	return nil
+}
+
+func parseBindingTime(str string, timeFormat string) (time.Time, error) {
+	switch timeFormat {
+	case BLANK:
+		return time.Parse(time.RFC3339, str)
+	case BINDING_TIME_FORMAT_UNIX:
+		seconds, err := strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(seconds, 0), nil
+	default:
+		return time.Parse(timeFormat, str)
+	}
+}
+
+/*
+* formatBindingValue: convert value of field into string values, it is the reverse of setBindingValue
+* Nil pointer returns no value, so server keeps its default value
+* Zero value is formatted, caller skips it for field with omitempty option
+ */
+func formatBindingValue(value reflect.Value, timeFormat string) []string {
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil
+		}
+		return formatBindingValue(value.Elem(), timeFormat)
+	}
+
+	if value.Kind() == reflect.Slice && value.Type().Elem().Kind() != reflect.Uint8 {
+		values := []string{}
+		for i := 0; i < value.Len(); i++ {
+			values = append(values, formatBindingString(value.Index(i), timeFormat))
+		}
+		return values
+	}
+
+	return []string{formatBindingString(value, timeFormat)}
+}
+
+func formatBindingString(value reflect.Value, timeFormat string) string {
+	switch value.Type() {
+	case timeType:
+		t := value.Interface().(time.Time)
+		switch timeFormat {
+		case BLANK:
+			return t.Format(time.RFC3339)
+		case BINDING_TIME_FORMAT_UNIX:
+			return strconv.FormatInt(t.Unix(), 10)
+		default:
+			return t.Format(timeFormat)
+		}
+	case durationType:
+		return time.Duration(value.Int()).String()
+	}
+
+	if marshaler, ok := value.Interface().(encoding.TextMarshaler); ok {
+		if text, err := marshaler.MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+
+	return fmt.Sprint(value.Interface())
+}
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testBindingFilter struct {
+	Status []string `query:"status"`
+}
+
+type testBindingRequest struct {
+	ID       int64         `path:"id"`
+	Page     int           `query:"page"`
+	Active   *bool         `query:"active"`
+	Tags     []string      `query:"tag"`
+	From     time.Time     `query:"from" time_format:"2006-01-02"`
+	Timeout  time.Duration `query:"timeout"`
+	Tenant   string        `header:"X-Tenant"`
+	Name     string        `json:"name"`
+	Nickname string        `form:"nickname"`
+	Filter   testBindingFilter
+}
+
+func TestRequestBinder_BindAllSources(t *testing.T) {
+	ctx := GetHttpContextForTest()
+	defer putHttpContext(ctx)
+
+	request := httptest.NewRequest(http.MethodPost, "/users/12?page=3&active=true&tag=a&tag=b&from=2024-05-01&timeout=3s&status=new,done", strings.NewReader("nickname=bob"))
+	request.Header.Set("X-Tenant", "tenant-1")
+	request.Header.Set(CONTENT_TYPE_KEY, FORM_URLENCODED_CONTENT_TYPE)
+	request.ParseForm()
+	ctx.request = request
+	ctx.urlParams = map[string]string{"id": "12"}
+
+	req := testBindingRequest{Name: "from json"}
+	binder := newRequestBinder(reflect.TypeOf(req))
+	if err := binder.bind(ctx, reflect.ValueOf(&req).Elem()); err != nil {
+		t.Errorf("Expected nil, got %v", err)
+		return
+	}
+
+	if req.ID != 12 || req.Page != 3 || req.Active == nil || !*req.Active {
+		t.Errorf("Expected id = 12, page = 3, active = true, got %+v", req)
+	}
+
+	if len(req.Tags) != 2 || req.Tags[1] != "b" {
+		t.Errorf("Expected tags [a b], got %v", req.Tags)
+	}
+
+	if !req.From.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) || req.Timeout != 3*time.Second {
+		t.Errorf("Expected from = 2024-05-01, timeout = 3s, got %v, %v", req.From, req.Timeout)
+	}
+
+	if req.Tenant != "tenant-1" || req.Nickname != "bob" || req.Name != "from json" {
+		t.Errorf("Expected tenant-1, bob, from json, got %s, %s, %s", req.Tenant, req.Nickname, req.Name)
+	}
+
+	if len(req.Filter.Status) != 2 || req.Filter.Status[0] != "new" {
+		t.Errorf("Expected status [new done], got %v", req.Filter.Status)
+	}
+}
+
+func TestRequestBinder_InvalidValue(t *testing.T) {
+	ctx := GetHttpContextForTest()
+	defer putHttpContext(ctx)
+	ctx.request = httptest.NewRequest(http.MethodGet, "/users?page=abc", nil)
+
+	req := testBindingRequest{}
+	binder := newRequestBinder(reflect.TypeOf(req))
+	err := binder.bind(ctx, reflect.ValueOf(&req).Elem())
+	if err == nil {
+		t.Errorf("Expected error, got nil")
+		return
+	}
+
+	if err.GetStatusCode() != http.StatusBadRequest || err.GetCode() != ERROR_CODE_INVALID_REQUEST_PARAM {
+		t.Errorf("Expected bad request, got %v", err)
+	}
+}
@@ -0,0 +1,252 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ETag which is computed from response body
+const (
+	ETAG_MODE_NONE = iota
+	ETAG_MODE_STRONG
+	ETAG_MODE_WEAK
+)
+
+// Header keys of conditional request and cache
+const (
+	ETAG_KEY              = "ETag"
+	IF_NONE_MATCH_KEY     = "If-None-Match"
+	IF_MODIFIED_SINCE_KEY = "If-Modified-Since"
+	LAST_MODIFIED_KEY     = "Last-Modified"
+	CACHE_CONTROL_KEY     = "Cache-Control"
+)
+
+/*
+* CacheControl: policy which is written into Cache-Control header
+* Ex: CacheControl{Public: true, MaxAge: time.Hour}.String() = "public, max-age=3600"
+ */
+type CacheControl struct {
+	Public               bool
+	Private              bool
+	NoCache              bool
+	NoStore              bool
+	MustRevalidate       bool
+	Immutable            bool
+	MaxAge               time.Duration
+	SharedMaxAge         time.Duration
+	StaleWhileRevalidate time.Duration
+}
+
+/*
+* CacheControlNoStore: response is never stored by browser or proxy
+ */
+func CacheControlNoStore() CacheControl {
+	return CacheControl{NoStore: true}
+}
+
+/*
+* CacheControlNoCache: response is stored but it is revalidated by ETag or Last-Modified before each use
+ */
+func CacheControlNoCache() CacheControl {
+	return CacheControl{NoCache: true}
+}
+
+/*
+* CacheControlPublic: response is stored by browser and proxy in maxAge
+ */
+func CacheControlPublic(maxAge time.Duration) CacheControl {
+	return CacheControl{Public: true, MaxAge: maxAge}
+}
+
+/*
+* CacheControlPrivate: response is stored only by browser in maxAge
+ */
+func CacheControlPrivate(maxAge time.Duration) CacheControl {
+	return CacheControl{Private: true, MaxAge: maxAge}
+}
+
+/*
+* CacheControlImmutable: response is never changed, it is used for versioned static files
+ */
+func CacheControlImmutable(maxAge time.Duration) CacheControl {
+	return CacheControl{Public: true, MaxAge: maxAge, Immutable: true}
+}
+
+func (cacheControl CacheControl) String() string {
+	directives := []string{}
+	if cacheControl.Public {
+		directives = append(directives, "public")
+	}
+	if cacheControl.Private {
+		directives = append(directives, "private")
+	}
+	if cacheControl.NoCache {
+		directives = append(directives, "no-cache")
+	}
+	if cacheControl.NoStore {
+		directives = append(directives, "no-store")
+	}
+	if cacheControl.MustRevalidate {
+		directives = append(directives, "must-revalidate")
+	}
+	if cacheControl.Immutable {
+		directives = append(directives, "immutable")
+	}
+	if cacheControl.MaxAge > 0 || cacheControl.Public || cacheControl.Private {
+		directives = append(directives, "max-age="+strconv.FormatInt(int64(cacheControl.MaxAge/time.Second), 10))
+	}
+	if cacheControl.SharedMaxAge > 0 {
+		directives = append(directives, "s-maxage="+strconv.FormatInt(int64(cacheControl.SharedMaxAge/time.Second), 10))
+	}
+	if cacheControl.StaleWhileRevalidate > 0 {
+		directives = append(directives, "stale-while-revalidate="+strconv.FormatInt(int64(cacheControl.StaleWhileRevalidate/time.Second), 10))
+	}
+	return strings.Join(directives, ", ")
+}
+
+/*
+* SetCacheControl: set Cache-Control header of response
+* @params: cacheControl CacheControl
+* @return: void
+ */
+func (ctx *HttpContext) SetCacheControl(cacheControl CacheControl) {
+	ctx.rw.Header().Set(CACHE_CONTROL_KEY, cacheControl.String())
+}
+
+/*
+* EnableETag: compute ETag from serialized body of success response and page
+* Request with matching If-None-Match is responded with 304
+* @params: weak bool: weak ETag (W/"...") is computed if it is true
+* @return: void
+ */
+func (ctx *HttpContext) EnableETag(weak bool) {
+	if weak {
+		ctx.etagMode = ETAG_MODE_WEAK
+	} else {
+		ctx.etagMode = ETAG_MODE_STRONG
+	}
+}
+
+/*
+* SetETag: set ETag which is computed by handler, for example version of record
+* @params: value string: value without quotes, weak bool
+* @return: void
+ */
+func (ctx *HttpContext) SetETag(value string, weak bool) {
+	ctx.etag = formatETag(value, weak)
+}
+
+/*
+* SetLastModified: set Last-Modified header, request with If-Modified-Since which is not before it is responded with 304
+* @params: lastModified time.Time
+* @return: void
+ */
+func (ctx *HttpContext) SetLastModified(lastModified time.Time) {
+	ctx.lastModified = lastModified
+}
+
+/*
+* endCachedResponse: write ETag and Last-Modified header of success response
+* 304 is written instead of body if request is not modified
+ */
+func (ctx *HttpContext) endCachedResponse(statusCode int, body string) {
+	if statusCode != http.StatusOK || (ctx.Method != http.MethodGet && ctx.Method != http.MethodHead) {
+		ctx.endResponse(statusCode, body)
+		return
+	}
+
+	etag := ctx.etag
+	if etag == BLANK && ctx.etagMode != ETAG_MODE_NONE {
+		hash := sha256.Sum256([]byte(body))
+		etag = formatETag(hex.EncodeToString(hash[:16]), ctx.etagMode == ETAG_MODE_WEAK)
+	}
+
+	header := ctx.rw.Header()
+	if etag != BLANK {
+		header.Set(ETAG_KEY, etag)
+	}
+	if !ctx.lastModified.IsZero() {
+		header.Set(LAST_MODIFIED_KEY, ctx.lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if isNotModified(ctx.request, etag, ctx.lastModified) {
+		header.Del(CONTENT_TYPE_KEY)
+		ctx.endResponse(http.StatusNotModified, BLANK)
+		return
+	}
+
+	ctx.endResponse(statusCode, body)
+}
+
+/*
+* isNotModified: If-None-Match is checked by weak comparison, If-Modified-Since is only checked if If-None-Match is not sent
+ */
+func isNotModified(request *http.Request, etag string, lastModified time.Time) bool {
+	if request == nil {
+		return false
+	}
+
+	if ifNoneMatch := request.Header.Get(IF_NONE_MATCH_KEY); ifNoneMatch != BLANK {
+		if etag == BLANK {
+			return false
+		}
+
+		for _, item := range strings.Split(ifNoneMatch, ",") {
+			item = strings.TrimSpace(item)
+			if item == "*" || strings.TrimPrefix(item, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ifModifiedSince := request.Header.Get(IF_MODIFIED_SINCE_KEY); ifModifiedSince != BLANK && !lastModified.IsZero() {
+		since, err := http.ParseTime(ifModifiedSince)
+		if err != nil {
+			return false
+		}
+		// Header has precision of second
+		return !lastModified.Truncate(time.Second).After(since)
+	}
+
+	return false
+}
+
+func formatETag(value string, weak bool) string {
+	value = `"` + strings.Trim(value, `"`) + `"`
+	if weak {
+		return "W/" + value
+	}
+	return value
+}
+
+/*
+* staticCacheHandler: set weak ETag from size and modified time of file and Cache-Control of static folder
+* Conditional request is answered with 304 by http.FileServer
+ */
+func staticCacheHandler(folder staticFolder, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		filePath := filepath.Join(folder.path, filepath.FromSlash(path.Clean("/"+r.URL.Path)))
+		info, err := os.Stat(filePath)
+		if err == nil && info.IsDir() {
+			info, err = os.Stat(filepath.Join(filePath, "index.html"))
+		}
+
+		if err == nil {
+			w.Header().Set(ETAG_KEY, formatETag(fmt.Sprintf("%x-%x", info.Size(), info.ModTime().UnixNano()), true))
+			if folder.cacheControl != nil {
+				w.Header().Set(CACHE_CONTROL_KEY, folder.cacheControl.String())
+			}
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCacheControl_String(t *testing.T) {
+	testCases := map[string]CacheControl{
+		"no-store":                      CacheControlNoStore(),
+		"public, max-age=3600":          CacheControlPublic(time.Hour),
+		"private, max-age=0":            CacheControlPrivate(0),
+		"public, immutable, max-age=60": CacheControlImmutable(time.Minute),
+	}
+
+	for expected, cacheControl := range testCases {
+		if value := cacheControl.String(); value != expected {
+			t.Errorf("Expected %s, got %s", expected, value)
+		}
+	}
+}
+
+func TestDispatchRequest_ETag(t *testing.T) {
+	RegisterAPI("/test/cache/etag", http.MethodGet, testDispatchHandler, func(ctx *HttpContext) HttpError {
+		ctx.EnableETag(true)
+		ctx.Next()
+		return nil
+	})
+
+	recorder := httptest.NewRecorder()
+	dispatchRequest(recorder, httptest.NewRequest(http.MethodGet, "/test/cache/etag", nil))
+
+	etag := recorder.Header().Get(ETAG_KEY)
+	if recorder.Code != http.StatusOK || len(etag) < 4 || etag[:2] != "W/" {
+		t.Fatalf("Expected 200 with weak ETag, got %d %s", recorder.Code, etag)
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/test/cache/etag", nil)
+	request.Header.Set(IF_NONE_MATCH_KEY, etag)
+	recorder = httptest.NewRecorder()
+	dispatchRequest(recorder, request)
+
+	if recorder.Code != http.StatusNotModified || recorder.Body.Len() != 0 {
+		t.Errorf("Expected 304 without body, got %d %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestDispatchRequest_ETagWithoutData(t *testing.T) {
+	RegisterAPI("/test/cache/etag-no-data", http.MethodGet, func(ctx *HttpContext, request testDispatchRequest) (HttpResponse, HttpError) {
+		ctx.SetETag("v1", false)
+		return nil, nil
+	})
+
+	recorder := httptest.NewRecorder()
+	dispatchRequest(recorder, httptest.NewRequest(http.MethodGet, "/test/cache/etag-no-data", nil))
+	if etag := recorder.Header().Get(ETAG_KEY); recorder.Code != http.StatusOK || etag != `"v1"` {
+		t.Fatalf("Expected 200 with ETag \"v1\", got %d %s", recorder.Code, etag)
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/test/cache/etag-no-data", nil)
+	request.Header.Set(IF_NONE_MATCH_KEY, `"v1"`)
+	recorder = httptest.NewRecorder()
+	dispatchRequest(recorder, request)
+	if recorder.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 for handler without data, got %d", recorder.Code)
+	}
+}
+
+func TestDispatchRequest_LastModified(t *testing.T) {
+	lastModified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	RegisterAPI("/test/cache/last-modified", http.MethodGet, func(ctx *HttpContext, request testDispatchRequest) (HttpResponse, HttpError) {
+		ctx.SetLastModified(lastModified)
+		return NewDefaultHttpResponse("ok"), nil
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/test/cache/last-modified", nil)
+	request.Header.Set(IF_MODIFIED_SINCE_KEY, lastModified.Format(http.TimeFormat))
+	recorder := httptest.NewRecorder()
+	dispatchRequest(recorder, request)
+
+	if recorder.Code != http.StatusNotModified {
+		t.Errorf("Expected 304, got %d", recorder.Code)
+	}
+
+	request = httptest.NewRequest(http.MethodGet, "/test/cache/last-modified", nil)
+	request.Header.Set(IF_MODIFIED_SINCE_KEY, lastModified.Add(-time.Hour).Format(http.TimeFormat))
+	recorder = httptest.NewRecorder()
+	dispatchRequest(recorder, request)
+
+	if recorder.Code != http.StatusOK || recorder.Header().Get(LAST_MODIFIED_KEY) == BLANK {
+		t.Errorf("Expected 200 with Last-Modified, got %d", recorder.Code)
+	}
+}
+
+func TestStaticCacheHandler(t *testing.T) {
+	folder := t.TempDir()
+	if err := os.WriteFile(filepath.Join(folder, "app.js"), []byte("console.log('core')"), 0644); err != nil {
+		t.Fatalf("Write file fail: %v", err)
+	}
+
+	cacheControl := CacheControlPublic(time.Hour)
+	handler := staticCacheHandler(staticFolder{path: folder, cacheControl: &cacheControl}, http.FileServer(http.Dir(folder)))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/app.js", nil))
+
+	etag := recorder.Header().Get(ETAG_KEY)
+	if recorder.Code != http.StatusOK || etag == BLANK {
+		t.Fatalf("Expected 200 with ETag, got %d %s", recorder.Code, etag)
+	}
+
+	if recorder.Header().Get(CACHE_CONTROL_KEY) != "public, max-age=3600" {
+		t.Errorf("Expected Cache-Control public, max-age=3600, got %s", recorder.Header().Get(CACHE_CONTROL_KEY))
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/app.js", nil)
+	request.Header.Set(IF_NONE_MATCH_KEY, etag)
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusNotModified {
+		t.Errorf("Expected 304, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,172 @@
+package core
+
+import (
+	"crypto/tls"
+	"errors"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+/*
+* certificateStore: certificates of secure server which are swapped without restarting server
+* Certificate is chosen for each handshake, so new connections use reloaded certificates immediately
+ */
+type certificateStore struct {
+	mu           sync.RWMutex
+	files        []CertificateConfig
+	certificates []*tls.Certificate
+	modTimes     map[string]time.Time
+}
+
+var secureCertificates *certificateStore
+
+/*
+* newCertificateStore: load certificate files, server cannot start without valid certificates
+* @param files: certificate files, the first one is used when client sends no matching server name
+* @return *certificateStore, error
+ */
+func newCertificateStore(files []CertificateConfig) (*certificateStore, error) {
+	if len(files) == 0 {
+		return nil, errors.New("secure server has no certificate")
+	}
+
+	store := &certificateStore{files: files}
+	if err := store.load(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+/*
+* ReloadCertificates: reload certificate files of secure server
+* Current certificates are kept if any file cannot be loaded
+* @return Error
+ */
+func ReloadCertificates() Error {
+	if secureCertificates == nil {
+		return ERROR_SECURE_SERVER_IS_NOT_STARTED
+	}
+
+	if err := secureCertificates.reload(); err != nil {
+		return NewError(ERROR_FROM_LIBRARY, err.Error())
+	}
+	return nil
+}
+
+/*
+* load: load all certificate files and replace current certificates
+ */
+func (store *certificateStore) load() error {
+	// Modified time is taken before reading, so change during reading is loaded in next check
+	modTimes := make(map[string]time.Time)
+	certificates := make([]*tls.Certificate, 0, len(store.files))
+	for _, file := range store.files {
+		for _, path := range []string{file.CertFile, file.KeyFile} {
+			if info, err := os.Stat(path); err == nil {
+				modTimes[path] = info.ModTime()
+			}
+		}
+
+		certificate, err := tls.LoadX509KeyPair(file.CertFile, file.KeyFile)
+		if err != nil {
+			return err
+		}
+		certificates = append(certificates, &certificate)
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.certificates = certificates
+	store.modTimes = modTimes
+	return nil
+}
+
+/*
+* reload: load certificates, result is logged and counted in metric
+ */
+func (store *certificateStore) reload() error {
+	if err := store.load(); err != nil {
+		IncreaseMetric(METRIC_TLS_CERT_RELOAD_FAIL)
+		LogError("Reload tls certificates fail, current certificates are kept: %v", err)
+		return err
+	}
+
+	IncreaseMetric(METRIC_TLS_CERT_RELOAD_SUCCESS)
+	LogInfo("Reload tls certificates success: %d certificates", len(store.files))
+	return nil
+}
+
+/*
+* isModified: check if any certificate file is changed after the last successful load
+ */
+func (store *certificateStore) isModified() bool {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	for _, file := range store.files {
+		for _, path := range []string{file.CertFile, file.KeyFile} {
+			info, err := os.Stat(path)
+			if err != nil {
+				// File is being replaced, it is checked again in next tick
+				continue
+			}
+			if !info.ModTime().Equal(store.modTimes[path]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/*
+* getCertificate: choose certificate which supports server name and algorithms of client
+* It is used as GetCertificate of tls config
+ */
+func (store *certificateStore) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	if hello.ServerName != BLANK {
+		for _, certificate := range store.certificates {
+			if hello.SupportsCertificate(certificate) == nil {
+				return certificate, nil
+			}
+		}
+	}
+	return store.certificates[0], nil
+}
+
+/*
+* watch: reload certificates when files are changed or SIGHUP is received until stop is closed
+* @param interval: interval of checking files, files are not checked if it is zero
+* @param stop: channel which stops watching
+ */
+func (store *certificateStore) watch(interval time.Duration, stop <-chan struct{}) {
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, syscall.SIGHUP)
+	defer signal.Stop(signalChan)
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-signalChan:
+			LogInfo("Receive signal: SIGHUP, reload tls certificates")
+			store.reload()
+		case <-tick:
+			if store.isModified() {
+				LogInfo("Tls certificate files are changed, reload certificates")
+				store.reload()
+			}
+		}
+	}
+}
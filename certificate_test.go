@@ -0,0 +1,77 @@
+package core
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCertificateStore_SNIAndReload(t *testing.T) {
+	dir := t.TempDir()
+	defaultCert, defaultKey := writeTestCertificate(t, dir, "default.test")
+	apiCert, apiKey := writeTestCertificate(t, dir, "api.test")
+
+	server, store, err := newSecureServer(SecureServerConfig{
+		CertFile:     defaultCert,
+		KeyFile:      defaultKey,
+		Certificates: []CertificateConfig{{CertFile: apiCert, KeyFile: apiKey}},
+	}, http.NotFoundHandler())
+	if err != nil {
+		t.Fatalf("Create secure server fail: %v", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.ServeTLS(listener, BLANK, BLANK)
+	defer server.Close()
+
+	handshake := func(serverName string) string {
+		connection, err := tls.Dial("tcp", listener.Addr().String(), &tls.Config{ServerName: serverName, InsecureSkipVerify: true})
+		if err != nil {
+			t.Fatalf("Handshake fail: %v", err)
+		}
+		defer connection.Close()
+		leaf := connection.ConnectionState().PeerCertificates[0]
+		return leaf.Subject.CommonName + "/" + leaf.SerialNumber.String()
+	}
+
+	if name := handshake("api.test"); name[:len("api.test")] != "api.test" {
+		t.Errorf("Expected certificate of api.test, got %s", name)
+	}
+	before := handshake("other.test")
+	if before[:len("default.test")] != "default.test" {
+		t.Errorf("Expected default certificate, got %s", before)
+	}
+
+	// Replace default certificate, it is used by new connections after reload
+	writeTestCertificate(t, dir, "default.test")
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(defaultCert, future, future)
+	if !store.isModified() {
+		t.Errorf("Expected certificate files are modified")
+	}
+
+	success := GetMetric(METRIC_TLS_CERT_RELOAD_SUCCESS)
+	if err := store.reload(); err != nil || GetMetric(METRIC_TLS_CERT_RELOAD_SUCCESS) != success+1 {
+		t.Fatalf("Expected reload success, error = %v", err)
+	}
+	if after := handshake("other.test"); after == before {
+		t.Errorf("Expected new certificate after reload, got %s", after)
+	}
+
+	// Broken file is not loaded, current certificates are kept
+	current := handshake("other.test")
+	os.WriteFile(defaultCert, []byte("broken"), 0600)
+	fail := GetMetric(METRIC_TLS_CERT_RELOAD_FAIL)
+	if err := store.reload(); err == nil || GetMetric(METRIC_TLS_CERT_RELOAD_FAIL) != fail+1 {
+		t.Errorf("Expected reload fail, error = %v", err)
+	}
+	if kept := handshake("other.test"); kept != current {
+		t.Errorf("Expected current certificate is kept, got %s", kept)
+	}
+}
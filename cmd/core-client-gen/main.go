@@ -0,0 +1,781 @@
+// Command core-client-gen generates a typed Go client for apis which are registered by
+// RegisterAPI, RegisterTypedAPI, RegisterGroupAPI and RegisterGroupTypedAPI of package core.
+//
+// Every route whose url and method are literals becomes a method of Client which takes
+// the request struct of handler and returns data of response and core.Error.
+// Response type is the result of typed handler or the model of SetResponseModel, otherwise any.
+//
+// Usage in the package which registers apis:
+//
+//	//go:generate go run github.com/dangviethung096/core/cmd/core-client-gen -out ../userclient/client_gen.go
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const coreImportPath = "github.com/dangviethung096/core"
+
+var httpMethods = map[string]string{
+	"MethodGet":     "GET",
+	"MethodHead":    "HEAD",
+	"MethodPost":    "POST",
+	"MethodPut":     "PUT",
+	"MethodPatch":   "PATCH",
+	"MethodDelete":  "DELETE",
+	"MethodConnect": "CONNECT",
+	"MethodOptions": "OPTIONS",
+	"MethodTrace":   "TRACE",
+}
+
+// Names which are used by generated client
+var reservedNames = map[string]bool{"BaseUrl": true, "Prepare": true, "NewClient": true}
+
+func main() {
+	dir := flag.String("dir", ".", "directory of package which registers apis")
+	out := flag.String("out", "client_gen.go", "output file of client")
+	packageName := flag.String("package", "", "package name of client, default is name of output directory")
+	importPath := flag.String("import", "", "import path of package which registers apis, default is resolved from go.mod")
+	flag.Parse()
+
+	source, err := generate(options{
+		dir:         *dir,
+		out:         *out,
+		packageName: *packageName,
+		importPath:  *importPath,
+	})
+	if err != nil {
+		log.Fatalf("core-client-gen: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(*out), 0755); err != nil {
+		log.Fatalf("core-client-gen: %v", err)
+	}
+	if err := os.WriteFile(*out, source, 0644); err != nil {
+		log.Fatalf("core-client-gen: %v", err)
+	}
+}
+
+type options struct {
+	dir         string
+	out         string
+	packageName string
+	importPath  string
+}
+
+/*
+* route: api which is found in source, types are rendered as they are written in generated file
+ */
+type route struct {
+	name     string
+	method   string
+	pattern  string
+	request  string
+	response string
+}
+
+type sourceFile struct {
+	file      *ast.File
+	coreAlias string
+	imports   map[string]string
+}
+
+type generator struct {
+	fset        *token.FileSet
+	files       []*sourceFile
+	packageName string
+	packageRef  string
+	funcs       map[string]*ast.FuncType
+	methods     map[string][]*ast.FuncType
+	types       map[string]bool
+	groups      map[string]string
+	models      map[*ast.CallExpr]ast.Expr
+	imports     map[string]string
+	names       map[string]int
+	routes      []route
+}
+
+/*
+* generate: parse package in dir and return formatted source of client
+ */
+func generate(opts options) ([]byte, error) {
+	g := &generator{
+		fset:    token.NewFileSet(),
+		funcs:   map[string]*ast.FuncType{},
+		methods: map[string][]*ast.FuncType{},
+		types:   map[string]bool{},
+		groups:  map[string]string{},
+		models:  map[*ast.CallExpr]ast.Expr{},
+		imports: map[string]string{"core": coreImportPath},
+		names:   map[string]int{},
+	}
+
+	if err := g.parse(opts.dir, opts.out); err != nil {
+		return nil, err
+	}
+
+	absDir, _ := filepath.Abs(opts.dir)
+	absOutDir, _ := filepath.Abs(filepath.Dir(opts.out))
+	clientPackage := opts.packageName
+	if absDir == absOutDir {
+		// Client is generated into the package of apis, so its types are not qualified
+		clientPackage = g.packageName
+	} else {
+		if g.packageName == "main" {
+			return nil, errors.New("types of package main cannot be imported, generate client into the same directory")
+		}
+
+		path := opts.importPath
+		if path == "" {
+			var err error
+			if path, err = resolveImportPath(absDir); err != nil {
+				return nil, err
+			}
+		}
+		g.packageRef = g.packageName
+		if err := g.addImport(g.packageName, path); err != nil {
+			return nil, err
+		}
+	}
+	if clientPackage == "" {
+		clientPackage = filepath.Base(absOutDir)
+	}
+
+	g.collectDeclarations()
+	g.collectGroups()
+	g.collectModels()
+	for _, file := range g.files {
+		ast.Inspect(file.file, func(node ast.Node) bool {
+			if call, ok := node.(*ast.CallExpr); ok {
+				g.collectRoute(file, call)
+			}
+			return true
+		})
+	}
+
+	if len(g.routes) == 0 {
+		return nil, fmt.Errorf("no api is registered in %s", opts.dir)
+	}
+	return g.render(clientPackage)
+}
+
+func (g *generator) parse(dir string, out string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	absOut, _ := filepath.Abs(out)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+
+		path := filepath.Join(dir, name)
+		if absPath, _ := filepath.Abs(path); absPath == absOut {
+			// Skip client which is generated before
+			continue
+		}
+
+		file, err := parser.ParseFile(g.fset, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return err
+		}
+		if g.packageName == "" {
+			g.packageName = file.Name.Name
+		}
+
+		source := &sourceFile{file: file, imports: map[string]string{}}
+		for _, spec := range file.Imports {
+			importPath, _ := strconv.Unquote(spec.Path.Value)
+			alias := filepath.Base(importPath)
+			if spec.Name != nil {
+				alias = spec.Name.Name
+			}
+			source.imports[alias] = importPath
+			if importPath == coreImportPath {
+				source.coreAlias = alias
+			}
+		}
+		g.files = append(g.files, source)
+	}
+	return nil
+}
+
+/*
+* collectDeclarations: collect handler functions, methods and type names of package
+ */
+func (g *generator) collectDeclarations() {
+	for _, file := range g.files {
+		for _, decl := range file.file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if decl.Recv == nil {
+					g.funcs[decl.Name.Name] = decl.Type
+				} else {
+					g.methods[decl.Name.Name] = append(g.methods[decl.Name.Name], decl.Type)
+				}
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					if typeSpec, ok := spec.(*ast.TypeSpec); ok {
+						g.types[typeSpec.Name.Name] = true
+					}
+				}
+			}
+		}
+	}
+}
+
+/*
+* collectGroups: resolve prefix of variables which are assigned by Group with literal prefix
+* Variables are matched by name, so group variables should have unique names in package
+ */
+func (g *generator) collectGroups() {
+	for changed := true; changed; {
+		changed = false
+		for _, file := range g.files {
+			ast.Inspect(file.file, func(node ast.Node) bool {
+				var names []*ast.Ident
+				var values []ast.Expr
+				switch node := node.(type) {
+				case *ast.AssignStmt:
+					for _, lhs := range node.Lhs {
+						ident, _ := lhs.(*ast.Ident)
+						names = append(names, ident)
+					}
+					values = node.Rhs
+				case *ast.ValueSpec:
+					names = node.Names
+					values = node.Values
+				default:
+					return true
+				}
+
+				if len(names) != len(values) {
+					return true
+				}
+				for i, value := range values {
+					if names[i] == nil {
+						continue
+					}
+					if prefix, ok := g.groupPrefix(file, value); ok {
+						if old, exist := g.groups[names[i].Name]; !exist || old != prefix {
+							g.groups[names[i].Name] = prefix
+							changed = true
+						}
+					}
+				}
+				return true
+			})
+		}
+	}
+}
+
+func (g *generator) groupPrefix(file *sourceFile, expr ast.Expr) (string, bool) {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok || len(call.Args) == 0 {
+		return "", false
+	}
+	selector, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || selector.Sel.Name != "Group" {
+		return "", false
+	}
+	x, ok := selector.X.(*ast.Ident)
+	if !ok {
+		return "", false
+	}
+	prefix, ok := stringLiteral(call.Args[0])
+	if !ok {
+		return "", false
+	}
+	prefix = strings.TrimSuffix(prefix, "/")
+
+	if x.Name == file.coreAlias && file.coreAlias != "" {
+		return prefix, true
+	}
+	if parent, ok := g.groups[x.Name]; ok {
+		return joinUrl(parent, prefix), true
+	}
+	return "", false
+}
+
+/*
+* collectModels: find response model of chained call. Ex: core.RegisterAPI(...).SetTags("user").SetResponseModel(User{})
+ */
+func (g *generator) collectModels() {
+	for _, file := range g.files {
+		ast.Inspect(file.file, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpr)
+			if !ok || len(call.Args) != 1 {
+				return true
+			}
+			selector, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || selector.Sel.Name != "SetResponseModel" {
+				return true
+			}
+
+			// Walk down the chain to the register call
+			expr := selector.X
+			for {
+				inner, ok := expr.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				if _, ok := registerFunction(file, inner); ok {
+					g.models[inner] = call.Args[0]
+					return true
+				}
+				innerSelector, ok := inner.Fun.(*ast.SelectorExpr)
+				if !ok {
+					return true
+				}
+				expr = innerSelector.X
+			}
+		})
+	}
+}
+
+/*
+* registerFunction: name of register function which is called, blank if call is not a register call
+ */
+func registerFunction(file *sourceFile, call *ast.CallExpr) (string, bool) {
+	fun := call.Fun
+	switch index := fun.(type) {
+	case *ast.IndexExpr:
+		fun = index.X
+	case *ast.IndexListExpr:
+		fun = index.X
+	}
+
+	selector, ok := fun.(*ast.SelectorExpr)
+	if !ok || file.coreAlias == "" {
+		return "", false
+	}
+	if x, ok := selector.X.(*ast.Ident); !ok || x.Name != file.coreAlias {
+		return "", false
+	}
+
+	switch selector.Sel.Name {
+	case "RegisterAPI", "RegisterTypedAPI", "RegisterGroupAPI", "RegisterGroupTypedAPI":
+		return selector.Sel.Name, true
+	}
+	return "", false
+}
+
+func (g *generator) collectRoute(file *sourceFile, call *ast.CallExpr) {
+	function, ok := registerFunction(file, call)
+	if !ok {
+		return
+	}
+	position := g.fset.Position(call.Pos())
+	warn := func(format string, args ...any) {
+		log.Printf("core-client-gen: %s: skip %s: %s", position, function, fmt.Sprintf(format, args...))
+	}
+
+	args := call.Args
+	prefix := ""
+	if strings.HasPrefix(function, "RegisterGroup") {
+		if len(args) == 0 {
+			return
+		}
+		group, ok := args[0].(*ast.Ident)
+		if !ok {
+			warn("group must be a variable")
+			return
+		}
+		if prefix, ok = g.groups[group.Name]; !ok {
+			warn("prefix of group %s is unknown", group.Name)
+			return
+		}
+		args = args[1:]
+	}
+	if len(args) < 3 {
+		return
+	}
+
+	url, ok := stringLiteral(args[0])
+	if !ok {
+		warn("url is not a literal")
+		return
+	}
+	if prefix != "" {
+		url = joinUrl(prefix, url)
+	}
+	method, ok := methodLiteral(args[1])
+	if !ok {
+		warn("method is not a literal")
+		return
+	}
+
+	// Type of request and response: explicit type arguments, then signature of handler
+	var requestExpr, responseExpr ast.Expr
+	switch index := call.Fun.(type) {
+	case *ast.IndexExpr:
+		requestExpr = index.Index
+	case *ast.IndexListExpr:
+		requestExpr = index.Indices[0]
+		if len(index.Indices) > 1 {
+			responseExpr = index.Indices[1]
+		}
+	}
+
+	funcType, handlerName := g.handler(args[2])
+	if funcType != nil {
+		params := flattenFields(funcType.Params)
+		if requestExpr == nil && len(params) == 2 {
+			requestExpr = params[1]
+		}
+		results := flattenFields(funcType.Results)
+		if responseExpr == nil && strings.HasSuffix(function, "TypedAPI") && len(results) == 2 {
+			responseExpr = results[0]
+		}
+	}
+	if requestExpr == nil {
+		warn("type of request is unknown")
+		return
+	}
+	if responseExpr == nil {
+		responseExpr = modelType(g.models[call])
+	}
+
+	request, err := g.typeString(file, requestExpr)
+	if err != nil {
+		warn("%v", err)
+		return
+	}
+	response := "any"
+	if responseExpr != nil {
+		if response, err = g.typeString(file, responseExpr); err != nil {
+			warn("%v", err)
+			return
+		}
+	}
+
+	g.routes = append(g.routes, route{
+		name:     g.methodName(handlerName, method, url),
+		method:   method,
+		pattern:  url,
+		request:  request,
+		response: response,
+	})
+}
+
+/*
+* handler: signature and name of handler expression
+* Method value is resolved by its name if only one method of package has this name
+ */
+func (g *generator) handler(expr ast.Expr) (*ast.FuncType, string) {
+	switch expr := expr.(type) {
+	case *ast.FuncLit:
+		return expr.Type, ""
+	case *ast.Ident:
+		return g.funcs[expr.Name], expr.Name
+	case *ast.SelectorExpr:
+		if methods := g.methods[expr.Sel.Name]; len(methods) == 1 {
+			return methods[0], expr.Sel.Name
+		}
+		return nil, expr.Sel.Name
+	}
+	return nil, ""
+}
+
+/*
+* modelType: type of value which is passed to SetResponseModel. Ex: User{}, []User{}, &User{}
+ */
+func modelType(expr ast.Expr) ast.Expr {
+	switch expr := expr.(type) {
+	case *ast.CompositeLit:
+		return expr.Type
+	case *ast.UnaryExpr:
+		if inner := modelType(expr.X); inner != nil && expr.Op == token.AND {
+			return &ast.StarExpr{X: inner}
+		}
+	case *ast.ParenExpr:
+		return modelType(expr.X)
+	}
+	return nil
+}
+
+/*
+* typeString: render type expression for generated file
+* Types of api package are qualified and imports of other packages are added to generated file
+ */
+func (g *generator) typeString(file *sourceFile, expr ast.Expr) (string, error) {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		if g.types[expr.Name] {
+			if g.packageRef == "" {
+				return expr.Name, nil
+			}
+			if !ast.IsExported(expr.Name) {
+				return "", fmt.Errorf("type %s is not exported", expr.Name)
+			}
+			return g.packageRef + "." + expr.Name, nil
+		}
+		if object := types.Universe.Lookup(expr.Name); object != nil {
+			if _, ok := object.(*types.TypeName); ok {
+				return expr.Name, nil
+			}
+		}
+		return "", fmt.Errorf("type %s is unknown", expr.Name)
+	case *ast.SelectorExpr:
+		x, ok := expr.X.(*ast.Ident)
+		if !ok {
+			return "", fmt.Errorf("type %T is not supported", expr.X)
+		}
+		path, ok := file.imports[x.Name]
+		if !ok {
+			return "", fmt.Errorf("package %s is not imported", x.Name)
+		}
+		alias := x.Name
+		if path == coreImportPath {
+			alias = "core"
+		}
+		if err := g.addImport(alias, path); err != nil {
+			return "", err
+		}
+		return alias + "." + expr.Sel.Name, nil
+	case *ast.StarExpr:
+		inner, err := g.typeString(file, expr.X)
+		return "*" + inner, err
+	case *ast.ArrayType:
+		elem, err := g.typeString(file, expr.Elt)
+		if expr.Len == nil {
+			return "[]" + elem, err
+		}
+		length, ok := expr.Len.(*ast.BasicLit)
+		if !ok {
+			return "", errors.New("array length must be a literal")
+		}
+		return "[" + length.Value + "]" + elem, err
+	case *ast.MapType:
+		key, err := g.typeString(file, expr.Key)
+		if err != nil {
+			return "", err
+		}
+		value, err := g.typeString(file, expr.Value)
+		return "map[" + key + "]" + value, err
+	case *ast.InterfaceType:
+		if expr.Methods == nil || len(expr.Methods.List) == 0 {
+			return "any", nil
+		}
+	case *ast.IndexExpr:
+		return g.genericTypeString(file, expr.X, []ast.Expr{expr.Index})
+	case *ast.IndexListExpr:
+		return g.genericTypeString(file, expr.X, expr.Indices)
+	}
+	return "", fmt.Errorf("type %T is not supported", expr)
+}
+
+func (g *generator) genericTypeString(file *sourceFile, base ast.Expr, arguments []ast.Expr) (string, error) {
+	result, err := g.typeString(file, base)
+	if err != nil {
+		return "", err
+	}
+
+	rendered := []string{}
+	for _, argument := range arguments {
+		value, err := g.typeString(file, argument)
+		if err != nil {
+			return "", err
+		}
+		rendered = append(rendered, value)
+	}
+	return result + "[" + strings.Join(rendered, ", ") + "]", nil
+}
+
+func (g *generator) addImport(alias string, path string) error {
+	if old, ok := g.imports[alias]; ok && old != path {
+		return fmt.Errorf("import %s is used for both %s and %s", alias, old, path)
+	}
+	g.imports[alias] = path
+	return nil
+}
+
+/*
+* methodName: exported name of handler, name is built from method and url for function literal
+* Ex: createUser => CreateUser, POST /users/{id}/avatar => PostUsersIdAvatar
+ */
+func (g *generator) methodName(handlerName string, method string, url string) string {
+	name := exportName(handlerName)
+	if name == "" {
+		name = exportName(strings.ToLower(method))
+		for _, segment := range strings.Split(url, "/") {
+			segment = strings.Trim(segment, "{}*")
+			segment, _, _ = strings.Cut(segment, ":")
+			for _, word := range strings.FieldsFunc(segment, func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+			}) {
+				name += exportName(word)
+			}
+		}
+	}
+
+	if reservedNames[name] {
+		name += "API"
+	}
+	g.names[name]++
+	if count := g.names[name]; count > 1 {
+		name += strconv.Itoa(count)
+	}
+	return name
+}
+
+func (g *generator) render(packageName string) ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString("// Code generated by core-client-gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buffer, "package %s\n\n", packageName)
+
+	aliases := make([]string, 0, len(g.imports))
+	for alias := range g.imports {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	buffer.WriteString("import (\n")
+	for _, alias := range aliases {
+		path := g.imports[alias]
+		if filepath.Base(path) == alias {
+			fmt.Fprintf(&buffer, "\t%q\n", path)
+		} else {
+			fmt.Fprintf(&buffer, "\t%s %q\n", alias, path)
+		}
+	}
+	buffer.WriteString(")\n\n")
+
+	buffer.WriteString(`/*
+* Client: typed client of apis, each method calls one api and returns data of response
+ */
+type Client struct {
+	BaseUrl string
+	// Prepare is called before each request to set headers, retry or proxy of builder
+	Prepare func(builder core.HttpClientBuilder) core.HttpClientBuilder
+}
+
+/*
+* NewClient: create client of service
+* @param baseUrl: url of service. Ex: http://user-service:8080
+* @return *Client
+ */
+func NewClient(baseUrl string) *Client {
+	return &Client{BaseUrl: baseUrl}
+}
+
+func (client *Client) builder(ctx core.Context) core.HttpClientBuilder {
+	builder := core.NewClient().SetContext(ctx)
+	if client.Prepare != nil {
+		builder = client.Prepare(builder)
+	}
+	return builder
+}
+`)
+
+	for _, route := range g.routes {
+		fmt.Fprintf(&buffer, `
+/*
+* %s: %s %s
+ */
+func (client *Client) %s(ctx core.Context, request %s) (%s, core.Error) {
+	return core.CallInternalAPI[%s, %s](client.builder(ctx), client.BaseUrl, %q, %q, request)
+}
+`, route.name, route.method, route.pattern, route.name, route.request, route.response, route.request, route.response, route.method, route.pattern)
+	}
+
+	source, err := format.Source(buffer.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format client: %v\n%s", err, buffer.String())
+	}
+	return source, nil
+}
+
+/*
+* resolveImportPath: import path of dir from module path in go.mod
+ */
+func resolveImportPath(dir string) (string, error) {
+	for root := dir; ; {
+		data, err := os.ReadFile(filepath.Join(root, "go.mod"))
+		if err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				line = strings.TrimSpace(line)
+				if modulePath, ok := strings.CutPrefix(line, "module "); ok {
+					relative, _ := filepath.Rel(root, dir)
+					return strings.TrimSuffix(strings.Trim(modulePath, `"`)+"/"+filepath.ToSlash(relative), "/."), nil
+				}
+			}
+			return "", fmt.Errorf("module path is not found in %s", filepath.Join(root, "go.mod"))
+		}
+
+		parent := filepath.Dir(root)
+		if parent == root {
+			return "", errors.New("go.mod is not found, set -import flag")
+		}
+		root = parent
+	}
+}
+
+func flattenFields(fields *ast.FieldList) []ast.Expr {
+	if fields == nil {
+		return nil
+	}
+
+	result := []ast.Expr{}
+	for _, field := range fields.List {
+		count := len(field.Names)
+		if count == 0 {
+			count = 1
+		}
+		for i := 0; i < count; i++ {
+			result = append(result, field.Type)
+		}
+	}
+	return result
+}
+
+func stringLiteral(expr ast.Expr) (string, bool) {
+	literal, ok := expr.(*ast.BasicLit)
+	if !ok || literal.Kind != token.STRING {
+		return "", false
+	}
+	value, err := strconv.Unquote(literal.Value)
+	return value, err == nil
+}
+
+func methodLiteral(expr ast.Expr) (string, bool) {
+	if selector, ok := expr.(*ast.SelectorExpr); ok {
+		method, ok := httpMethods[selector.Sel.Name]
+		return method, ok
+	}
+	value, ok := stringLiteral(expr)
+	return strings.ToUpper(value), ok
+}
+
+func joinUrl(prefix string, url string) string {
+	if url == "" {
+		return prefix
+	}
+	return prefix + "/" + strings.TrimPrefix(url, "/")
+}
+
+func exportName(name string) string {
+	if name == "" {
+		return ""
+	}
+	runes := []rune(name)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
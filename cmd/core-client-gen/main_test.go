@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSource = `package api
+
+import (
+	"net/http"
+
+	"github.com/dangviethung096/core"
+)
+
+type GetUserRequest struct {
+	Id int64 ` + "`path:\"id\"`" + `
+}
+
+type SearchRequest struct {
+	Name string ` + "`query:\"name\"`" + `
+}
+
+type User struct {
+	Id   int64
+	Name string
+}
+
+func getUser(ctx *core.HttpContext, request GetUserRequest) (User, core.HttpError) {
+	return User{Id: request.Id}, nil
+}
+
+func init() {
+	admin := core.Group("/admin/")
+	users := admin.Group("/users")
+
+	core.RegisterTypedAPI("/users/{id:int}", http.MethodGet, getUser)
+	core.RegisterGroupAPI(users, "/search", "post", func(ctx *core.HttpContext, request SearchRequest) (core.HttpResponse, core.HttpError) {
+		return nil, nil
+	}).SetTags("user").SetResponseModel([]User{})
+}
+`
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "api.go"), []byte(testSource), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := generate(options{
+		dir:        dir,
+		out:        filepath.Join(dir, "client", "client_gen.go"),
+		importPath: "example.com/service/api",
+	})
+	if err != nil {
+		t.Fatalf("Generate fail: %v", err)
+	}
+
+	expected := []string{
+		"package client",
+		`"example.com/service/api"`,
+		`"github.com/dangviethung096/core"`,
+		"func (client *Client) GetUser(ctx core.Context, request api.GetUserRequest) (api.User, core.Error)",
+		`core.CallInternalAPI[api.GetUserRequest, api.User](client.builder(ctx), client.BaseUrl, "GET", "/users/{id:int}", request)`,
+		"func (client *Client) PostAdminUsersSearch(ctx core.Context, request api.SearchRequest) ([]api.User, core.Error)",
+		`"POST", "/admin/users/search"`,
+	}
+	for _, item := range expected {
+		if !strings.Contains(string(source), item) {
+			t.Errorf("Expected %q in client:\n%s", item, source)
+		}
+	}
+}
+
+func TestGenerate_SamePackage(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "api.go"), []byte(testSource), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := generate(options{dir: dir, out: filepath.Join(dir, "client_gen.go")})
+	if err != nil {
+		t.Fatalf("Generate fail: %v", err)
+	}
+
+	if !strings.Contains(string(source), "package api") || !strings.Contains(string(source), "request GetUserRequest) (User, core.Error)") {
+		t.Errorf("Expected unqualified types in package api:\n%s", source)
+	}
+}
@@ -0,0 +1,150 @@
+// Command swagger-ui-dist downloads assets of swagger-ui-dist npm package into a folder,
+// so package core embeds them and swagger ui page loads no script from other origin.
+//
+// Package is checked by sha512 integrity of npm registry before files are written.
+//
+// Usage in package core:
+//
+//	//go:generate go run ./cmd/swagger-ui-dist -version 5.17.14 -out swagger-ui
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const registryUrl = "https://registry.npmjs.org/swagger-ui-dist/"
+
+// Files of package which are used by swagger ui page
+var assetFiles = []string{"swagger-ui.css", "swagger-ui-bundle.js"}
+
+var client = &http.Client{Timeout: time.Minute}
+
+func main() {
+	version := flag.String("version", "", "exact version of swagger-ui-dist")
+	out := flag.String("out", "swagger-ui", "output folder of assets")
+	flag.Parse()
+
+	if *version == "" {
+		log.Fatal("swagger-ui-dist: version is required")
+	}
+
+	files, err := download(*version)
+	if err != nil {
+		log.Fatalf("swagger-ui-dist: %v", err)
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(*out, name), data, 0644); err != nil {
+			log.Fatalf("swagger-ui-dist: %v", err)
+		}
+	}
+}
+
+/*
+* download: find tarball of version in npm registry, check its integrity and extract asset files
+ */
+func download(version string) (map[string][]byte, error) {
+	metadata, err := get(registryUrl + version)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest struct {
+		Dist struct {
+			Tarball   string `json:"tarball"`
+			Integrity string `json:"integrity"`
+		} `json:"dist"`
+	}
+	if err := json.Unmarshal(metadata, &manifest); err != nil {
+		return nil, err
+	}
+
+	tarball, err := get(manifest.Dist.Tarball)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkIntegrity(tarball, manifest.Dist.Integrity); err != nil {
+		return nil, err
+	}
+	return extract(tarball)
+}
+
+func get(url string) ([]byte, error) {
+	response, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get %s fail: status = %d", url, response.StatusCode)
+	}
+	return io.ReadAll(response.Body)
+}
+
+/*
+* checkIntegrity: compare sha512 of data with subresource integrity of npm registry ("sha512-<base64>")
+ */
+func checkIntegrity(data []byte, integrity string) error {
+	expected, found := strings.CutPrefix(integrity, "sha512-")
+	if !found {
+		return fmt.Errorf("unsupported integrity: %s", integrity)
+	}
+
+	sum := sha512.Sum512(data)
+	if base64.StdEncoding.EncodeToString(sum[:]) != expected {
+		return errors.New("integrity of tarball does not match registry")
+	}
+	return nil
+}
+
+/*
+* extract: read asset files in "package" folder of gzip tarball
+ */
+func extract(tarball []byte) (map[string][]byte, error) {
+	gzipReader, err := gzip.NewReader(bytes.NewReader(tarball))
+	if err != nil {
+		return nil, err
+	}
+	defer gzipReader.Close()
+
+	files := map[string][]byte{}
+	reader := tar.NewReader(gzipReader)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range assetFiles {
+			if header.Name == "package/"+name {
+				if files[name], err = io.ReadAll(reader); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	for _, name := range assetFiles {
+		if _, ok := files[name]; !ok {
+			return nil, fmt.Errorf("tarball has no %s", name)
+		}
+	}
+	return files, nil
+}
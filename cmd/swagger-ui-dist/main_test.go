@@ -0,0 +1,56 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha512"
+	"encoding/base64"
+	"testing"
+)
+
+func testTarball(files map[string]string) []byte {
+	var buffer bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buffer)
+	writer := tar.NewWriter(gzipWriter)
+	for name, content := range files {
+		writer.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))})
+		writer.Write([]byte(content))
+	}
+	writer.Close()
+	gzipWriter.Close()
+	return buffer.Bytes()
+}
+
+func TestExtract(t *testing.T) {
+	tarball := testTarball(map[string]string{
+		"package/swagger-ui.css":       "body {}",
+		"package/swagger-ui-bundle.js": "var SwaggerUIBundle;",
+		"package/index.html":           "<html></html>",
+	})
+
+	files, err := extract(tarball)
+	if err != nil || len(files) != 2 || string(files["swagger-ui-bundle.js"]) != "var SwaggerUIBundle;" {
+		t.Fatalf("Expected css and bundle only, got %v, error = %v", files, err)
+	}
+
+	if _, err := extract(testTarball(map[string]string{"package/swagger-ui.css": "body {}"})); err == nil {
+		t.Errorf("Expected error for tarball without bundle")
+	}
+}
+
+func TestCheckIntegrity(t *testing.T) {
+	data := []byte("tarball")
+	sum := sha512.Sum512(data)
+	integrity := "sha512-" + base64.StdEncoding.EncodeToString(sum[:])
+
+	if err := checkIntegrity(data, integrity); err != nil {
+		t.Errorf("Expected integrity to match, got %v", err)
+	}
+	if err := checkIntegrity([]byte("changed"), integrity); err == nil {
+		t.Errorf("Expected changed tarball to be rejected")
+	}
+	if err := checkIntegrity(data, "sha1-abc"); err == nil {
+		t.Errorf("Expected sha1 integrity to be rejected")
+	}
+}
@@ -0,0 +1,211 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+/*
+* Codec: encode response body and decode request body of a media type
+ */
+type Codec interface {
+	ContentType() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+var codecMutex sync.RWMutex
+
+// Media type -> codec
+var codecs = map[string]Codec{
+	JSON_CONTENT_TYPE:      jsonCodec{},
+	XML_CONTENT_TYPE:       xmlCodec{},
+	TEXT_XML_CONTENT_TYPE:  xmlCodec{},
+	MSGPACK_CONTENT_TYPE:   msgpackCodec{},
+	X_MSGPACK_CONTENT_TYPE: msgpackCodec{},
+	CBOR_CONTENT_TYPE:      cborCodec{},
+}
+
+// Codec which is used when client accepts any media type
+var defaultCodec Codec = jsonCodec{}
+
+/*
+* RegisterCodec: register or overwrite codec of media type
+* @param codec: codec which is used for codec.ContentType()
+* @param aliases: other media types which are handled by codec. Ex: text/xml for application/xml
+ */
+func RegisterCodec(codec Codec, aliases ...string) {
+	codecMutex.Lock()
+	defer codecMutex.Unlock()
+	codecs[codec.ContentType()] = codec
+	for _, alias := range aliases {
+		codecs[alias] = codec
+	}
+}
+
+/*
+* GetCodec: get codec of media type, parameters of media type are ignored
+* @param contentType: media type. Ex: application/json; charset=utf-8
+* @return Codec: nil if there is no codec of media type
+ */
+func GetCodec(contentType string) Codec {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil
+	}
+
+	codecMutex.RLock()
+	defer codecMutex.RUnlock()
+	return codecs[mediaType]
+}
+
+/*
+* negotiateCodec: choose codec of media type in Accept header
+* Default codec (json) is kept unless other codec has higher quality than json and wildcard media types
+* If client accepts json, other codec must also be the first choice of client,
+* so browser which prefers text/html and accepts xml and any type with lower quality gets json
+ */
+func negotiateCodec(accept string) Codec {
+	if accept == BLANK {
+		return defaultCodec
+	}
+
+	type acceptItem struct {
+		mediaType string
+		quality   float64
+	}
+
+	items := []acceptItem{}
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err := strconv.ParseFloat(value, 64); err == nil {
+				quality = q
+			}
+		}
+
+		if quality > 0 {
+			items = append(items, acceptItem{mediaType: mediaType, quality: quality})
+		}
+	}
+	if len(items) == 0 {
+		return defaultCodec
+	}
+
+	// Keep order of header for items which have the same quality
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].quality > items[j].quality
+	})
+
+	codecMutex.RLock()
+	defer codecMutex.RUnlock()
+
+	defaultQuality := 0.0
+	for _, item := range items {
+		if item.mediaType == "*/*" || item.mediaType == "application/*" || codecs[item.mediaType] == defaultCodec {
+			defaultQuality = max(defaultQuality, item.quality)
+		}
+	}
+
+	for _, item := range items {
+		codec, ok := codecs[item.mediaType]
+		if !ok || codec == defaultCodec {
+			continue
+		}
+
+		if item.quality > defaultQuality && (defaultQuality == 0 || item.quality == items[0].quality) {
+			return codec
+		}
+		break
+	}
+
+	return defaultCodec
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string {
+	return JSON_CONTENT_TYPE
+}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+type xmlCodec struct{}
+
+func (xmlCodec) ContentType() string {
+	return XML_CONTENT_TYPE
+}
+
+func (xmlCodec) Marshal(v any) ([]byte, error) {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+func (xmlCodec) Unmarshal(data []byte, v any) error {
+	return xml.Unmarshal(data, v)
+}
+
+/*
+* msgpackCodec: json tag is used as field name, so the same request and response struct is used for all codecs
+ */
+type msgpackCodec struct{}
+
+func (msgpackCodec) ContentType() string {
+	return MSGPACK_CONTENT_TYPE
+}
+
+func (msgpackCodec) Marshal(v any) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := msgpack.NewEncoder(&buffer)
+	encoder.SetCustomStructTag("json")
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v any) error {
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	decoder.SetCustomStructTag("json")
+	return decoder.Decode(v)
+}
+
+/*
+* cborCodec: cbor library uses json tag when field has no cbor tag
+ */
+type cborCodec struct{}
+
+func (cborCodec) ContentType() string {
+	return CBOR_CONTENT_TYPE
+}
+
+func (cborCodec) Marshal(v any) ([]byte, error) {
+	return cbor.Marshal(v)
+}
+
+func (cborCodec) Unmarshal(data []byte, v any) error {
+	return cbor.Unmarshal(data, v)
+}
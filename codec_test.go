@@ -0,0 +1,98 @@
+package core
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testCodecRequest struct {
+	Name string `json:"name"`
+}
+
+func TestNegotiateCodec(t *testing.T) {
+	testCases := map[string]string{
+		"":                                  JSON_CONTENT_TYPE,
+		"*/*":                               JSON_CONTENT_TYPE,
+		"application/xml":                   XML_CONTENT_TYPE,
+		"text/html, application/cbor;q=0.9": CBOR_CONTENT_TYPE,
+		"application/json;q=0.5, application/x-msgpack": MSGPACK_CONTENT_TYPE,
+		"image/png":                         JSON_CONTENT_TYPE,
+		"application/xml, application/json": JSON_CONTENT_TYPE,
+		"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8": JSON_CONTENT_TYPE,
+	}
+
+	for accept, contentType := range testCases {
+		if codec := negotiateCodec(accept); codec.ContentType() != contentType {
+			t.Errorf("Accept %s: expected %s, got %s", accept, contentType, codec.ContentType())
+		}
+	}
+}
+
+func TestCodec_DecodeRequestAndNegotiateResponse(t *testing.T) {
+	RegisterAPI("/test/codec/echo", http.MethodPost, func(ctx *HttpContext, request testCodecRequest) (HttpResponse, HttpError) {
+		return NewDefaultHttpResponse(request.Name), nil
+	})
+
+	body, _ := msgpackCodec{}.Marshal(testCodecRequest{Name: "core"})
+	request := httptest.NewRequest(http.MethodPost, "/test/codec/echo", bytes.NewReader(body))
+	request.Header.Set(CONTENT_TYPE_KEY, MSGPACK_CONTENT_TYPE)
+	request.Header.Set(ACCEPT_KEY, XML_CONTENT_TYPE)
+	recorder := httptest.NewRecorder()
+	dispatchRequest(recorder, request)
+
+	if contentType := recorder.Header().Get(CONTENT_TYPE_KEY); contentType != XML_CONTENT_TYPE {
+		t.Errorf("Expected content type %s, got %s", XML_CONTENT_TYPE, contentType)
+	}
+
+	var response struct {
+		Data string `xml:"data"`
+	}
+	if err := xml.Unmarshal(recorder.Body.Bytes(), &response); err != nil || response.Data != "core" {
+		t.Errorf("Expected data core, got %s", recorder.Body.String())
+	}
+}
+
+func TestCodec_ForcedResponseContentType(t *testing.T) {
+	RegisterAPI("/test/codec/forced", http.MethodGet, func(ctx *HttpContext, request testDispatchRequest) (HttpResponse, HttpError) {
+		response := NewDefaultHttpResponse(map[string]int{"count": 1})
+		response.SetResponseContentType(CBOR_CONTENT_TYPE)
+		return response, nil
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/test/codec/forced", nil)
+	request.Header.Set(ACCEPT_KEY, JSON_CONTENT_TYPE)
+	recorder := httptest.NewRecorder()
+	dispatchRequest(recorder, request)
+
+	if contentType := recorder.Header().Get(CONTENT_TYPE_KEY); contentType != CBOR_CONTENT_TYPE {
+		t.Errorf("Expected content type %s, got %s", CBOR_CONTENT_TYPE, contentType)
+	}
+
+	var response struct {
+		Data map[string]int `json:"data"`
+	}
+	if err := (cborCodec{}).Unmarshal(recorder.Body.Bytes(), &response); err != nil || response.Data["count"] != 1 {
+		t.Errorf("Expected cbor body with count 1, got %q", strings.TrimSpace(recorder.Body.String()))
+	}
+}
+
+func TestCodec_BrowserAcceptAndMapData(t *testing.T) {
+	RegisterAPI("/test/codec/map", http.MethodGet, func(ctx *HttpContext, request testDispatchRequest) (HttpResponse, HttpError) {
+		return NewDefaultHttpResponse(map[string]int{"count": 1}), nil
+	})
+
+	for _, accept := range []string{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", XML_CONTENT_TYPE} {
+		request := httptest.NewRequest(http.MethodGet, "/test/codec/map", nil)
+		request.Header.Set(ACCEPT_KEY, accept)
+		recorder := httptest.NewRecorder()
+		dispatchRequest(recorder, request)
+
+		if recorder.Code != http.StatusOK || recorder.Header().Get(CONTENT_TYPE_KEY) != JSON_CONTENT_TYPE || !strings.Contains(recorder.Body.String(), `"count":1`) {
+			t.Errorf("Accept %s: expected json response, got %d %s %s", accept, recorder.Code, recorder.Header().Get(CONTENT_TYPE_KEY), recorder.Body.String())
+		}
+	}
+}
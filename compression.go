@@ -0,0 +1,372 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gorilla/websocket"
+)
+
+// Content encodings which are supported by compression
+const (
+	ENCODING_BROTLI  = "br"
+	ENCODING_GZIP    = "gzip"
+	ENCODING_DEFLATE = "deflate"
+)
+
+// Header keys of compression
+const (
+	ACCEPT_ENCODING_KEY  = "Accept-Encoding"
+	CONTENT_ENCODING_KEY = "Content-Encoding"
+	CONTENT_LENGTH_KEY   = "Content-Length"
+	CONTENT_RANGE_KEY    = "Content-Range"
+)
+
+// Response which is smaller than this size (bytes) is not compressed
+const DEFAULT_COMPRESSION_MIN_SIZE = 1024
+
+// Encodings in order of preference when client accepts them with the same quality
+var compressionEncodings = []string{ENCODING_BROTLI, ENCODING_GZIP, ENCODING_DEFLATE}
+
+// Content types which are already compressed
+var compressedContentTypePrefixes = []string{
+	"image/",
+	"video/",
+	"audio/",
+	"font/woff",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/x-7z-compressed",
+	"application/x-rar-compressed",
+	"application/x-bzip2",
+	"application/zstd",
+	"application/pdf",
+	"application/octet-stream",
+	"text/event-stream",
+	MSGPACK_CONTENT_TYPE,
+	CBOR_CONTENT_TYPE,
+}
+
+var compressorPools sync.Map
+
+/*
+* compressHandler: compress response of handler by encoding in Accept-Encoding header
+* Handler is returned without change if compression is not used in config
+ */
+func compressHandler(handler http.Handler) http.Handler {
+	if !Config.Compression.Use {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Websocket connection is hijacked and head response has no body
+		if websocket.IsWebSocketUpgrade(r) || r.Method == http.MethodHead {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		encoding := negotiateEncoding(r.Header.Get(ACCEPT_ENCODING_KEY))
+		cw := &compressWriter{
+			writer:   w,
+			encoding: encoding,
+			minSize:  Config.Compression.GetMinSize(),
+			level:    compressionLevel(Config.Compression, encoding),
+		}
+		defer cw.Close()
+		handler.ServeHTTP(cw, r)
+	})
+}
+
+/*
+* negotiateEncoding: choose encoding which has the highest quality in Accept-Encoding header
+* @return string: blank if client accepts no supported encoding
+ */
+func negotiateEncoding(acceptEncoding string) string {
+	qualities := map[string]float64{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		quality := 1.0
+		if key, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(key) == "q" {
+			if q, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				quality = q
+			}
+		}
+
+		if name == "*" {
+			for _, encoding := range compressionEncodings {
+				if _, ok := qualities[encoding]; !ok {
+					qualities[encoding] = quality
+				}
+			}
+			continue
+		}
+		qualities[name] = quality
+	}
+
+	result := BLANK
+	bestQuality := 0.0
+	for _, encoding := range compressionEncodings {
+		if quality := qualities[encoding]; quality > bestQuality {
+			result = encoding
+			bestQuality = quality
+		}
+	}
+	return result
+}
+
+/*
+* compressWriter: buffer the beginning of response to decide if it is compressed
+* Response is compressed when it is bigger than min size and its content type is not compressed yet
+ */
+type compressWriter struct {
+	writer      http.ResponseWriter
+	encoding    string
+	minSize     int
+	level       int
+	statusCode  int
+	wroteHeader bool
+	decided     bool
+	buffer      bytes.Buffer
+	compressor  io.WriteCloser
+}
+
+func (cw *compressWriter) Header() http.Header {
+	return cw.writer.Header()
+}
+
+func (cw *compressWriter) WriteHeader(statusCode int) {
+	if cw.wroteHeader {
+		return
+	}
+
+	// Informational status is sent immediately
+	if statusCode >= 100 && statusCode < 200 {
+		cw.writer.WriteHeader(statusCode)
+		return
+	}
+
+	cw.wroteHeader = true
+	cw.statusCode = statusCode
+}
+
+func (cw *compressWriter) Write(data []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+
+	if cw.decided {
+		if cw.compressor != nil {
+			return cw.compressor.Write(data)
+		}
+		return cw.writer.Write(data)
+	}
+
+	cw.buffer.Write(data)
+	if cw.buffer.Len() >= cw.minSize {
+		if err := cw.decide(); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+/*
+* Flush: response is decided with buffered data and flushed to client
+ */
+func (cw *compressWriter) Flush() {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+
+	if !cw.decided {
+		cw.decide()
+	}
+
+	if flusher, ok := cw.compressor.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+
+	if flusher, ok := cw.writer.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+/*
+* Close: write remaining data and close compressor
+ */
+func (cw *compressWriter) Close() error {
+	if !cw.wroteHeader {
+		// Handler writes nothing, response is finished by net/http
+		return nil
+	}
+
+	if !cw.decided {
+		if err := cw.decide(); err != nil {
+			return err
+		}
+	}
+
+	if cw.compressor != nil {
+		err := cw.compressor.Close()
+		putCompressor(cw.encoding, cw.level, cw.compressor)
+		cw.compressor = nil
+		return err
+	}
+	return nil
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.writer
+}
+
+/*
+* Hijack: support handler which takes over the connection
+ */
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := cw.writer.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	cw.decided = true
+	return hijacker.Hijack()
+}
+
+/*
+* decide: write header and buffered data, compressor is created if response should be compressed
+ */
+func (cw *compressWriter) decide() error {
+	cw.decided = true
+	header := cw.writer.Header()
+	if cw.shouldCompress() {
+		header.Add(VARY_KEY, ACCEPT_ENCODING_KEY)
+		if cw.encoding != BLANK && cw.buffer.Len() >= cw.minSize {
+			header.Set(CONTENT_ENCODING_KEY, cw.encoding)
+			header.Del(CONTENT_LENGTH_KEY)
+			// Strong ETag is computed from identity body, compressed body must not share it
+			if etag := header.Get(ETAG_KEY); strings.HasPrefix(etag, `"`) {
+				header.Set(ETAG_KEY, "W/"+etag)
+			}
+			cw.compressor = getCompressor(cw.encoding, cw.level, cw.writer)
+		}
+	}
+
+	cw.writer.WriteHeader(cw.statusCode)
+	if cw.buffer.Len() == 0 {
+		return nil
+	}
+
+	var err error
+	if cw.compressor != nil {
+		_, err = cw.compressor.Write(cw.buffer.Bytes())
+	} else {
+		_, err = cw.writer.Write(cw.buffer.Bytes())
+	}
+	cw.buffer.Reset()
+	return err
+}
+
+func (cw *compressWriter) shouldCompress() bool {
+	header := cw.writer.Header()
+	if header.Get(CONTENT_ENCODING_KEY) != BLANK || header.Get(CONTENT_RANGE_KEY) != BLANK {
+		return false
+	}
+
+	switch cw.statusCode {
+	case http.StatusNoContent, http.StatusNotModified, http.StatusPartialContent:
+		return false
+	}
+
+	contentType := header.Get(CONTENT_TYPE_KEY)
+	if contentType == BLANK {
+		if cw.buffer.Len() == 0 {
+			return false
+		}
+		// Set detected content type, so net/http does not detect it from compressed data
+		contentType = http.DetectContentType(cw.buffer.Bytes())
+		header.Set(CONTENT_TYPE_KEY, contentType)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, prefix := range compressedContentTypePrefixes {
+		if strings.HasPrefix(mediaType, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+* compressionLevel: level of encoding in config, it is clamped into range of encoding
+* Brotli level is 0-11, gzip and deflate level is -2 (huffman only) to 9, zero means default level
+ */
+func compressionLevel(config CompressionConfig, encoding string) int {
+	if encoding == ENCODING_BROTLI {
+		level := config.Level
+		if config.BrotliLevel != 0 {
+			level = config.BrotliLevel
+		}
+		if level == 0 {
+			return brotli.DefaultCompression
+		}
+		return min(max(level, brotli.BestSpeed), brotli.BestCompression)
+	}
+
+	level := config.Level
+	if config.GzipLevel != 0 {
+		level = config.GzipLevel
+	}
+	if level == 0 {
+		return gzip.DefaultCompression
+	}
+	return min(max(level, gzip.HuffmanOnly), gzip.BestCompression)
+}
+
+/*
+* getCompressor: get compressor of encoding from pool and reset it to write into writer
+ */
+func getCompressor(encoding string, level int, writer io.Writer) io.WriteCloser {
+	pool, _ := compressorPools.LoadOrStore(encoding+strconv.Itoa(level), &sync.Pool{})
+	if compressor := pool.(*sync.Pool).Get(); compressor != nil {
+		compressor.(interface{ Reset(io.Writer) }).Reset(writer)
+		return compressor.(io.WriteCloser)
+	}
+
+	switch encoding {
+	case ENCODING_BROTLI:
+		return brotli.NewWriterLevel(writer, level)
+	case ENCODING_GZIP:
+		compressor, err := gzip.NewWriterLevel(writer, level)
+		if err != nil {
+			compressor = gzip.NewWriter(writer)
+		}
+		return compressor
+	default:
+		compressor, err := zlib.NewWriterLevel(writer, level)
+		if err != nil {
+			compressor = zlib.NewWriter(writer)
+		}
+		return compressor
+	}
+}
+
+func putCompressor(encoding string, level int, compressor io.WriteCloser) {
+	if pool, ok := compressorPools.Load(encoding + strconv.Itoa(level)); ok {
+		pool.(*sync.Pool).Put(compressor)
+	}
+}
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	testCases := map[string]string{
+		"":                     BLANK,
+		"gzip, deflate, br":    ENCODING_BROTLI,
+		"gzip;q=1.0, br;q=0.5": ENCODING_GZIP,
+		"deflate":              ENCODING_DEFLATE,
+		"*;q=0.1, gzip;q=0":    ENCODING_BROTLI,
+		"identity":             BLANK,
+	}
+
+	for acceptEncoding, encoding := range testCases {
+		if result := negotiateEncoding(acceptEncoding); result != encoding {
+			t.Errorf("Accept-Encoding %s: expected %s, got %s", acceptEncoding, encoding, result)
+		}
+	}
+}
+
+func TestCompressionLevel(t *testing.T) {
+	testCases := []struct {
+		config   CompressionConfig
+		encoding string
+		level    int
+	}{
+		{CompressionConfig{}, ENCODING_BROTLI, brotli.DefaultCompression},
+		{CompressionConfig{}, ENCODING_GZIP, gzip.DefaultCompression},
+		// Brotli level is out of range of gzip and deflate
+		{CompressionConfig{Level: 11}, ENCODING_BROTLI, 11},
+		{CompressionConfig{Level: 11}, ENCODING_GZIP, gzip.BestCompression},
+		{CompressionConfig{Level: 11}, ENCODING_DEFLATE, gzip.BestCompression},
+		{CompressionConfig{Level: -5}, ENCODING_BROTLI, brotli.BestSpeed},
+		{CompressionConfig{Level: 4, BrotliLevel: 10, GzipLevel: 2}, ENCODING_BROTLI, 10},
+		{CompressionConfig{Level: 4, BrotliLevel: 10, GzipLevel: 2}, ENCODING_DEFLATE, 2},
+	}
+
+	for _, testCase := range testCases {
+		level := compressionLevel(testCase.config, testCase.encoding)
+		if level != testCase.level {
+			t.Errorf("%+v %s: expected level %d, got %d", testCase.config, testCase.encoding, testCase.level, level)
+		}
+
+		// Compressor is created with level of encoding
+		var buffer bytes.Buffer
+		compressor := getCompressor(testCase.encoding, level, &buffer)
+		compressor.Write([]byte("data"))
+		if err := compressor.Close(); err != nil || buffer.Len() == 0 {
+			t.Errorf("%+v %s: expected compressed data, got error = %v", testCase.config, testCase.encoding, err)
+		}
+	}
+}
+
+func TestCompressHandler(t *testing.T) {
+	compressionConfig := Config.Compression
+	Config.Compression.Use = true
+	defer func() { Config.Compression = compressionConfig }()
+
+	largeBody := strings.Repeat(`{"name":"core"}`, 200)
+	handler := compressHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/large":
+			w.Header().Set(CONTENT_TYPE_KEY, JSON_CONTENT_TYPE)
+			w.Header().Set(ETAG_KEY, `"large"`)
+			io.WriteString(w, largeBody)
+		case "/small":
+			w.Header().Set(CONTENT_TYPE_KEY, JSON_CONTENT_TYPE)
+			io.WriteString(w, `{"name":"core"}`)
+		case "/image":
+			w.Header().Set(CONTENT_TYPE_KEY, "image/png")
+			io.WriteString(w, largeBody)
+		}
+	}))
+
+	request := httptest.NewRequest(http.MethodGet, "/large", nil)
+	request.Header.Set(ACCEPT_ENCODING_KEY, "gzip")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	if recorder.Header().Get(CONTENT_ENCODING_KEY) != ENCODING_GZIP {
+		t.Fatalf("Expected gzip encoding, got %s", recorder.Header().Get(CONTENT_ENCODING_KEY))
+	}
+
+	if recorder.Header().Get(VARY_KEY) != ACCEPT_ENCODING_KEY {
+		t.Errorf("Expected Vary: Accept-Encoding, got %s", recorder.Header().Get(VARY_KEY))
+	}
+
+	if etag := recorder.Header().Get(ETAG_KEY); etag != `W/"large"` {
+		t.Errorf("Expected strong ETag to be weakened for gzip body, got %s", etag)
+	}
+
+	reader, err := gzip.NewReader(recorder.Body)
+	if err != nil {
+		t.Fatalf("Create gzip reader fail: %v", err)
+	}
+	if data, _ := io.ReadAll(reader); string(data) != largeBody {
+		t.Errorf("Expected decompressed body equals original body")
+	}
+
+	for _, path := range []string{"/small", "/image"} {
+		request = httptest.NewRequest(http.MethodGet, path, nil)
+		request.Header.Set(ACCEPT_ENCODING_KEY, "gzip")
+		recorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		if encoding := recorder.Header().Get(CONTENT_ENCODING_KEY); encoding != BLANK {
+			t.Errorf("Path %s: expected no encoding, got %s", path, encoding)
+		}
+	}
+}
@@ -2,41 +2,150 @@ package core
 
 import (
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v2"
 )
 
 type CoreConfig struct {
-	Debug             bool               `yaml:"debug"`
-	Server            ServerConfig       `yaml:"server"`
-	SecureServer      SecureServerConfig `yaml:"secure_server"`
-	Context           ContextConfig      `yaml:"context"`
-	IdGenerator       IdGenerator        `yaml:"id_generator"`
-	Database          Database           `yaml:"database"`
-	SecondaryDatabase Database           `yaml:"secondary_database"`
-	NatsQueue         NatsQueue          `yaml:"nats_queue"`
-	Redis             RedisConfig        `yaml:"redis"`
-	Proxy             ProxyConfig        `yaml:"proxy"`
-	HttpClient        HttpClientConfig   `yaml:"http_client"`
-	Scheduler         SchedulerConfig    `yaml:"scheduler"`
-	Emqx              EmqxConfig         `yaml:"emqx"`
+	Debug             bool                `yaml:"debug"`
+	Server            ServerConfig        `yaml:"server"`
+	SecureServer      SecureServerConfig  `yaml:"secure_server"`
+	Context           ContextConfig       `yaml:"context"`
+	IdGenerator       IdGenerator         `yaml:"id_generator"`
+	Database          Database            `yaml:"database"`
+	SecondaryDatabase Database            `yaml:"secondary_database"`
+	NatsQueue         NatsQueue           `yaml:"nats_queue"`
+	Redis             RedisConfig         `yaml:"redis"`
+	Proxy             ProxyConfig         `yaml:"proxy"`
+	HttpClient        HttpClientConfig    `yaml:"http_client"`
+	Scheduler         SchedulerConfig     `yaml:"scheduler"`
+	Emqx              EmqxConfig          `yaml:"emqx"`
+	OpenApi           OpenApiConfig       `yaml:"open_api"`
+	Compression       CompressionConfig   `yaml:"compression"`
+	Cors              CorsConfig          `yaml:"cors"`
+	Jwt               JwtConfig           `yaml:"jwt"`
+	Authorization     AuthorizationConfig `yaml:"authorization"`
+	Session           SessionConfig       `yaml:"session"`
+	Csrf              CsrfConfig          `yaml:"csrf"`
 }
 
 type ServerConfig struct {
-	Port      int    `yaml:"port"`
-	Name      string `yaml:"name"`
-	CacheHtml bool   `yaml:"cache_html"`
+	Port                 int    `yaml:"port"`
+	Host                 string `yaml:"host"`
+	Name                 string `yaml:"name"`
+	CacheHtml            bool   `yaml:"cache_html"`
+	ShutdownTimeout      int    `yaml:"shutdown_timeout"`
+	MaxBodySize          int64  `yaml:"max_body_size"`
+	MaxUploadSize        int64  `yaml:"max_upload_size"`
+	H2C                  bool   `yaml:"h2c"`
+	ServerTimeoutsConfig `yaml:",inline"`
 }
 
+/*
+* SecureServerConfig: certificates are chosen by server name (SNI) of client, cert_file and key_file is the default one
+* Certificate files are checked for changes every cert_reload_interval seconds and reloaded on SIGHUP
+ */
 type SecureServerConfig struct {
-	Use       bool   `yaml:"use"`
-	Port      int    `yaml:"port"`
-	Name      string `yaml:"name"`
-	CacheHtml bool   `yaml:"cache_html"`
-	CertFile  string `yaml:"cert_file"`
-	KeyFile   string `yaml:"key_file"`
+	Use                  bool                `yaml:"use"`
+	Port                 int                 `yaml:"port"`
+	Host                 string              `yaml:"host"`
+	Name                 string              `yaml:"name"`
+	CacheHtml            bool                `yaml:"cache_html"`
+	CertFile             string              `yaml:"cert_file"`
+	KeyFile              string              `yaml:"key_file"`
+	MinTLSVersion        string              `yaml:"min_tls_version"`
+	CipherSuites         []string            `yaml:"cipher_suites"`
+	ClientCAFile         string              `yaml:"client_ca_file"`
+	ClientAuth           string              `yaml:"client_auth"`
+	Certificates         []CertificateConfig `yaml:"certificates"`
+	CertReloadInterval   int                 `yaml:"cert_reload_interval"`
+	ServerTimeoutsConfig `yaml:",inline"`
+}
+
+type CertificateConfig struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
+
+/*
+* Get all certificates of secure server, certificate of cert_file and key_file is the first one
+* @return: certificate files
+ */
+func (config SecureServerConfig) GetCertificates() []CertificateConfig {
+	certificates := []CertificateConfig{}
+	if config.CertFile != BLANK {
+		certificates = append(certificates, CertificateConfig{CertFile: config.CertFile, KeyFile: config.KeyFile})
+	}
+	return append(certificates, config.Certificates...)
+}
+
+/*
+* Get interval which certificate files are checked for changes
+* @return: DEFAULT_CERT_RELOAD_INTERVAL if it is not set, zero if checking is disabled
+ */
+func (config SecureServerConfig) GetCertReloadInterval() time.Duration {
+	if config.CertReloadInterval == 0 {
+		return time.Second * DEFAULT_CERT_RELOAD_INTERVAL
+	}
+	if config.CertReloadInterval < 0 {
+		return 0
+	}
+	return time.Second * time.Duration(config.CertReloadInterval)
+}
+
+/*
+* ServerTimeoutsConfig: timeouts (seconds) and header limit of http server, zero means no limit
+* Write timeout also stops event streams and streaming responses, so keep it zero if server has them
+ */
+type ServerTimeoutsConfig struct {
+	ReadTimeout       int `yaml:"read_timeout"`
+	ReadHeaderTimeout int `yaml:"read_header_timeout"`
+	WriteTimeout      int `yaml:"write_timeout"`
+	IdleTimeout       int `yaml:"idle_timeout"`
+	MaxHeaderBytes    int `yaml:"max_header_bytes"`
+}
+
+/*
+* Get address which server listens on
+* @return: host and port, host is 0.0.0.0 if it is not set
+ */
+func (config ServerConfig) GetAddress() string {
+	return serverAddress(config.Host, config.Port)
+}
+
+/*
+* Get address which secure server listens on
+* @return: host and port, host is 0.0.0.0 if it is not set
+ */
+func (config SecureServerConfig) GetAddress() string {
+	return serverAddress(config.Host, config.Port)
+}
+
+func serverAddress(host string, port int) string {
+	if host == BLANK {
+		host = DEFAULT_SERVER_HOST
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+/*
+* Get read header timeout, it protects server from clients which send header slowly
+* @return: DEFAULT_READ_HEADER_TIMEOUT if it is not set, no timeout if it is negative
+ */
+func (config ServerTimeoutsConfig) GetReadHeaderTimeout() time.Duration {
+	if config.ReadHeaderTimeout == 0 {
+		return time.Second * DEFAULT_READ_HEADER_TIMEOUT
+	}
+	if config.ReadHeaderTimeout < 0 {
+		return 0
+	}
+	return time.Second * time.Duration(config.ReadHeaderTimeout)
 }
 
 type ContextConfig struct {
@@ -66,6 +175,40 @@ func (config CoreConfig) GetContextTimeout() time.Duration {
 	return time.Duration(config.Context.Timeout) * time.Second
 }
 
+/*
+* Get shutdown timeout from core config
+* @return: time to wait for in-flight requests when server is shut down
+ */
+func (config CoreConfig) GetShutdownTimeout() time.Duration {
+	timeout := time.Second * DEFAULT_SHUTDOWN_TIMEOUT
+	if config.Server.ShutdownTimeout != 0 {
+		timeout = time.Second * time.Duration(config.Server.ShutdownTimeout)
+	}
+	return timeout
+}
+
+/*
+* Get max body size from core config
+* @return: max size of request body in bytes, negative value means no limit
+ */
+func (config CoreConfig) GetMaxBodySize() int64 {
+	if config.Server.MaxBodySize != 0 {
+		return config.Server.MaxBodySize
+	}
+	return DEFAULT_MAX_BODY_SIZE
+}
+
+/*
+* Get max upload size from core config, it is the limit of whole multipart body of file upload
+* @return: max size of upload request body in bytes, negative value means no limit
+ */
+func (config CoreConfig) GetMaxUploadSize() int64 {
+	if config.Server.MaxUploadSize != 0 {
+		return config.Server.MaxUploadSize
+	}
+	return DEFAULT_MAX_UPLOAD_SIZE
+}
+
 type IdGenerator struct {
 	Distributed bool `yaml:"distributed"`
 }
@@ -119,6 +262,247 @@ type EmqxConfig struct {
 	PrefixClient string `yaml:"prefix_client_id"`
 }
 
+/*
+* OpenApiConfig: swagger ui serves embedded assets of swagger-ui-dist SWAGGER_UI_VERSION, they are loaded from unpkg if they are not embedded
+* ui_assets_path: folder which has swagger-ui.css and swagger-ui-bundle.js, it is used instead of embedded assets
+* ui_css_integrity, ui_js_integrity: subresource integrity of assets which are loaded from unpkg
+ */
+type OpenApiConfig struct {
+	Use            bool   `yaml:"use"`
+	Path           string `yaml:"path"`
+	UiPath         string `yaml:"ui_path"`
+	Title          string `yaml:"title"`
+	Version        string `yaml:"version"`
+	Description    string `yaml:"description"`
+	UiAssetsPath   string `yaml:"ui_assets_path"`
+	UiCssIntegrity string `yaml:"ui_css_integrity"`
+	UiJsIntegrity  string `yaml:"ui_js_integrity"`
+}
+
+/*
+* GetTitle: title of api document, default is name of server
+ */
+func (openApiConfig OpenApiConfig) GetTitle() string {
+	if openApiConfig.Title != BLANK {
+		return openApiConfig.Title
+	}
+
+	if Config.Server.Name != BLANK {
+		return Config.Server.Name
+	}
+	return "API"
+}
+
+/*
+* GetVersion: version of api document, default is 1.0.0
+ */
+func (openApiConfig OpenApiConfig) GetVersion() string {
+	if openApiConfig.Version != BLANK {
+		return openApiConfig.Version
+	}
+	return "1.0.0"
+}
+
+/*
+* CompressionConfig: level is used by every encoding, brotli_level and gzip_level (gzip and deflate) override it
+* Level out of range of encoding is clamped: brotli 0-11, gzip and deflate -2 to 9, zero is default level
+ */
+type CompressionConfig struct {
+	Use         bool `yaml:"use"`
+	MinSize     int  `yaml:"min_size"`
+	Level       int  `yaml:"level"`
+	BrotliLevel int  `yaml:"brotli_level"`
+	GzipLevel   int  `yaml:"gzip_level"`
+}
+
+/*
+* GetMinSize: response which is smaller than min size (bytes) is not compressed
+ */
+func (compressionConfig CompressionConfig) GetMinSize() int {
+	if compressionConfig.MinSize > 0 {
+		return compressionConfig.MinSize
+	}
+	return DEFAULT_COMPRESSION_MIN_SIZE
+}
+
+/*
+* CorsConfig: cross origin policy of apis, uploads and websocket handshakes
+* allowed_origins: "*", exact origin, origin with "*" wildcards (https://*.example.com) or "regex:" followed by a regular expression
+* allowed_methods: methods of url are allowed if it is empty
+* allow_credentials: it is ignored when allowed_origins has "*"
+* exposed_headers: Request-Id is always exposed
+* max_age: seconds which browser caches preflight response, it is not sent if it is zero
+ */
+type CorsConfig struct {
+	Use              bool     `yaml:"use"`
+	AllowedOrigins   []string `yaml:"allowed_origins"`
+	AllowedMethods   []string `yaml:"allowed_methods"`
+	AllowedHeaders   []string `yaml:"allowed_headers"`
+	ExposedHeaders   []string `yaml:"exposed_headers"`
+	AllowCredentials bool     `yaml:"allow_credentials"`
+	MaxAge           int      `yaml:"max_age"`
+}
+
+/*
+* JwtConfig: keys and claims checks of JwtAuth
+* algorithms: allowed algorithms, default is HS256 if secret is set and RS256, ES256 if public key or jwks is set
+* jwks_cache_time: seconds which keys of jwks_url are cached, unknown kid refreshes keys earlier
+* clock_skew: seconds of leeway in exp, nbf and iat checks
+* cookie_name: cookie which has token when request has no Authorization header
+* login_url: page request without valid token is redirected to this url
+ */
+type JwtConfig struct {
+	Algorithms    []string `yaml:"algorithms"`
+	Secret        string   `yaml:"secret"`
+	PublicKeyFile string   `yaml:"public_key_file"`
+	JwksFile      string   `yaml:"jwks_file"`
+	JwksUrl       string   `yaml:"jwks_url"`
+	JwksCacheTime int      `yaml:"jwks_cache_time"`
+	Issuer        string   `yaml:"issuer"`
+	Audience      string   `yaml:"audience"`
+	ClockSkew     int      `yaml:"clock_skew"`
+	CookieName    string   `yaml:"cookie_name"`
+	LoginUrl      string   `yaml:"login_url"`
+}
+
+/*
+* GetJwksCacheTime: DEFAULT_JWKS_CACHE_TIME if it is not set
+ */
+func (jwtConfig JwtConfig) GetJwksCacheTime() time.Duration {
+	if jwtConfig.JwksCacheTime > 0 {
+		return time.Second * time.Duration(jwtConfig.JwksCacheTime)
+	}
+	return time.Second * DEFAULT_JWKS_CACHE_TIME
+}
+
+func (jwtConfig JwtConfig) GetClockSkew() time.Duration {
+	return time.Second * time.Duration(jwtConfig.ClockSkew)
+}
+
+/*
+* AuthorizationConfig: forbidden_url is page which forbidden page request is redirected to
+* cache_time: seconds which permissions of role are cached
+ */
+type AuthorizationConfig struct {
+	ForbiddenUrl string `yaml:"forbidden_url"`
+	CacheTime    int    `yaml:"cache_time"`
+}
+
+/*
+* GetCacheTime: DEFAULT_PERMISSION_CACHE_TIME if it is not set, no cache if it is negative
+ */
+func (authorizationConfig AuthorizationConfig) GetCacheTime() time.Duration {
+	if authorizationConfig.CacheTime == 0 {
+		return time.Second * DEFAULT_PERMISSION_CACHE_TIME
+	}
+	return time.Second * time.Duration(authorizationConfig.CacheTime)
+}
+
+/*
+* SessionConfig: store is memory, redis or database
+* idle_timeout: seconds which session expires after the last request (sliding expiry)
+* absolute_timeout: seconds which session expires after it is created, even if it is used
+* Timeout is default if it is not set and disabled if it is negative
+ */
+type SessionConfig struct {
+	Use             bool   `yaml:"use"`
+	Store           string `yaml:"store"`
+	CookieName      string `yaml:"cookie_name"`
+	Secret          string `yaml:"secret"`
+	IdleTimeout     int    `yaml:"idle_timeout"`
+	AbsoluteTimeout int    `yaml:"absolute_timeout"`
+	Domain          string `yaml:"domain"`
+	Secure          bool   `yaml:"secure"`
+	SameSite        string `yaml:"same_site"`
+}
+
+func (sessionConfig SessionConfig) GetCookieName() string {
+	if sessionConfig.CookieName != BLANK {
+		return sessionConfig.CookieName
+	}
+	return DEFAULT_SESSION_COOKIE_NAME
+}
+
+func (sessionConfig SessionConfig) GetIdleTimeout() time.Duration {
+	return sessionTimeout(sessionConfig.IdleTimeout, DEFAULT_SESSION_IDLE_TIMEOUT)
+}
+
+func (sessionConfig SessionConfig) GetAbsoluteTimeout() time.Duration {
+	return sessionTimeout(sessionConfig.AbsoluteTimeout, DEFAULT_SESSION_ABSOLUTE_TIMEOUT)
+}
+
+func sessionTimeout(timeout int, defaultTimeout int) time.Duration {
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	if timeout < 0 {
+		return 0
+	}
+	return time.Second * time.Duration(timeout)
+}
+
+/*
+* GetSameSite: SameSite of session cookie, default is lax
+ */
+func (sessionConfig SessionConfig) GetSameSite() http.SameSite {
+	return parseSameSite(sessionConfig.SameSite)
+}
+
+func parseSameSite(sameSite string) http.SameSite {
+	switch strings.ToLower(sameSite) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	}
+	return http.SameSiteLaxMode
+}
+
+/*
+* CsrfConfig: mode is double_submit (token in cookie) or synchronizer (token in session)
+* Token is sent in header_name header or field_name form field
+* exempt_paths: url prefixes which are not checked, ex: webhooks which are called by other servers
+ */
+type CsrfConfig struct {
+	Use         bool     `yaml:"use"`
+	Mode        string   `yaml:"mode"`
+	Secret      string   `yaml:"secret"`
+	CookieName  string   `yaml:"cookie_name"`
+	HeaderName  string   `yaml:"header_name"`
+	FieldName   string   `yaml:"field_name"`
+	ExemptPaths []string `yaml:"exempt_paths"`
+	Secure      bool     `yaml:"secure"`
+	SameSite    string   `yaml:"same_site"`
+}
+
+func (csrfConfig CsrfConfig) GetCookieName() string {
+	if csrfConfig.CookieName != BLANK {
+		return csrfConfig.CookieName
+	}
+	return DEFAULT_CSRF_COOKIE_NAME
+}
+
+func (csrfConfig CsrfConfig) GetHeaderName() string {
+	if csrfConfig.HeaderName != BLANK {
+		return csrfConfig.HeaderName
+	}
+	return DEFAULT_CSRF_HEADER_NAME
+}
+
+func (csrfConfig CsrfConfig) GetFieldName() string {
+	if csrfConfig.FieldName != BLANK {
+		return csrfConfig.FieldName
+	}
+	return DEFAULT_CSRF_FIELD_NAME
+}
+
+/*
+* GetSameSite: SameSite of csrf cookie, default is lax
+ */
+func (csrfConfig CsrfConfig) GetSameSite() http.SameSite {
+	return parseSameSite(csrfConfig.SameSite)
+}
+
 func loadConfigFile(configFile string) CoreConfig {
 	data, err := os.ReadFile(configFile)
 	if err != nil {
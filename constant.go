@@ -7,6 +7,9 @@ const (
 	DEFAULT_CONSUMER_TAG   = "default_consumer"
 	CONTENT_TYPE_KEY       = "Content-Type"
 	ACCEPT_KEY             = "Accept"
+	ACCEPT_LANGUAGE_KEY    = "Accept-Language"
+	ALLOW_KEY              = "Allow"
+	VARY_KEY               = "Vary"
 	REGEX_URL_PATH_ELEMENT = "[\\w-]+"
 	DEFAULT_INTEGER        = 0
 )
@@ -28,6 +31,15 @@ const (
 	ERROR_FROM_LIBRARY                 = 103
 	ERROR_CODE_FROM_DATABASE           = 104
 	ERROR_CODE_FROM_MQTT               = 105
+	ERROR_CODE_METHOD_NOT_ALLOWED      = 106
+	ERROR_CODE_INVALID_REQUEST_PARAM   = 107
+	ERROR_CODE_TIMEOUT                 = 108
+	ERROR_CODE_INTERNAL_SERVER_ERROR   = 109
+	ERROR_CODE_REQUEST_BODY_TOO_LARGE  = 110
+	ERROR_CODE_UNSUPPORTED_API_VERSION = 111
+	ERROR_CODE_UNAUTHORIZED            = 112
+	ERROR_CODE_FORBIDDEN               = 113
+	ERROR_CODE_INVALID_CSRF_TOKEN      = 114
 )
 
 // Scheduler
@@ -44,6 +56,24 @@ const (
 
 const MAX_UPLOAD_FILE_SIZE = 50 << 20
 
+// Seconds to wait for in-flight requests when server is shut down
+const DEFAULT_SHUTDOWN_TIMEOUT = 30
+
+// Default max size of request body: 10 MB
+const DEFAULT_MAX_BODY_SIZE = 10 << 20
+
+// Default max size of upload request body: 50 MB
+const DEFAULT_MAX_UPLOAD_SIZE = MAX_UPLOAD_FILE_SIZE
+
+// Default host which server listens on
+const DEFAULT_SERVER_HOST = "0.0.0.0"
+
+// Default time (seconds) which server waits for request header
+const DEFAULT_READ_HEADER_TIMEOUT = 10
+
+// Default interval (seconds) which certificate files of secure server are checked for changes
+const DEFAULT_CERT_RELOAD_INTERVAL = 60
+
 const MAX_WEBSOCKET_READ_BUFFER_SIZE = 1024
 const MAX_WEBSOCKET_WRITE_BUFFER_SIZE = 1024
 
@@ -33,4 +33,27 @@ CREATE TABLE scheduler_done (
     task_id bigint,
     operation_time text,
     status text
-);
\ No newline at end of file
+);
+
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS roles;
+
+CREATE TABLE roles (
+    id bigserial PRIMARY KEY,
+    name text,
+    parent_id bigint REFERENCES roles(id)
+);
+
+CREATE TABLE role_permissions (
+    role_id bigint REFERENCES roles(id),
+    permission text,
+    PRIMARY KEY (role_id, permission)
+);
+
+DROP TABLE IF EXISTS sessions;
+
+CREATE TABLE sessions (
+    id text PRIMARY KEY,
+    data text,
+    expires_at bigint
+);
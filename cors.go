@@ -0,0 +1,243 @@
+package core
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Header keys of cross origin resource sharing
+const (
+	ORIGIN_KEY                           = "Origin"
+	ACCESS_CONTROL_ALLOW_ORIGIN_KEY      = "Access-Control-Allow-Origin"
+	ACCESS_CONTROL_ALLOW_METHODS_KEY     = "Access-Control-Allow-Methods"
+	ACCESS_CONTROL_ALLOW_HEADERS_KEY     = "Access-Control-Allow-Headers"
+	ACCESS_CONTROL_ALLOW_CREDENTIALS_KEY = "Access-Control-Allow-Credentials"
+	ACCESS_CONTROL_EXPOSE_HEADERS_KEY    = "Access-Control-Expose-Headers"
+	ACCESS_CONTROL_MAX_AGE_KEY           = "Access-Control-Max-Age"
+	ACCESS_CONTROL_REQUEST_METHOD_KEY    = "Access-Control-Request-Method"
+	ACCESS_CONTROL_REQUEST_HEADERS_KEY   = "Access-Control-Request-Headers"
+)
+
+// Prefix of origin in allowed origins which is a regular expression
+const CORS_REGEX_ORIGIN_PREFIX = "regex:"
+
+// Methods which are allowed when url has no registered methods
+const DEFAULT_CORS_ALLOWED_METHODS = "POST, GET, OPTIONS, PUT, DELETE"
+
+var defaultCorsAllowedHeaders = []string{"Accept", "Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization"}
+
+/*
+* corsPolicy: compiled CorsConfig which is used by cors middleware and websocket handshake
+ */
+type corsPolicy struct {
+	allowAll         bool
+	origins          map[string]bool
+	patterns         []*regexp.Regexp
+	allowCredentials bool
+	allowedMethods   string
+	allowedHeaders   map[string]bool
+	allowAllHeaders  bool
+	exposedHeaders   string
+	maxAge           string
+}
+
+var currentCorsPolicy *corsPolicy
+
+/*
+* UseCors: answer cross origin requests of apis, uploads and websocket handshakes by options
+* Cors middleware is executed before other common middlewares, so preflight request is answered without authentication
+* Ex: UseCors(CorsConfig{AllowedOrigins: []string{"https://*.example.com"}, AllowCredentials: true})
+* @param options: cors policy, origin is allowed if it matches any allowed origin
+ */
+func UseCors(options CorsConfig) {
+	policy := newCorsPolicy(options)
+	LogInfo("Use cors: origins = %v, credentials = %v", options.AllowedOrigins, options.AllowCredentials)
+	currentCorsPolicy = policy
+	commonApiMiddlewares = append([]ApiMiddleware{policy.middleware}, commonApiMiddlewares...)
+}
+
+/*
+* newCorsPolicy: compile allowed origins of options
+* Origin is "*", exact origin, origin with "*" wildcards (https://*.example.com) or "regex:" followed by a regular expression
+* Credentials are never allowed with "*", so cookies are only sent by listed origins
+ */
+func newCorsPolicy(options CorsConfig) *corsPolicy {
+	policy := &corsPolicy{
+		origins:          make(map[string]bool),
+		allowCredentials: options.AllowCredentials,
+		allowedMethods:   strings.Join(options.AllowedMethods, ", "),
+		allowedHeaders:   make(map[string]bool),
+	}
+
+	for _, origin := range options.AllowedOrigins {
+		origin = strings.TrimSpace(origin)
+		switch {
+		case origin == "*":
+			policy.allowAll = true
+		case strings.HasPrefix(origin, CORS_REGEX_ORIGIN_PREFIX):
+			pattern, err := regexp.Compile(strings.TrimPrefix(origin, CORS_REGEX_ORIGIN_PREFIX))
+			if err != nil {
+				LogFatal("Invalid cors origin %s: %v", origin, err)
+			}
+			policy.patterns = append(policy.patterns, pattern)
+		case strings.Contains(origin, "*"):
+			expression := strings.ReplaceAll(regexp.QuoteMeta(strings.ToLower(origin)), `\*`, `[^/]*`)
+			policy.patterns = append(policy.patterns, regexp.MustCompile("^"+expression+"$"))
+		default:
+			policy.origins[strings.ToLower(strings.TrimSuffix(origin, "/"))] = true
+		}
+	}
+
+	if policy.allowAll && policy.allowCredentials {
+		// Any website could send cookies of user and read the response
+		LogWarning("Cors credentials are not allowed with origin *, allow_credentials is ignored")
+		policy.allowCredentials = false
+	}
+
+	allowedHeaders := options.AllowedHeaders
+	if len(allowedHeaders) == 0 {
+		allowedHeaders = defaultCorsAllowedHeaders
+	}
+	for _, header := range allowedHeaders {
+		if header == "*" {
+			policy.allowAllHeaders = true
+		}
+		policy.allowedHeaders[http.CanonicalHeaderKey(header)] = true
+	}
+
+	// Request id is always exposed, so client can report it
+	exposedHeaders := []string{"Request-Id"}
+	for _, header := range options.ExposedHeaders {
+		if !strings.EqualFold(header, "Request-Id") {
+			exposedHeaders = append(exposedHeaders, header)
+		}
+	}
+	policy.exposedHeaders = strings.Join(exposedHeaders, ", ")
+
+	if options.MaxAge > 0 {
+		policy.maxAge = strconv.Itoa(options.MaxAge)
+	}
+	return policy
+}
+
+/*
+* isAllowedOrigin: check if origin matches any allowed origin
+ */
+func (policy *corsPolicy) isAllowedOrigin(origin string) bool {
+	if policy.allowAll {
+		return true
+	}
+
+	origin = strings.ToLower(origin)
+	if policy.origins[origin] {
+		return true
+	}
+	for _, pattern := range policy.patterns {
+		if pattern.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+* middleware: write cors headers of allowed origin and answer preflight request
+* Request from origin which is not allowed is served without cors headers, so browser blocks its response
+ */
+func (policy *corsPolicy) middleware(ctx *HttpContext) HttpError {
+	header := ctx.rw.Header()
+	// Response depends on origin unless every origin gets the same "*" response
+	if !policy.allowAll {
+		header.Add(VARY_KEY, ORIGIN_KEY)
+	}
+
+	origin := ctx.GetRequestHeader(ORIGIN_KEY)
+	isPreflight := ctx.Method == http.MethodOptions && ctx.GetRequestHeader(ACCESS_CONTROL_REQUEST_METHOD_KEY) != BLANK
+	if origin == BLANK || !policy.isAllowedOrigin(origin) {
+		if origin != BLANK && isPreflight {
+			ctx.LogInfo("Cors origin is not allowed: origin = %s, url = %s", origin, ctx.URL)
+			header.Set("Request-Id", ctx.requestID)
+			ctx.endResponse(http.StatusForbidden, BLANK)
+			return nil
+		}
+		ctx.keepHeadersOnTimeout(VARY_KEY)
+		ctx.Next()
+		return nil
+	}
+
+	if policy.allowAll {
+		header.Set(ACCESS_CONTROL_ALLOW_ORIGIN_KEY, "*")
+	} else {
+		header.Set(ACCESS_CONTROL_ALLOW_ORIGIN_KEY, origin)
+	}
+	if policy.allowCredentials {
+		header.Set(ACCESS_CONTROL_ALLOW_CREDENTIALS_KEY, "true")
+	}
+
+	if !isPreflight {
+		header.Set(ACCESS_CONTROL_EXPOSE_HEADERS_KEY, policy.exposedHeaders)
+		// Browser can read 504 error of api timeout only with cors headers
+		ctx.keepHeadersOnTimeout(VARY_KEY, ACCESS_CONTROL_ALLOW_ORIGIN_KEY, ACCESS_CONTROL_ALLOW_CREDENTIALS_KEY, ACCESS_CONTROL_EXPOSE_HEADERS_KEY)
+		ctx.Next()
+		return nil
+	}
+
+	header.Add(VARY_KEY, ACCESS_CONTROL_REQUEST_METHOD_KEY)
+	header.Add(VARY_KEY, ACCESS_CONTROL_REQUEST_HEADERS_KEY)
+	allowedMethods := policy.allowedMethods
+	if allowedMethods == BLANK {
+		allowedMethods = DEFAULT_CORS_ALLOWED_METHODS
+		if len(ctx.allowedMethods) > 0 {
+			allowedMethods = strings.Join(ctx.allowedMethods, ", ")
+		}
+	}
+	header.Set(ACCESS_CONTROL_ALLOW_METHODS_KEY, allowedMethods)
+	if allowedHeaders := policy.filterRequestHeaders(ctx.GetRequestHeader(ACCESS_CONTROL_REQUEST_HEADERS_KEY)); allowedHeaders != BLANK {
+		header.Set(ACCESS_CONTROL_ALLOW_HEADERS_KEY, allowedHeaders)
+	}
+	if policy.maxAge != BLANK {
+		header.Set(ACCESS_CONTROL_MAX_AGE_KEY, policy.maxAge)
+	}
+
+	header.Set("Request-Id", ctx.requestID)
+	ctx.endResponse(http.StatusNoContent, BLANK)
+	return nil
+}
+
+/*
+* filterRequestHeaders: headers in Access-Control-Request-Headers which are allowed
+ */
+func (policy *corsPolicy) filterRequestHeaders(requestHeaders string) string {
+	allowed := []string{}
+	for _, header := range strings.Split(requestHeaders, ",") {
+		header = strings.TrimSpace(header)
+		if header == BLANK {
+			continue
+		}
+		if policy.allowAllHeaders || policy.allowedHeaders[http.CanonicalHeaderKey(header)] {
+			allowed = append(allowed, header)
+		}
+	}
+	return strings.Join(allowed, ", ")
+}
+
+/*
+* checkWebsocketOrigin: CheckOrigin of websocket upgrader
+* Handshake from the same host is always allowed, other origins are allowed by cors policy
+ */
+func checkWebsocketOrigin(request *http.Request) bool {
+	origin := request.Header.Get(ORIGIN_KEY)
+	if origin == BLANK {
+		return true
+	}
+
+	if currentCorsPolicy != nil && currentCorsPolicy.isAllowedOrigin(origin) {
+		return true
+	}
+
+	originUrl, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(originUrl.Host, request.Host)
+}
@@ -0,0 +1,184 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCorsPolicy_IsAllowedOrigin(t *testing.T) {
+	policy := newCorsPolicy(CorsConfig{
+		AllowedOrigins: []string{"https://example.com", "https://*.example.org", `regex:^http://localhost:\d+$`},
+	})
+
+	testCases := map[string]bool{
+		"https://example.com":           true,
+		"HTTPS://EXAMPLE.COM":           true,
+		"https://api.example.org":       true,
+		"https://example.org":           false,
+		"https://api.example.org.evil":  false,
+		"http://localhost:3000":         true,
+		"http://localhost":              false,
+		"https://example.com.evil.test": false,
+	}
+
+	for origin, expected := range testCases {
+		if result := policy.isAllowedOrigin(origin); result != expected {
+			t.Errorf("Origin %s: expected %v, got %v", origin, expected, result)
+		}
+	}
+}
+
+func TestUseCors(t *testing.T) {
+	middlewares := commonApiMiddlewares
+	defer func() {
+		commonApiMiddlewares = middlewares
+		currentCorsPolicy = nil
+	}()
+	UseCors(CorsConfig{
+		AllowedOrigins:   []string{"https://*.example.com"},
+		ExposedHeaders:   []string{"X-Total-Count"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	})
+	RegisterAPI("/test/cors/users", http.MethodPost, testDispatchHandler)
+
+	// Preflight request
+	request := httptest.NewRequest(http.MethodOptions, "/test/cors/users", nil)
+	request.Header.Set(ORIGIN_KEY, "https://app.example.com")
+	request.Header.Set(ACCESS_CONTROL_REQUEST_METHOD_KEY, http.MethodPost)
+	request.Header.Set(ACCESS_CONTROL_REQUEST_HEADERS_KEY, "content-type, x-unknown")
+	recorder := httptest.NewRecorder()
+	dispatchRequest(recorder, request)
+
+	header := recorder.Header()
+	if recorder.Code != http.StatusNoContent {
+		t.Errorf("Preflight: expected status 204, got %d", recorder.Code)
+	}
+	if origin := header.Get(ACCESS_CONTROL_ALLOW_ORIGIN_KEY); origin != "https://app.example.com" {
+		t.Errorf("Preflight: expected origin to be written back, got %s", origin)
+	}
+	if methods := header.Get(ACCESS_CONTROL_ALLOW_METHODS_KEY); methods != "POST, OPTIONS" {
+		t.Errorf("Preflight: expected methods POST, OPTIONS, got %s", methods)
+	}
+	if headers := header.Get(ACCESS_CONTROL_ALLOW_HEADERS_KEY); headers != "content-type" {
+		t.Errorf("Preflight: expected allowed headers content-type, got %s", headers)
+	}
+	if header.Get(ACCESS_CONTROL_ALLOW_CREDENTIALS_KEY) != "true" || header.Get(ACCESS_CONTROL_MAX_AGE_KEY) != "600" {
+		t.Errorf("Preflight: expected credentials and max age, got %v", header)
+	}
+
+	// Actual request
+	request = httptest.NewRequest(http.MethodPost, "/test/cors/users", nil)
+	request.Header.Set(ORIGIN_KEY, "https://app.example.com")
+	recorder = httptest.NewRecorder()
+	dispatchRequest(recorder, request)
+
+	header = recorder.Header()
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Request: expected status 200, got %d", recorder.Code)
+	}
+	if header.Get(ACCESS_CONTROL_EXPOSE_HEADERS_KEY) != "Request-Id, X-Total-Count" {
+		t.Errorf("Request: expected exposed headers, got %s", header.Get(ACCESS_CONTROL_EXPOSE_HEADERS_KEY))
+	}
+	if !slices.Contains(header.Values(VARY_KEY), ORIGIN_KEY) {
+		t.Errorf("Request: expected Vary: Origin, got %v", header.Values(VARY_KEY))
+	}
+
+	// Origin which is not allowed
+	request = httptest.NewRequest(http.MethodOptions, "/test/cors/users", nil)
+	request.Header.Set(ORIGIN_KEY, "https://evil.test")
+	request.Header.Set(ACCESS_CONTROL_REQUEST_METHOD_KEY, http.MethodPost)
+	recorder = httptest.NewRecorder()
+	dispatchRequest(recorder, request)
+
+	if recorder.Code != http.StatusForbidden || recorder.Header().Get(ACCESS_CONTROL_ALLOW_ORIGIN_KEY) != "" {
+		t.Errorf("Not allowed origin: expected 403 without cors headers, got %d %v", recorder.Code, recorder.Header())
+	}
+
+	// Websocket handshake
+	request = httptest.NewRequest(http.MethodGet, "http://api.test/ws", nil)
+	for origin, expected := range map[string]bool{"https://app.example.com": true, "http://api.test": true, "https://evil.test": false} {
+		request.Header.Set(ORIGIN_KEY, origin)
+		if result := checkWebsocketOrigin(request); result != expected {
+			t.Errorf("Websocket origin %s: expected %v, got %v", origin, expected, result)
+		}
+	}
+}
+
+func TestUseCorsMiddleware_AllowAll(t *testing.T) {
+	middlewares := commonApiMiddlewares
+	defer func() { commonApiMiddlewares = middlewares }()
+	UseCorsMiddleware()
+	RegisterAPI("/test/cors/public", http.MethodGet, testDispatchHandler)
+
+	request := httptest.NewRequest(http.MethodGet, "/test/cors/public", nil)
+	request.Header.Set(ORIGIN_KEY, "https://any.test")
+	recorder := httptest.NewRecorder()
+	dispatchRequest(recorder, request)
+
+	if origin := recorder.Header().Get(ACCESS_CONTROL_ALLOW_ORIGIN_KEY); origin != "*" {
+		t.Errorf("Expected origin *, got %s", origin)
+	}
+	if vary := recorder.Header().Values(VARY_KEY); slices.Contains(vary, ORIGIN_KEY) {
+		t.Errorf("Expected no Vary: Origin for wildcard origin, got %v", vary)
+	}
+}
+
+func TestUseCors_WildcardWithCredentials(t *testing.T) {
+	middlewares := commonApiMiddlewares
+	defer func() {
+		commonApiMiddlewares = middlewares
+		currentCorsPolicy = nil
+	}()
+	UseCors(CorsConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+	RegisterAPI("/test/cors/wildcard/credentials", http.MethodGet, testDispatchHandler)
+
+	request := httptest.NewRequest(http.MethodGet, "/test/cors/wildcard/credentials", nil)
+	request.Header.Set(ORIGIN_KEY, "https://evil.test")
+	recorder := httptest.NewRecorder()
+	dispatchRequest(recorder, request)
+
+	// Credentials are dropped, so other website cannot read response of cookie request
+	if credentials := recorder.Header().Get(ACCESS_CONTROL_ALLOW_CREDENTIALS_KEY); credentials != BLANK {
+		t.Errorf("Expected no credentials with origin *, got %s", credentials)
+	}
+	if origin := recorder.Header().Get(ACCESS_CONTROL_ALLOW_ORIGIN_KEY); origin != "*" {
+		t.Errorf("Expected origin *, got %s", origin)
+	}
+}
+
+func TestUseCors_Timeout(t *testing.T) {
+	middlewares := commonApiMiddlewares
+	defer func() {
+		commonApiMiddlewares = middlewares
+		currentCorsPolicy = nil
+	}()
+	UseCors(CorsConfig{AllowedOrigins: []string{"https://app.example.com"}, AllowCredentials: true})
+	finished := make(chan struct{})
+	RegisterAPI("/test/cors/timeout", http.MethodGet, func(ctx *HttpContext, request testDispatchRequest) (HttpResponse, HttpError) {
+		defer close(finished)
+		time.Sleep(100 * time.Millisecond)
+		return NewDefaultHttpResponse("late"), nil
+	}).SetTimeout(20 * time.Millisecond)
+
+	request := httptest.NewRequest(http.MethodGet, "/test/cors/timeout", nil)
+	request.Header.Set(ORIGIN_KEY, "https://app.example.com")
+	recorder := httptest.NewRecorder()
+	dispatchRequest(recorder, request)
+	<-finished
+
+	// Browser can read 504 error and its request id
+	if recorder.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected status 504, got %d", recorder.Code)
+	}
+	header := recorder.Header()
+	if header.Get(ACCESS_CONTROL_ALLOW_ORIGIN_KEY) != "https://app.example.com" || header.Get(ACCESS_CONTROL_ALLOW_CREDENTIALS_KEY) != "true" {
+		t.Errorf("Expected cors headers on timeout error, got %v", header)
+	}
+	if !slices.Contains(header.Values(VARY_KEY), ORIGIN_KEY) || header.Get(ACCESS_CONTROL_EXPOSE_HEADERS_KEY) != "Request-Id" || header.Get("Request-Id") == BLANK {
+		t.Errorf("Expected Vary, exposed headers and request id on timeout error, got %v", header)
+	}
+}
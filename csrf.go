@@ -0,0 +1,280 @@
+package core
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Modes of csrf protection
+const (
+	CSRF_MODE_DOUBLE_SUBMIT = "double_submit"
+	CSRF_MODE_SYNCHRONIZER  = "synchronizer"
+)
+
+// Default names of csrf token
+const (
+	DEFAULT_CSRF_COOKIE_NAME = "csrf_token"
+	DEFAULT_CSRF_HEADER_NAME = "X-CSRF-Token"
+	DEFAULT_CSRF_FIELD_NAME  = "csrf_token"
+)
+
+// Key of csrf token in session (synchronizer mode) and in temp data of request
+const CSRF_TOKEN_KEY = "_csrf_token"
+
+const CSRF_TOKEN_SIZE = 32
+
+/*
+* csrfProtection: token and validation of CsrfConfig
+ */
+type csrfProtection struct {
+	config CsrfConfig
+	secret []byte
+}
+
+var csrf *csrfProtection
+
+/*
+* UseCsrf: validate csrf token of POST, PUT, PATCH and DELETE requests of apis and uploads
+* Token is sent in X-CSRF-Token header or csrf_token form field, form of page emits the field by {{ csrfField }}
+* Request with bearer token in Authorization header and without cookie is exempted, because browser does not send the token automatically
+* Synchronizer mode keeps token in session, so UseSessions must be called before
+* Double submit token is bound to session when UseSessions is called before, so token of other client cannot be planted
+* @param config: csrf config, usually Config.Csrf
+ */
+func UseCsrf(config CsrfConfig) {
+	if config.Mode == BLANK {
+		config.Mode = CSRF_MODE_DOUBLE_SUBMIT
+	}
+	if config.Mode != CSRF_MODE_DOUBLE_SUBMIT && config.Mode != CSRF_MODE_SYNCHRONIZER {
+		LogFatal("Unsupported csrf mode: %s", config.Mode)
+	}
+	if config.Mode == CSRF_MODE_SYNCHRONIZER && sessions == nil {
+		LogFatal("Csrf synchronizer mode needs sessions, call UseSessions before UseCsrf")
+	}
+
+	secret := []byte(config.Secret)
+	if len(secret) == 0 {
+		// Tokens which are signed by random secret are invalid after restart
+		LogError("Csrf secret is not set, random secret is used")
+		secret = make([]byte, 32)
+		rand.Read(secret)
+	}
+
+	LogInfo("Use csrf protection: mode = %s", config.Mode)
+	csrf = &csrfProtection{config: config, secret: secret}
+	UseMiddleware(csrf.middleware)
+}
+
+/*
+* GetCsrfToken: csrf token of request, it is created if request has no token
+* Double submit token is also set in cookie which javascript can read
+* @return string: blank if csrf protection is not used
+ */
+func GetCsrfToken(ctx *HttpContext) string {
+	if csrf == nil {
+		return BLANK
+	}
+	if token, ok := ctx.GetTempData(CSRF_TOKEN_KEY).(string); ok {
+		return token
+	}
+
+	token := csrf.currentToken(ctx)
+	if token == BLANK {
+		token = csrf.newToken(csrf.binding(ctx))
+		if csrf.config.Mode == CSRF_MODE_SYNCHRONIZER {
+			ctx.Session().Set(CSRF_TOKEN_KEY, token)
+		} else {
+			http.SetCookie(ctx.rw, &http.Cookie{
+				Name:     csrf.config.GetCookieName(),
+				Value:    token,
+				Path:     "/",
+				Secure:   csrf.config.Secure,
+				HttpOnly: false,
+				SameSite: csrf.config.GetSameSite(),
+			})
+		}
+	}
+	ctx.SetTempData(CSRF_TOKEN_KEY, token)
+	return token
+}
+
+/*
+* middleware: reject unsafe request which has no valid csrf token with 403
+ */
+func (protection *csrfProtection) middleware(ctx *HttpContext) HttpError {
+	if !protection.isProtected(ctx) {
+		ctx.Next()
+		return nil
+	}
+
+	expected := protection.currentToken(ctx)
+	submitted, httpErr := protection.submittedToken(ctx)
+	if httpErr != nil {
+		return httpErr
+	}
+	if expected == BLANK || submitted == BLANK || subtle.ConstantTimeCompare([]byte(expected), []byte(submitted)) != 1 {
+		ctx.LogInfo("Invalid csrf token: url = %s, method = %s", ctx.URL, ctx.Method)
+		return HTTP_ERROR_INVALID_CSRF_TOKEN
+	}
+
+	ctx.Next()
+	return nil
+}
+
+/*
+* isProtected: unsafe method which is not exempted by bearer token without cookie or exempt paths
+ */
+func (protection *csrfProtection) isProtected(ctx *HttpContext) bool {
+	switch ctx.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return false
+	}
+
+	// Token is not verified here, so request with cookie (session or jwt cookie) is still checked
+	if authorization := ctx.GetRequestHeader(AUTHORIZATION_KEY); len(authorization) > len(BEARER_PREFIX) && strings.EqualFold(authorization[:len(BEARER_PREFIX)], BEARER_PREFIX) && len(ctx.request.Cookies()) == 0 {
+		return false
+	}
+
+	for _, path := range protection.config.ExemptPaths {
+		if strings.HasPrefix(ctx.URL.Path, path) {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+* currentToken: token which is issued to client, blank if it is not issued or double submit cookie is not signed by server
+ */
+func (protection *csrfProtection) currentToken(ctx *HttpContext) string {
+	if protection.config.Mode == CSRF_MODE_SYNCHRONIZER {
+		token := BLANK
+		ctx.Session().Get(CSRF_TOKEN_KEY, &token)
+		return token
+	}
+
+	cookie, err := ctx.request.Cookie(protection.config.GetCookieName())
+	if err != nil || !protection.verify(cookie.Value, protection.binding(ctx)) {
+		return BLANK
+	}
+	return cookie.Value
+}
+
+/*
+* submittedToken: token in header, or in field of url encoded or multipart form
+* @return string, HttpError: error if multipart body is over its limit
+ */
+func (protection *csrfProtection) submittedToken(ctx *HttpContext) (string, HttpError) {
+	if token := ctx.GetRequestHeader(protection.config.GetHeaderName()); token != BLANK {
+		return token, nil
+	}
+
+	fieldName := protection.config.GetFieldName()
+	contentType := strings.ToLower(ctx.GetRequestHeader(CONTENT_TYPE_KEY))
+	switch {
+	case strings.Contains(contentType, FORM_URLENCODED_CONTENT_TYPE):
+		form, err := url.ParseQuery(string(ctx.requestBody))
+		if err != nil {
+			return BLANK, nil
+		}
+		return form.Get(fieldName), nil
+	case strings.Contains(contentType, MULTIPART_FORM_DATA_CONTENT_TYPE):
+		if len(ctx.requestBody) != 0 {
+			// Body of api is read into context with its limit
+			ctx.request.Body = io.NopCloser(bytes.NewBuffer(ctx.requestBody))
+		} else if maxUploadSize := Config.GetMaxUploadSize(); maxUploadSize >= 0 {
+			// Body of upload is parsed here and kept for upload handler, so it is limited before parsing
+			ctx.request.Body = http.MaxBytesReader(ctx.rw, ctx.request.Body, maxUploadSize)
+		}
+		if err := ctx.request.ParseMultipartForm(MAX_UPLOAD_FILE_SIZE); err != nil {
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				return BLANK, HTTP_ERROR_REQUEST_BODY_TOO_LARGE
+			}
+			return BLANK, nil
+		}
+		return ctx.request.FormValue(fieldName), nil
+	}
+	return BLANK, nil
+}
+
+/*
+* newToken: random value and hmac of value and session id
+* Cookie which is written by other subdomain is rejected when it is not issued for session of request
+* Without stored session, token only proves that it is issued by server
+ */
+func (protection *csrfProtection) newToken(binding string) string {
+	value := make([]byte, CSRF_TOKEN_SIZE)
+	rand.Read(value)
+	encoded := base64.RawURLEncoding.EncodeToString(value)
+	return encoded + "." + protection.sign(encoded, binding)
+}
+
+/*
+* binding: id of stored session which token is bound to, blank if sessions are not used or session is new
+* Token is issued again after login, because id of session is changed
+ */
+func (protection *csrfProtection) binding(ctx *HttpContext) string {
+	if sessions == nil {
+		return BLANK
+	}
+	session := ctx.Session()
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if session.isNew {
+		return BLANK
+	}
+	return session.id
+}
+
+func (protection *csrfProtection) sign(value string, binding string) string {
+	mac := hmac.New(sha256.New, protection.secret)
+	// Dot is not in base64 url alphabet, so value and binding cannot be shifted
+	mac.Write([]byte(value + "." + binding))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (protection *csrfProtection) verify(token string, binding string) bool {
+	value, signature, found := strings.Cut(token, ".")
+	return found && hmac.Equal([]byte(signature), []byte(protection.sign(value, binding)))
+}
+
+/*
+* templateWithToken: copy of page template whose csrf functions return token of request
+* Cached template is never executed, so it can be cloned for every request
+ */
+func (protection *csrfProtection) templateWithToken(ctx *HttpContext, tmpl *template.Template) (*template.Template, error) {
+	clone, err := tmpl.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	return clone.Funcs(template.FuncMap{
+		"csrfToken": func() string {
+			return GetCsrfToken(ctx)
+		},
+		"csrfField": func() template.HTML {
+			return csrfHiddenInput(protection.config.GetFieldName(), GetCsrfToken(ctx))
+		},
+	}), nil
+}
+
+/*
+* csrfHiddenInput: hidden input of csrf token, it is blank if csrf protection is not used
+ */
+func csrfHiddenInput(fieldName string, token string) template.HTML {
+	if token == BLANK {
+		return template.HTML(BLANK)
+	}
+	return template.HTML(`<input type="hidden" name="` + template.HTMLEscapeString(fieldName) + `" value="` + template.HTMLEscapeString(token) + `">`)
+}
@@ -0,0 +1,213 @@
+package core
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func useTestCsrf(t *testing.T, config CsrfConfig) {
+	middlewares := commonApiMiddlewares
+	UseCsrf(config)
+	t.Cleanup(func() {
+		csrf = nil
+		commonApiMiddlewares = middlewares
+	})
+}
+
+func sendCsrfRequest(method string, url string, body string, header map[string]string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, url, strings.NewReader(body))
+	for key, value := range header {
+		request.Header.Set(key, value)
+	}
+	for _, cookie := range cookies {
+		request.AddCookie(cookie)
+	}
+	recorder := httptest.NewRecorder()
+	dispatchRequest(recorder, request)
+	return recorder
+}
+
+func responseCookie(recorder *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, cookie := range recorder.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func TestCsrfDoubleSubmit(t *testing.T) {
+	useTestCsrf(t, CsrfConfig{Secret: "secret", ExemptPaths: []string{"/test/csrf/webhooks/"}})
+
+	RegisterAPI("/test/csrf/token", http.MethodGet, func(ctx *HttpContext, request testDispatchRequest) (HttpResponse, HttpError) {
+		return NewDefaultHttpResponse(GetCsrfToken(ctx)), nil
+	})
+	RegisterAPI("/test/csrf/articles", http.MethodPost, testDispatchHandler)
+	RegisterAPI("/test/csrf/webhooks/payment", http.MethodPost, testDispatchHandler)
+
+	recorder := sendCsrfRequest(http.MethodGet, "/test/csrf/token", "", nil)
+	cookie := responseCookie(recorder, DEFAULT_CSRF_COOKIE_NAME)
+	if cookie == nil || cookie.HttpOnly || !strings.Contains(recorder.Body.String(), cookie.Value) {
+		t.Fatalf("Expected readable csrf cookie with token in response, got %v %s", cookie, recorder.Body.String())
+	}
+
+	// Token of request which has cookie is not changed
+	if recorder = sendCsrfRequest(http.MethodGet, "/test/csrf/token", "", nil, cookie); responseCookie(recorder, DEFAULT_CSRF_COOKIE_NAME) != nil {
+		t.Errorf("Expected no new cookie for request with valid token")
+	}
+
+	form := url.Values{DEFAULT_CSRF_FIELD_NAME: {cookie.Value}}.Encode()
+	forged := &http.Cookie{Name: DEFAULT_CSRF_COOKIE_NAME, Value: "attacker.forged"}
+	testCases := []struct {
+		name    string
+		url     string
+		body    string
+		header  map[string]string
+		cookies []*http.Cookie
+		status  int
+	}{
+		{"missing token", "/test/csrf/articles", "", nil, []*http.Cookie{cookie}, http.StatusForbidden},
+		{"missing cookie", "/test/csrf/articles", "", map[string]string{DEFAULT_CSRF_HEADER_NAME: cookie.Value}, nil, http.StatusForbidden},
+		{"header token", "/test/csrf/articles", "", map[string]string{DEFAULT_CSRF_HEADER_NAME: cookie.Value}, []*http.Cookie{cookie}, http.StatusOK},
+		{"form token", "/test/csrf/articles", form, map[string]string{CONTENT_TYPE_KEY: FORM_URLENCODED_CONTENT_TYPE}, []*http.Cookie{cookie}, http.StatusOK},
+		{"forged cookie", "/test/csrf/articles", "", map[string]string{DEFAULT_CSRF_HEADER_NAME: forged.Value}, []*http.Cookie{forged}, http.StatusForbidden},
+		{"bearer token", "/test/csrf/articles", "", map[string]string{AUTHORIZATION_KEY: "Bearer token"}, nil, http.StatusOK},
+		{"bearer token with cookie", "/test/csrf/articles", "", map[string]string{AUTHORIZATION_KEY: "Bearer token"}, []*http.Cookie{{Name: DEFAULT_SESSION_COOKIE_NAME, Value: "session"}}, http.StatusForbidden},
+		{"exempt path", "/test/csrf/webhooks/payment", "", nil, nil, http.StatusOK},
+	}
+
+	for _, testCase := range testCases {
+		recorder := sendCsrfRequest(http.MethodPost, testCase.url, testCase.body, testCase.header, testCase.cookies...)
+		if recorder.Code != testCase.status {
+			t.Errorf("%s: expected status %d, got %d", testCase.name, testCase.status, recorder.Code)
+		}
+	}
+}
+
+func TestCsrfDoubleSubmit_BoundToSession(t *testing.T) {
+	UseSessions(SessionConfig{Secret: "secret"})
+	defer func() { sessions = nil }()
+	useTestCsrf(t, CsrfConfig{Secret: "secret"})
+
+	RegisterAPI("/test/csrf/bound/login", http.MethodGet, func(ctx *HttpContext, request testDispatchRequest) (HttpResponse, HttpError) {
+		ctx.Session().Set("accountID", int64(10))
+		return NewDefaultHttpResponse("ok"), nil
+	})
+	RegisterAPI("/test/csrf/bound/token", http.MethodGet, func(ctx *HttpContext, request testDispatchRequest) (HttpResponse, HttpError) {
+		return NewDefaultHttpResponse(GetCsrfToken(ctx)), nil
+	})
+	RegisterAPI("/test/csrf/bound/articles", http.MethodPost, testDispatchHandler)
+
+	login := func() (*http.Cookie, *http.Cookie) {
+		session := responseCookie(sendCsrfRequest(http.MethodGet, "/test/csrf/bound/login", "", nil), DEFAULT_SESSION_COOKIE_NAME)
+		token := responseCookie(sendCsrfRequest(http.MethodGet, "/test/csrf/bound/token", "", nil, session), DEFAULT_CSRF_COOKIE_NAME)
+		if session == nil || token == nil {
+			t.Fatalf("Expected session and csrf cookies, got %v %v", session, token)
+		}
+		return session, token
+	}
+	session, token := login()
+	otherSession, _ := login()
+
+	header := map[string]string{DEFAULT_CSRF_HEADER_NAME: token.Value}
+	if recorder := sendCsrfRequest(http.MethodPost, "/test/csrf/bound/articles", "", header, session, token); recorder.Code != http.StatusOK {
+		t.Errorf("Expected token of session to be accepted, got %d", recorder.Code)
+	}
+	// Token which is planted into other session is rejected
+	if recorder := sendCsrfRequest(http.MethodPost, "/test/csrf/bound/articles", "", header, otherSession, token); recorder.Code != http.StatusForbidden {
+		t.Errorf("Expected token of other session to be rejected, got %d", recorder.Code)
+	}
+}
+
+func TestCsrfUpload_MaxUploadSize(t *testing.T) {
+	useTestCsrf(t, CsrfConfig{Secret: "secret"})
+	maxUploadSize := Config.Server.MaxUploadSize
+	Config.Server.MaxUploadSize = 1024
+	defer func() { Config.Server.MaxUploadSize = maxUploadSize }()
+	defer os.RemoveAll("uploads")
+
+	RegisterFileUpload("/test/csrf/upload", http.MethodPost, func(ctx *HttpContext, filePath string) (HttpResponse, HttpError) {
+		return NewDefaultHttpResponse("ok"), nil
+	})
+
+	cookie := &http.Cookie{Name: DEFAULT_CSRF_COOKIE_NAME, Value: csrf.newToken(BLANK)}
+	for _, testCase := range []struct {
+		size   int
+		status int
+	}{{100, http.StatusOK}, {4096, http.StatusRequestEntityTooLarge}} {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		writer.WriteField(DEFAULT_CSRF_FIELD_NAME, cookie.Value)
+		part, _ := writer.CreateFormFile("file", "data.txt")
+		part.Write(bytes.Repeat([]byte("a"), testCase.size))
+		writer.Close()
+
+		request := httptest.NewRequest(http.MethodPost, "/test/csrf/upload", &body)
+		request.Header.Set(CONTENT_TYPE_KEY, writer.FormDataContentType())
+		request.ContentLength = -1
+		request.AddCookie(cookie)
+		recorder := httptest.NewRecorder()
+		dispatchRequest(recorder, request)
+
+		// Form field is parsed from limited body
+		if recorder.Code != testCase.status {
+			t.Errorf("Size %d: expected status %d, got %d", testCase.size, testCase.status, recorder.Code)
+		}
+	}
+}
+
+func TestCsrfSynchronizer(t *testing.T) {
+	UseSessions(SessionConfig{Secret: "secret"})
+	defer func() { sessions = nil }()
+	useTestCsrf(t, CsrfConfig{Mode: CSRF_MODE_SYNCHRONIZER})
+
+	RegisterAPI("/test/csrf/session/token", http.MethodGet, func(ctx *HttpContext, request testDispatchRequest) (HttpResponse, HttpError) {
+		return NewDefaultHttpResponse(GetCsrfToken(ctx)), nil
+	})
+	RegisterAPI("/test/csrf/session/articles", http.MethodPut, testDispatchHandler)
+
+	recorder := sendCsrfRequest(http.MethodGet, "/test/csrf/session/token", "", nil)
+	session := responseCookie(recorder, DEFAULT_SESSION_COOKIE_NAME)
+	if session == nil || responseCookie(recorder, DEFAULT_CSRF_COOKIE_NAME) != nil {
+		t.Fatalf("Expected token in session without csrf cookie, got %v", recorder.Result().Cookies())
+	}
+	token := strings.Trim(regexp.MustCompile(`"data":"[^"]+"`).FindString(recorder.Body.String())[len(`"data":`):], `"`)
+
+	if recorder = sendCsrfRequest(http.MethodPut, "/test/csrf/session/articles", "", map[string]string{DEFAULT_CSRF_HEADER_NAME: token}, session); recorder.Code != http.StatusOK {
+		t.Errorf("Expected session token to be accepted, got %d", recorder.Code)
+	}
+	if recorder = sendCsrfRequest(http.MethodPut, "/test/csrf/session/articles", "", map[string]string{DEFAULT_CSRF_HEADER_NAME: token}); recorder.Code != http.StatusForbidden {
+		t.Errorf("Expected token without session to be rejected, got %d", recorder.Code)
+	}
+}
+
+func TestCsrfPage(t *testing.T) {
+	useTestCsrf(t, CsrfConfig{Secret: "secret"})
+
+	pageFile := filepath.Join(t.TempDir(), "form.html")
+	os.WriteFile(pageFile, []byte(`<form method="post">{{ csrfField }}</form>`), 0644)
+	RegisterPage("/test/csrf/page", func(ctx *HttpContext, request *PageRequest) (PageResponse, Error) {
+		return PageResponse{PageFiles: []string{pageFile}, TemplateName: "form.html", Cache: true}, nil
+	})
+
+	cacheHtml := Config.Server.CacheHtml
+	Config.Server.CacheHtml = true
+	defer func() { Config.Server.CacheHtml = cacheHtml }()
+
+	// Every request renders field of cached template with its own token
+	for i := 0; i < 2; i++ {
+		recorder := sendCsrfRequest(http.MethodGet, "/test/csrf/page", "", nil)
+		cookie := responseCookie(recorder, DEFAULT_CSRF_COOKIE_NAME)
+		if cookie == nil || !strings.Contains(recorder.Body.String(), `<input type="hidden" name="csrf_token" value="`+cookie.Value+`">`) {
+			t.Fatalf("Expected hidden field with token of cookie, got %s", recorder.Body.String())
+		}
+	}
+}
@@ -78,4 +78,11 @@ var (
 	ERROR_CANNOT_PUBLISH_MESSAGE                Error = NewError(37, "Cannot publish message")
 	ERROR_WHERE_QUERY_IS_EMPTY                  Error = NewError(38, "Where query is empty")
 	ERROR_INVALID_STRUCTURE_FOR_RESPONSE        Error = NewError(39, "Invalid structure for response")
+	ERROR_CLIENT_DISCONNECTED                   Error = NewError(40, "Client is disconnected")
+	ERROR_MISSING_PATH_PARAM                    Error = NewError(41, "Missing path param")
+	ERROR_SECURE_SERVER_IS_NOT_STARTED          Error = NewError(42, "Secure server is not started")
+	ERROR_INVALID_JWT_CONFIG                    Error = NewError(43, "Invalid jwt config")
+	ERROR_MISSING_JWT_TOKEN                     Error = NewError(44, "Missing jwt token")
+	ERROR_INVALID_JWT_TOKEN                     Error = NewError(45, "Invalid jwt token")
+	ERROR_EXPIRED_JWT_TOKEN                     Error = NewError(46, "Jwt token is expired")
 )
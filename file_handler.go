@@ -1,6 +1,7 @@
 package core
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -14,7 +15,7 @@ type FileHandler func(ctx *HttpContext, filePath string) (HttpResponse, HttpErro
 type UploadFileHandler struct {
 	URL     Url
 	Method  string
-	handler func(writer http.ResponseWriter, request *http.Request)
+	handler func(writer http.ResponseWriter, request *http.Request, optional optionalParams)
 }
 
 func RegisterFileUpload(url string, method string, handler FileHandler, middlewares ...ApiMiddleware) {
@@ -22,15 +23,22 @@ func RegisterFileUpload(url string, method string, handler FileHandler, middlewa
 		LogFatal("Error creating uploads directory: %v", err)
 	}
 
-	h := func(writer http.ResponseWriter, request *http.Request) {
+	h := func(writer http.ResponseWriter, request *http.Request, optional optionalParams) {
 		// Create a new context
 		ctx := getHttpContext()
 		defer putHttpContext(ctx)
+		defer recoverHttpPanic(ctx)
 
-		ctx.rw = writer
-		ctx.request = request
-		ctx.URL = request.URL
-		ctx.Method = request.Method
+		// Body is limited before middlewares, so multipart form which is parsed by them is limited too
+		errBuild := buildStreamContext(ctx, writer, request, Config.GetMaxUploadSize())
+		ctx.allowedMethods = optional.allowedMethods
+		if optional.urlParams != nil {
+			ctx.urlParams = optional.urlParams
+		}
+		if errBuild != nil {
+			ctx.writeError(errBuild)
+			return
+		}
 
 		// Append to common middleware
 		middlewareList := []ApiMiddleware{}
@@ -38,18 +46,18 @@ func RegisterFileUpload(url string, method string, handler FileHandler, middlewa
 		middlewareList = append(middlewareList, middlewares...)
 
 		// Call middleware of function
-		for _, middleware := range middlewareList {
-			ctx.isRequestEnd = true
-			if err := middleware(ctx); ctx.isRequestEnd {
-				if err != nil {
-					ctx.writeError(err)
-				}
-				return
-			}
+		if executeApiMiddlewares(ctx, middlewareList) {
+			return
 		}
 
 		// Parse the multipart form
 		err := request.ParseMultipartForm(MAX_UPLOAD_FILE_SIZE) // 50 MB
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			ctx.LogInfo("Upload body too large: Url = %s, limit = %d", ctx.URL, maxBytesError.Limit)
+			ctx.writeError(HTTP_ERROR_REQUEST_BODY_TOO_LARGE)
+			return
+		}
 		if err != nil {
 			ctx.LogError("Error parsing form: %v", err)
 			ctx.writeError(NewHttpError(http.StatusInternalServerError, http.StatusInternalServerError, err.Error(), nil))
@@ -107,7 +115,7 @@ func RegisterFileUpload(url string, method string, handler FileHandler, middlewa
 		}
 	}
 
-	uploadFileHandlerMap[url] = UploadFileHandler{
+	router.insert(url).upload = &UploadFileHandler{
 		handler: h,
 		URL: Url{
 			Path: url,
@@ -0,0 +1,46 @@
+package core
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestRegisterFileUpload_MaxUploadSize(t *testing.T) {
+	maxUploadSize := Config.Server.MaxUploadSize
+	Config.Server.MaxUploadSize = 1024
+	defer func() { Config.Server.MaxUploadSize = maxUploadSize }()
+	defer os.Remove("uploads")
+
+	isCalled := false
+	RegisterFileUpload("/test/upload/limit", http.MethodPost, func(ctx *HttpContext, filePath string) (HttpResponse, HttpError) {
+		isCalled = true
+		return NewDefaultHttpResponse("ok"), nil
+	})
+
+	for _, size := range []int{100, 4096} {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		part, _ := writer.CreateFormFile("file", "data.txt")
+		part.Write(bytes.Repeat([]byte("a"), size))
+		writer.Close()
+
+		request := httptest.NewRequest(http.MethodPost, "/test/upload/limit", &body)
+		request.Header.Set(CONTENT_TYPE_KEY, writer.FormDataContentType())
+		// Unknown length is limited by reader, not only by Content-Length
+		request.ContentLength = -1
+		recorder := httptest.NewRecorder()
+		isCalled = false
+		dispatchRequest(recorder, request)
+
+		if size < 1024 && (recorder.Code != http.StatusOK || !isCalled) {
+			t.Errorf("Size %d: expected upload to be handled, got %d %s", size, recorder.Code, recorder.Body.String())
+		}
+		if size > 1024 && (recorder.Code != http.StatusRequestEntityTooLarge || isCalled) {
+			t.Errorf("Size %d: expected status 413, got %d", size, recorder.Code)
+		}
+	}
+}
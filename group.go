@@ -0,0 +1,95 @@
+package core
+
+import "strings"
+
+/*
+* RouteGroup: routes which share the same url prefix and middlewares
+* Middlewares are executed in order: common middlewares -> group middlewares -> route middlewares
+ */
+type RouteGroup struct {
+	prefix      string
+	middlewares []ApiMiddleware
+}
+
+/*
+* Group: create a route group
+* @param prefix: url prefix of group. Ex: /api/v1/admin
+* @param middlewares: middlewares of group
+* @return *RouteGroup
+ */
+func Group(prefix string, middlewares ...ApiMiddleware) *RouteGroup {
+	return &RouteGroup{
+		prefix:      strings.TrimSuffix(prefix, "/"),
+		middlewares: middlewares,
+	}
+}
+
+/*
+* Group: create a sub group which inherits prefix and middlewares of group
+* @param prefix: url prefix of sub group, it is appended to prefix of group
+* @param middlewares: middlewares of sub group
+* @return *RouteGroup
+ */
+func (group *RouteGroup) Group(prefix string, middlewares ...ApiMiddleware) *RouteGroup {
+	return &RouteGroup{
+		prefix:      group.url(strings.TrimSuffix(prefix, "/")),
+		middlewares: group.routeMiddlewares(middlewares),
+	}
+}
+
+/*
+* GetPrefix: get full url prefix of group
+* @return string
+ */
+func (group *RouteGroup) GetPrefix() string {
+	return group.prefix
+}
+
+/*
+* Use: append middlewares to group
+* Middlewares only apply to routes which are registered after this call
+ */
+func (group *RouteGroup) Use(middlewares ...ApiMiddleware) {
+	group.middlewares = append(group.middlewares, middlewares...)
+}
+
+/*
+* RegisterFileUpload: register upload file handler in group
+ */
+func (group *RouteGroup) RegisterFileUpload(url string, method string, handler FileHandler, middlewares ...ApiMiddleware) {
+	RegisterFileUpload(group.url(url), method, handler, group.routeMiddlewares(middlewares)...)
+}
+
+/*
+* RegisterGroupAPI: register api in group
+* Go does not allow generic method, so it is a function which takes group as the first param
+* @param group: route group
+* @param url: url of api, it is appended to prefix of group
+* @return *RouteConfig: optional settings of api
+ */
+func RegisterGroupAPI[T any](group *RouteGroup, url string, method string, handler Handler[T], middlewares ...ApiMiddleware) *RouteConfig {
+	return RegisterAPI(group.url(url), method, handler, group.routeMiddlewares(middlewares)...)
+}
+
+/*
+* RegisterGroupWebsocket: register websocket in group
+* Common api middlewares and middlewares of group are executed at handshake, before websocket middlewares
+ */
+func RegisterGroupWebsocket[T any](group *RouteGroup, url string, handler WebsocketHandler[T], middlewares ...WebsocketMiddleware) {
+	registerWebsocket(group.url(url), group.routeMiddlewares(nil), handler, middlewares...)
+}
+
+func (group *RouteGroup) url(url string) string {
+	if url == BLANK {
+		return group.prefix
+	}
+
+	return group.prefix + "/" + strings.TrimPrefix(url, "/")
+}
+
+func (group *RouteGroup) routeMiddlewares(middlewares []ApiMiddleware) []ApiMiddleware {
+	result := make([]ApiMiddleware, 0, len(group.middlewares)+len(middlewares))
+	result = append(result, group.middlewares...)
+	result = append(result, middlewares...)
+	return result
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func testOrderMiddleware(name string) ApiMiddleware {
+	return func(ctx *HttpContext) HttpError {
+		order, _ := ctx.GetTempData("order").([]string)
+		ctx.SetTempData("order", append(order, name))
+		ctx.Next()
+		return nil
+	}
+}
+
+func TestGroup_MiddlewareOrderAndPrefix(t *testing.T) {
+	api := Group("/test/group/api", testOrderMiddleware("group"))
+	admin := api.Group("/admin/", testOrderMiddleware("sub-group"))
+
+	if admin.GetPrefix() != "/test/group/api/admin" {
+		t.Errorf("Expected prefix /test/group/api/admin, got %s", admin.GetPrefix())
+	}
+
+	var order []string
+	var id string
+	RegisterGroupAPI(admin, "/users/{id}", http.MethodGet, func(ctx *HttpContext, request testDispatchRequest) (HttpResponse, HttpError) {
+		order, _ = ctx.GetTempData("order").([]string)
+		id = ctx.GetUrlParam("id")
+		return nil, nil
+	}, testOrderMiddleware("route"))
+
+	recorder := httptest.NewRecorder()
+	dispatchRequest(recorder, httptest.NewRequest(http.MethodGet, "/test/group/api/admin/users/10", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", recorder.Code)
+	}
+
+	if strings.Join(order, ",") != "group,sub-group,route" {
+		t.Errorf("Expected order group,sub-group,route, got %v", order)
+	}
+
+	if id != "10" {
+		t.Errorf("Expected id = 10, got %s", id)
+	}
+}
+
+func TestGroup_MiddlewareEndsRequest(t *testing.T) {
+	group := Group("/test/group/deny", func(ctx *HttpContext) HttpError {
+		return NewHttpError(http.StatusUnauthorized, http.StatusUnauthorized, "Unauthorized", nil)
+	})
+
+	isCalled := false
+	RegisterGroupAPI(group, "/resource", http.MethodPost, func(ctx *HttpContext, request testDispatchRequest) (HttpResponse, HttpError) {
+		isCalled = true
+		return nil, nil
+	})
+
+	recorder := httptest.NewRecorder()
+	dispatchRequest(recorder, httptest.NewRequest(http.MethodPost, "/test/group/deny/resource", nil))
+
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", recorder.Code)
+	}
+
+	if isCalled {
+		t.Errorf("Expected handler is not called")
+	}
+}
+
+func TestGroup_WebsocketRunsCommonMiddlewares(t *testing.T) {
+	middlewares := commonApiMiddlewares
+	UseMiddleware(func(ctx *HttpContext) HttpError {
+		return NewHttpError(http.StatusUnauthorized, http.StatusUnauthorized, "Unauthorized", nil)
+	})
+	defer func() { commonApiMiddlewares = middlewares }()
+
+	group := Group("/test/group/websocket")
+	RegisterGroupWebsocket(group, "/chat", func(ctx WebsocketContext, request testDispatchRequest) (*WebsocketResponse, Error) { return nil, nil })
+
+	recorder := httptest.NewRecorder()
+	dispatchRequest(recorder, httptest.NewRequest(http.MethodGet, "/test/group/websocket/chat", nil))
+
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Expected common middleware to reject handshake with 401, got %d", recorder.Code)
+	}
+}
+
+func TestGroup_WebsocketHandshakeHeaders(t *testing.T) {
+	group := Group("/test/group/websocket/headers", func(ctx *HttpContext) HttpError {
+		ctx.rw.Header().Set("X-Handshake", "middleware")
+		ctx.SetResponseHeader("X-Response", []string{"context"})
+		ctx.Next()
+		return nil
+	})
+	RegisterGroupWebsocket(group, "/chat", func(ctx WebsocketContext, request testDispatchRequest) (*WebsocketResponse, Error) { return nil, nil })
+
+	server := httptest.NewServer(http.HandlerFunc(dispatchRequest))
+	defer server.Close()
+
+	connection, response, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/test/group/websocket/headers/chat", nil)
+	if err != nil {
+		t.Fatalf("Dial websocket fail: %v", err)
+	}
+	defer connection.Close()
+
+	// Headers of middlewares are sent with 101 response
+	if response.Header.Get("X-Handshake") != "middleware" || response.Header.Get("X-Response") != "context" || response.Header.Get("Request-Id") == BLANK {
+		t.Errorf("Expected headers of middlewares in upgrade response, got %v", response.Header)
+	}
+}
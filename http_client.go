@@ -257,7 +257,8 @@ func (builder *httpClientBuilder) Request(response any) (HttpClientResponse, Err
 		return nil, err
 	}
 
-	var body *bytes.Buffer
+	// Body is nil interface when there is no body, typed nil buffer makes NewRequest panic
+	var body io.Reader
 	if builder.body != nil && builder.bodyType == BodyType_JSON {
 		// Handle json body
 		bodyBytes, err := json.Marshal(builder.body)
@@ -469,7 +470,8 @@ func (builder *httpClientBuilder) GetFile(path string) (string, Error) {
 		builder.ctx.LogInfo("Request time: %fs", time.Duration(diff).Seconds())
 	}()
 
-	var body *bytes.Buffer
+	// Body is nil interface when there is no body, typed nil buffer makes NewRequest panic
+	var body io.Reader
 	if builder.body != nil && builder.bodyType == BodyType_JSON {
 		// Handle json body
 		bodyBytes, err := json.Marshal(builder.body)
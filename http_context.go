@@ -1,12 +1,12 @@
 package core
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
-	"strings"
 	"time"
 )
 
@@ -29,6 +29,12 @@ type HttpContext struct {
 	requestID      string
 	timeout        time.Duration
 	tempData       map[string]any
+	allowedMethods []string
+	bodyReader     io.Reader
+	etag           string
+	etagMode       int
+	lastModified   time.Time
+	session        *Session
 }
 
 /*
@@ -36,9 +42,32 @@ type HttpContext struct {
 * @return: Context
  */
 func getHttpContext() *HttpContext {
+	return getHttpContextWithTimeout(contextTimeout)
+}
+
+/*
+* getHttpContextWithTimeout: Get context from pool with timeout
+* @params: timeout time.Duration
+* @return: Context
+ */
+func getHttpContextWithTimeout(timeout time.Duration) *HttpContext {
+	ctx := httpContextPool.Get().(*HttpContext)
+	ctx.Context, ctx.cancelFunc = context.WithTimeout(coreContext, timeout)
+	ctx.timeout = timeout
+	ctx.isResponseEnd = false
+	ctx.responseHeader = make(map[string][]string)
+	ctx.requestID = ID.GenerateID()
+	return ctx
+}
+
+/*
+* getHttpContextWithoutTimeout: Get context from pool which is only cancelled by cancel function or core context
+* @return: Context
+ */
+func getHttpContextWithoutTimeout() *HttpContext {
 	ctx := httpContextPool.Get().(*HttpContext)
-	ctx.Context, ctx.cancelFunc = context.WithTimeout(coreContext, contextTimeout)
-	ctx.timeout = contextTimeout
+	ctx.Context, ctx.cancelFunc = context.WithCancel(coreContext)
+	ctx.timeout = time.Duration(0)
 	ctx.isResponseEnd = false
 	ctx.responseHeader = make(map[string][]string)
 	ctx.requestID = ID.GenerateID()
@@ -52,10 +81,24 @@ func getHttpContext() *HttpContext {
  */
 func putHttpContext(ctx *HttpContext) {
 	ctx.cancelFunc()
+	// Reset state of request, so context is clean even if handler is panic
+	ctx.URL = nil
+	ctx.Method = BLANK
+	ctx.requestBody = ctx.requestBody[:0]
+	ctx.isRequestEnd = false
+	ctx.isResponseEnd = false
+	ctx.request = nil
+	ctx.rw = nil
+	ctx.bodyReader = nil
+	ctx.etag = BLANK
+	ctx.etagMode = ETAG_MODE_NONE
+	ctx.lastModified = time.Time{}
+	ctx.session = nil
 	// Release memory of context: urlParams, responseHeader, tempData
 	ctx.urlParams = nil
 	ctx.responseHeader = nil
 	ctx.tempData = nil
+	ctx.allowedMethods = nil
 	// Put context to pool
 	httpContextPool.Put(ctx)
 }
@@ -151,6 +194,7 @@ func (ctx *HttpContext) GetResponseHeader(key string) []string {
  */
 func (ctx *HttpContext) RedirectURL(url string) {
 	ctx.isResponseEnd = true
+	ctx.commitSession()
 	http.Redirect(ctx.rw, ctx.request, url, http.StatusSeeOther)
 }
 
@@ -158,20 +202,7 @@ func (ctx *HttpContext) RedirectURL(url string) {
 * writeError: write error http response to user
  */
 func (ctx *HttpContext) writeError(httpErr HttpError) {
-	ctx.rw.Header().Set("Content-Type", "application/json")
-	ctx.rw.Header().Set("Request-Id", ctx.requestID)
-	for key, values := range ctx.responseHeader {
-		headerValue := BLANK
-		for i, value := range values {
-			if i == 0 {
-				headerValue = value
-			} else {
-				headerValue += "," + value
-			}
-		}
-
-		ctx.rw.Header().Set(key, headerValue)
-	}
+	ctx.setResponseHeaders()
 
 	resBody := responseBody{
 		Code:    httpErr.GetCode(),
@@ -179,66 +210,75 @@ func (ctx *HttpContext) writeError(httpErr HttpError) {
 		Data:    httpErr.GetErrorData(),
 	}
 
-	body, err := json.Marshal(resBody)
-	if err != nil {
-		ctx.LogError("Marshal error json. RequestId: %s, Error: %s", ctx.requestID, err.Error())
-		ctx.endResponse(http.StatusInternalServerError, `{"code":500,"message":"Internal server error(Marshal error response data)","errorData":null,"data":null}`)
-		return
+	if body, ok := ctx.encodeBody(resBody, BLANK); ok {
+		ctx.endResponse(int(httpErr.GetStatusCode()), body)
 	}
-
-	ctx.endResponse(int(httpErr.GetStatusCode()), string(body))
 }
 
 /*
 * writeSuccess: write success http response to user
  */
 func (ctx *HttpContext) writeSuccess(httpRes HttpResponse) {
-	ctx.rw.Header().Set("Request-Id", ctx.requestID)
+	ctx.setResponseHeaders()
 
-	for key, values := range ctx.responseHeader {
-		headerValue := BLANK
-		for i, value := range values {
-			if i == 0 {
-				headerValue = value
-			} else {
-				headerValue += "," + value
-			}
+	if httpRes.GetResponseContentType() == TEXT_PLAIN_CONTENT_TYPE {
+		// Set text plain response
+		ctx.rw.Header().Set(CONTENT_TYPE_KEY, TEXT_PLAIN_CONTENT_TYPE)
+		var body string
+		switch value := httpRes.GetBody().(type) {
+		case string:
+			body = value
+		case []byte:
+			body = string(value)
+		default:
+			body = fmt.Sprint(value)
 		}
-
-		ctx.rw.Header().Set(key, headerValue)
+		ctx.endCachedResponse(int(httpRes.GetStatusCode()), body)
+		return
 	}
 
-	var body []byte
+	resBody := responseBody{
+		Code:    httpRes.GetReponseCode(),
+		Message: httpRes.GetMessage(),
+		Data:    httpRes.GetBody(),
+	}
 
-	if httpRes.GetResponseContentType() == TEXT_PLAIN_CONTENT_TYPE {
-		// Set text plain response
-		ctx.rw.Header().Set("Content-Type", TEXT_PLAIN_CONTENT_TYPE)
-		body = []byte(httpRes.GetBody().(string))
-	} else {
-		ctx.rw.Header().Set("Content-Type", JSON_CONTENT_TYPE)
+	if body, ok := ctx.encodeBody(resBody, httpRes.GetResponseContentType()); ok {
+		ctx.endCachedResponse(int(httpRes.GetStatusCode()), body)
+	}
+}
 
-		resBody := responseBody{
-			Code:    httpRes.GetReponseCode(),
-			Message: httpRes.GetMessage(),
-			Data:    httpRes.GetBody(),
-		}
+func (ctx *HttpContext) writeDefaultSuccess() {
+	ctx.setResponseHeaders()
 
-		var errOrigin error
-		body, errOrigin = json.Marshal(resBody)
-		if errOrigin != nil {
-			ctx.LogError("Marshal json. RequestId: %s, Error: %v", ctx.requestID, errOrigin)
-			ctx.endResponse(http.StatusInternalServerError, `{"code":500,"message":"Internal server error(Marshal response data)","errorData":null,"data":null}`)
-			return
-		}
+	resBody := responseBody{
+		Code:    DEFAULT_INTEGER,
+		Message: "Success",
+		Data:    nil,
+	}
 
+	if body, ok := ctx.encodeBody(resBody, BLANK); ok {
+		ctx.endCachedResponse(http.StatusOK, body)
 	}
+}
 
-	ctx.endResponse(int(httpRes.GetStatusCode()), string(body))
+/*
+* addRouterHeaders: add headers of router to writer of handler, so they are kept by timeout writer and compression
+ */
+func (ctx *HttpContext) addRouterHeaders(header http.Header) {
+	for key, values := range header {
+		for _, value := range values {
+			ctx.rw.Header().Add(key, value)
+		}
+	}
 }
 
-func (ctx *HttpContext) writeDefaultSuccess() {
+/*
+* setResponseHeaders: write request id and headers which are set by handler into response writer
+* Session is committed where header is written, after this function
+ */
+func (ctx *HttpContext) setResponseHeaders() {
 	ctx.rw.Header().Set("Request-Id", ctx.requestID)
-
 	for key, values := range ctx.responseHeader {
 		headerValue := BLANK
 		for i, value := range values {
@@ -251,23 +291,50 @@ func (ctx *HttpContext) writeDefaultSuccess() {
 
 		ctx.rw.Header().Set(key, headerValue)
 	}
+}
 
-	ctx.rw.Header().Set("Content-Type", JSON_CONTENT_TYPE)
-
-	resBody := responseBody{
-		Code:    DEFAULT_INTEGER,
-		Message: "Success",
-		Data:    nil,
+/*
+* encodeBody: encode response body by codec of content type and set content type header
+* If content type is empty, codec is chosen from Accept header of request
+* @return string, bool: encoded body, false if encoding is failed and 500 error is written
+ */
+func (ctx *HttpContext) encodeBody(resBody responseBody, contentType ContentType) (string, bool) {
+	codec := ctx.responseCodec(contentType)
+	body, err := codec.Marshal(resBody)
+	if err != nil && contentType == BLANK && codec != defaultCodec {
+		// Negotiated codec cannot encode data (ex: map in xml), response of default codec is better than 500
+		ctx.LogInfo("Marshal response body by %s fail, default codec is used: %v", codec.ContentType(), err)
+		codec = defaultCodec
+		body, err = codec.Marshal(resBody)
 	}
-
-	body, errOrigin := json.Marshal(resBody)
-	if errOrigin != nil {
-		ctx.LogError("Marshal json. RequestId: %s, Error: %v", ctx.requestID, errOrigin)
+	if err != nil {
+		ctx.LogError("Marshal response body. RequestId: %s, ContentType: %s, Error: %v", ctx.requestID, codec.ContentType(), err)
+		ctx.rw.Header().Set(CONTENT_TYPE_KEY, JSON_CONTENT_TYPE)
 		ctx.endResponse(http.StatusInternalServerError, `{"code":500,"message":"Internal server error(Marshal response data)","errorData":null,"data":null}`)
-		return
+		return BLANK, false
+	}
+
+	ctx.rw.Header().Set(CONTENT_TYPE_KEY, codec.ContentType())
+	return string(body), true
+}
+
+/*
+* responseCodec: codec of content type which is forced by response, otherwise codec is negotiated from Accept header
+ */
+func (ctx *HttpContext) responseCodec(contentType ContentType) Codec {
+	if contentType != BLANK {
+		if codec := GetCodec(string(contentType)); codec != nil {
+			return codec
+		}
+		ctx.LogError("No codec for response content type: %s, default codec is used", contentType)
+		return defaultCodec
 	}
 
-	ctx.endResponse(http.StatusOK, string(body))
+	ctx.rw.Header().Add(VARY_KEY, ACCEPT_KEY)
+	if ctx.request == nil {
+		return defaultCodec
+	}
+	return negotiateCodec(ctx.request.Header.Get(ACCEPT_KEY))
 }
 
 /*
@@ -276,6 +343,7 @@ func (ctx *HttpContext) writeDefaultSuccess() {
 func (ctx *HttpContext) endResponse(statusCode int, body string) {
 	if !ctx.isResponseEnd {
 		ctx.isResponseEnd = true
+		ctx.commitSession()
 		// end response
 		ctx.rw.WriteHeader(statusCode)
 		fmt.Fprint(ctx.rw, body)
@@ -284,30 +352,14 @@ func (ctx *HttpContext) endResponse(statusCode int, body string) {
 }
 
 /*
-* endResponse: call write header if it is not called before and write body to writer
+* GetUrlParam: Get url param by key
+* @params: key string
+* @return: string
  */
 func (ctx *HttpContext) GetUrlParam(key string) string {
 	return ctx.urlParams[key]
 }
 
-func (ctx *HttpContext) convertUrlParams(pattern string, url string, params []string) {
-	pattern = pattern[1 : len(pattern)-1]
-	patternArray := strings.Split(pattern, "/")
-	urlArray := strings.Split(url, "/")
-	if len(urlArray) != len(patternArray) {
-		LogError("Cannot convert url params: %s, %s", pattern, url)
-		return
-	}
-
-	count := 0
-	for i, patternElement := range patternArray {
-		if patternElement == REGEX_URL_PATH_ELEMENT {
-			ctx.urlParams[params[count]] = urlArray[i]
-			count++
-		}
-	}
-}
-
 /*
 * GetContextID: Get the context id
 * @params: void
@@ -334,6 +386,30 @@ func (ctx *HttpContext) GetTimeout() time.Duration {
 	return ctx.timeout
 }
 
+/*
+* GetAllowedMethods: Get methods which are registered on the requested url
+* @params: void
+* @return: []string
+ */
+func (ctx *HttpContext) GetAllowedMethods() []string {
+	return ctx.allowedMethods
+}
+
+/*
+* GetBodyReader: Get reader of request body
+* Body of api which is registered with SetStreamBody is read directly from connection,
+* otherwise buffered body is returned
+* Reading more than max body size of api returns *http.MaxBytesError
+* @params: void
+* @return: io.Reader
+ */
+func (ctx *HttpContext) GetBodyReader() io.Reader {
+	if ctx.bodyReader != nil {
+		return ctx.bodyReader
+	}
+	return bytes.NewReader(ctx.requestBody)
+}
+
 /*
 * GetCookie: Get cookie by key
 * @params: key string
@@ -410,6 +486,7 @@ func (ctx *HttpContext) GetTempData(key string) any {
 func (ctx *HttpContext) EndResponse(statusCode int, header *http.Header, body []byte) {
 	if !ctx.isResponseEnd {
 		ctx.isResponseEnd = true
+		ctx.commitSession()
 
 		if header != nil {
 			for key, values := range *header {
@@ -71,4 +71,11 @@ var (
 	HTTP_ERROR_READ_BODY_REQUEST_FAIL  = NewHttpError(http.StatusInternalServerError, ERROR_CODE_READ_BODY_REQUEST_FAIL, "Read body request fail", nil)
 	HTTP_ERROR_BAD_REQUEST             = NewHttpError(http.StatusBadRequest, ERROR_CODE_READ_BODY_REQUEST_FAIL, "Read body request fail", nil)
 	HTTP_ERROR_CLOSE_BODY_REQUEST_FAIL = NewHttpError(http.StatusInternalServerError, ERROR_CODE_CLOSE_BODY_REQUEST_FAIL, "Close body request fail", nil)
+	HTTP_ERROR_METHOD_NOT_ALLOWED      = NewHttpError(http.StatusMethodNotAllowed, ERROR_CODE_METHOD_NOT_ALLOWED, "Method not allowed", nil)
+	HTTP_ERROR_TIMEOUT                 = NewHttpError(http.StatusGatewayTimeout, ERROR_CODE_TIMEOUT, "Request timeout", nil)
+	HTTP_ERROR_INTERNAL_SERVER_ERROR   = NewHttpError(http.StatusInternalServerError, ERROR_CODE_INTERNAL_SERVER_ERROR, "Internal server error", nil)
+	HTTP_ERROR_REQUEST_BODY_TOO_LARGE  = NewHttpError(http.StatusRequestEntityTooLarge, ERROR_CODE_REQUEST_BODY_TOO_LARGE, "Request body too large", nil)
+	HTTP_ERROR_UNAUTHORIZED            = NewHttpError(http.StatusUnauthorized, ERROR_CODE_UNAUTHORIZED, "Unauthorized", nil)
+	HTTP_ERROR_FORBIDDEN               = NewHttpError(http.StatusForbidden, ERROR_CODE_FORBIDDEN, "Forbidden", nil)
+	HTTP_ERROR_INVALID_CSRF_TOKEN      = NewHttpError(http.StatusForbidden, ERROR_CODE_INVALID_CSRF_TOKEN, "Invalid csrf token", nil)
 )
@@ -1,6 +1,9 @@
 package core
 
-import "net/http"
+import (
+	"encoding/xml"
+	"net/http"
+)
 
 type HttpResponse interface {
 	GetStatusCode() int
@@ -15,12 +18,22 @@ type HttpResponse interface {
 type ContentType string
 
 const (
-	JSON_CONTENT_TYPE            = "application/json"
-	FORM_URLENCODED_CONTENT_TYPE = "application/x-www-form-urlencoded"
-	TEXT_HTML_CONTENT_TYPE       = "text/html"
-	TEXT_PLAIN_CONTENT_TYPE      = "text/plain"
+	JSON_CONTENT_TYPE                = "application/json"
+	FORM_URLENCODED_CONTENT_TYPE     = "application/x-www-form-urlencoded"
+	MULTIPART_FORM_DATA_CONTENT_TYPE = "multipart/form-data"
+	TEXT_HTML_CONTENT_TYPE           = "text/html"
+	TEXT_PLAIN_CONTENT_TYPE          = "text/plain"
+	XML_CONTENT_TYPE                 = "application/xml"
+	TEXT_XML_CONTENT_TYPE            = "text/xml"
+	MSGPACK_CONTENT_TYPE             = "application/msgpack"
+	X_MSGPACK_CONTENT_TYPE           = "application/x-msgpack"
+	CBOR_CONTENT_TYPE                = "application/cbor"
 )
 
+/*
+* httpResponse: content type is empty by default, response format is chosen from Accept header
+* SetResponseContentType forces format of response
+ */
 type httpResponse struct {
 	statusCode   int
 	body         any
@@ -62,7 +75,6 @@ func NewDefaultHttpResponse(body any) HttpResponse {
 		statusCode:   http.StatusOK,
 		body:         body,
 		responseCode: API_CODE_SUCCESS,
-		contentType:  JSON_CONTENT_TYPE,
 	}
 }
 
@@ -71,7 +83,6 @@ func NewHttpResponse(responseCode int, body any) HttpResponse {
 		responseCode: responseCode,
 		body:         body,
 		statusCode:   http.StatusOK,
-		contentType:  JSON_CONTENT_TYPE,
 	}
 }
 
@@ -81,12 +92,12 @@ func NewHttpResponseWithMessage(code int, message string, body any) HttpResponse
 		body:         body,
 		message:      message,
 		statusCode:   http.StatusOK,
-		contentType:  JSON_CONTENT_TYPE,
 	}
 }
 
 type responseBody struct {
-	Code    int    `json:"code"`
-	Message string `json:"message,omitempty"`
-	Data    any    `json:"data"`
+	XMLName xml.Name `json:"-" xml:"response"`
+	Code    int      `json:"code" xml:"code"`
+	Message string   `json:"message,omitempty" xml:"message,omitempty"`
+	Data    any      `json:"data" xml:"data"`
 }
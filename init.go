@@ -2,11 +2,10 @@ package core
 
 import (
 	"context"
-	"fmt"
 	"html/template"
 	"log"
+	"net"
 	"net/http"
-	"regexp"
 	"sync"
 	"time"
 
@@ -16,14 +15,10 @@ import (
 var mainDbSession dbSession
 var secondaryDbSession dbSession
 
-var routeMap map[string][]Route
-var routeRegexMap map[string][]Route
-var uploadFileHandlerMap map[string]UploadFileHandler
+var router *routeTree
 
 var staticFolderMap map[string]staticFolder
 
-var pageMap map[string]pageInfo
-
 var commonApiMiddlewares []ApiMiddleware
 
 var contextPool sync.Pool
@@ -31,7 +26,6 @@ var contextPool sync.Pool
 var httpContextPool sync.Pool
 
 var websocketContextPool sync.Pool
-var websocketRouteMap map[string]websocketRoute
 
 var Config CoreConfig
 var redisClient cacheClient
@@ -44,9 +38,11 @@ var emqxBrokerClient MqttClient
 var lockerManagerInstance *lockManager
 
 func Init(configFile string) {
-	// Init core context
+	// Init core context, it is cancelled when server is shut down
+	rootCtx, cancelFunc := context.WithCancel(context.Background())
 	coreContext = &rootContext{
-		Context: context.Background(),
+		Context:    rootCtx,
+		cancelFunc: cancelFunc,
 	}
 
 	// Init config
@@ -111,13 +107,9 @@ func Init(configFile string) {
 	// Core context will hold first id from instance
 	coreContext.(*rootContext).contextID = ID.GenerateID()
 
-	routeMap = make(map[string][]Route)
-	routeRegexMap = make(map[string][]Route)
+	router = newRouteTree()
 	staticFolderMap = make(map[string]staticFolder)
-	pageMap = make(map[string]pageInfo)
 	htmlTemplateMap = make(map[string]*template.Template)
-	uploadFileHandlerMap = make(map[string]UploadFileHandler)
-	websocketRouteMap = make(map[string]websocketRoute)
 
 	// Context pool
 	contextPool = sync.Pool{
@@ -153,7 +145,16 @@ func Init(configFile string) {
 	}
 
 	commonApiMiddlewares = make([]ApiMiddleware, 0)
-	validate = validator.New()
+	if Config.Cors.Use {
+		UseCors(Config.Cors)
+	}
+	if Config.Session.Use {
+		UseSessions(Config.Session)
+	}
+	if Config.Csrf.Use {
+		UseCsrf(Config.Csrf)
+	}
+	validate = newValidator()
 
 	// Set background job
 	interval := 30 * time.Second
@@ -177,13 +178,16 @@ func Init(configFile string) {
 
 /*
 * Release: Release all resources
+* Order: scheduler -> mqtt -> message queue -> cache -> database
+* Scheduler is stopped first because it still uses database when it is running
 * @return void
  */
 func Release() {
-	closeDB()
-	releaseCacheDB()
-	releaseMessageQueue()
 	stopScheduler()
+	disconnectMqttBroker()
+	releaseMessageQueue()
+	releaseCacheDB()
+	closeDB()
 }
 
 func closeDB() {
@@ -202,6 +206,12 @@ func releaseCacheDB() {
 	}
 }
 
+func disconnectMqttBroker() {
+	if emqxBrokerClient != nil {
+		emqxBrokerClient.Disconnect(coreContext)
+	}
+}
+
 func releaseMessageQueue() {
 	if queueClient.nc != nil {
 		queueClient.nc.Close()
@@ -211,44 +221,62 @@ func releaseMessageQueue() {
 /*
 * Start: Start server
 * Register all routes and listen to port
+* Block until server is shut down by SIGINT, SIGTERM or Shutdown function
 * @return void
  */
 func Start() {
 	// Register all static folders
 	handleStaticFolder()
 
+	// Register api document and swagger ui
+	if Config.OpenApi.Use {
+		registerOpenApi()
+	}
+
 	// Register all routes
 	handleAPIAndPage()
 
-	blockServerChan := make(chan string)
 	// Listen and serve
+	httpServer = newHttpServer(Config.Server, http.DefaultServeMux)
+	listener, err := net.Listen("tcp", httpServer.Addr)
+	if err != nil {
+		log.Fatalln("Listen fail: ", err)
+	}
+
 	go func() {
-		LogInfo("Start server at port: %d", Config.Server.Port)
-		blockServerChan <- "Start server"
-		err := http.ListenAndServe(fmt.Sprintf("0.0.0.0:%d", Config.Server.Port), nil)
-		if err != nil {
+		LogInfo("Start server at: %s, h2c = %v", httpServer.Addr, Config.Server.H2C)
+		LogInfo("Max request body size: api = %d, upload = %d bytes (negative is no limit)", Config.GetMaxBodySize(), Config.GetMaxUploadSize())
+		if err := httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
 			log.Fatalln("ListenAndServe fail: ", err)
 		}
 	}()
 
-	go func() {
-		if Config.SecureServer.Use {
-			LogInfo("Start secure server at port: %d", Config.SecureServer.Port)
-			err := http.ListenAndServeTLS(fmt.Sprintf("0.0.0.0:%d", Config.SecureServer.Port), Config.SecureServer.CertFile, Config.SecureServer.KeyFile, nil)
-			if err != nil {
+	if Config.SecureServer.Use {
+		secureServer, secureCertificates, err = newSecureServer(Config.SecureServer, http.DefaultServeMux)
+		if err != nil {
+			log.Fatalln("Create secure server fail: ", err)
+		}
+		go secureCertificates.watch(Config.SecureServer.GetCertReloadInterval(), shutdownChan)
+		secureListener, err := net.Listen("tcp", secureServer.Addr)
+		if err != nil {
+			log.Fatalln("Listen secure server fail: ", err)
+		}
+
+		go func() {
+			LogInfo("Start secure server at: %s", secureServer.Addr)
+			if err := secureServer.ServeTLS(secureListener, BLANK, BLANK); err != nil && err != http.ErrServerClosed {
 				log.Fatalln("ListenAndServeTLS fail: ", err)
 			}
-		}
-	}()
+		}()
+	}
 
-	<-blockServerChan
 	// Callback function
 	for _, cb := range callback {
 		cb()
 	}
 
 	// Wait for stop server signal
-	<-blockServerChan
+	waitForShutdown()
 }
 
 /*
@@ -292,54 +320,54 @@ func EmqxBrokerClient() MqttClient {
  */
 
 func handleAPIAndPage() {
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		isHandled := false
-		if page, ok := pageMap[r.URL.Path]; ok && r.Method == http.MethodGet {
-			pageHandler(page, w, r)
-			isHandled = true
-		} else if routeList, ok := routeMap[r.URL.Path]; ok {
-			LogInfo("Handle API: %s", r.URL.Path)
-			for _, route := range routeList {
-				if route.Method == r.Method {
-					route.handler(w, r, optionalParams{})
-					isHandled = true
-					break
-				}
-			}
-		} else if route, ok := websocketRouteMap[r.URL.Path]; ok {
-			route.handler(w, r)
-			isHandled = true
-		} else if handler, ok := uploadFileHandlerMap[r.URL.Path]; ok {
-			handler.handler(w, r)
-			isHandled = true
-		} else {
-			for regexPath, routeList := range routeRegexMap {
-				if match, _ := regexp.MatchString(regexPath, r.URL.Path); match {
-					LogInfo("Handle Regex API: %s", r.URL.Path)
-					for _, route := range routeList {
-						if route.Method == r.Method {
-							route.handler(w, r, optionalParams{
-								haveUrlParam: true,
-								urlPattern:   regexPath,
-								urlParamKeys: route.URL.Params,
-							})
-							isHandled = true
-							break
-						}
-					}
-
-					if isHandled {
-						break
-					}
-				}
-			}
-		}
+	http.Handle("/", compressHandler(http.HandlerFunc(dispatchRequest)))
+}
 
-		if !isHandled {
-			http.NotFound(w, r)
-		}
+/*
+* dispatchRequest: find the entry of request path in router and call its handler
+ */
+func dispatchRequest(w http.ResponseWriter, r *http.Request) {
+	activeHandlers.Add(1)
+	defer activeHandlers.Done()
+
+	entry, urlParams := router.lookup(r.URL.Path)
+	if entry == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	optional := optionalParams{
+		urlParams:      urlParams,
+		allowedMethods: entry.allowedMethods(),
+	}
+
+	if entry.page != nil && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+		pageHandler(*entry.page, w, r, optional)
+		return
+	}
+
+	if route, ok := entry.findRoute(r.Method); ok {
+		LogInfo("Handle API: %s", r.URL.Path)
+		route.handler(w, r, optional)
+		return
+	}
+
+	if entry.websocket != nil && r.Method == http.MethodGet {
+		entry.websocket.handler(w, r)
+		return
+	}
+
+	if entry.upload != nil && entry.upload.Method == r.Method {
+		entry.upload.handler(w, r, optional)
+		return
+	}
+
+	if r.Method == http.MethodOptions {
+		handleOptions(w, r, optional)
+		return
+	}
 
-	})
+	handleMethodNotAllowed(w, r, optional)
 }
 
 /*
@@ -349,6 +377,6 @@ func handleAPIAndPage() {
 func handleStaticFolder() {
 	for _, staticFolder := range staticFolderMap {
 		LogInfo("Register static folder: url = %s, path = %s", staticFolder.url, staticFolder.path)
-		http.Handle(staticFolder.url, http.StripPrefix(staticFolder.prefix, http.FileServer(http.Dir(staticFolder.path))))
+		http.Handle(staticFolder.url, compressHandler(http.StripPrefix(staticFolder.prefix, staticCacheHandler(staticFolder, http.FileServer(http.Dir(staticFolder.path))))))
 	}
 }
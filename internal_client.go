@@ -0,0 +1,108 @@
+package core
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+/*
+* CallInternalAPI: call api of other service which is registered by RegisterAPI or RegisterTypedAPI
+* Fields with path, query and header tag are put into url and header, request is sent as json body
+* except GET and HEAD request. Data of response is decoded into Res
+* It is used by client which is generated by cmd/core-client-gen
+* @param builder: http client, context and custom headers are set before calling
+* @param baseUrl: url of service. Ex: http://user-service:8080
+* @param method: method of api
+* @param pattern: url pattern of api. Ex: /users/{id:int}
+* @param request: request of api
+* @return Res, Error
+ */
+func CallInternalAPI[Req any, Res any](builder HttpClientBuilder, baseUrl string, method string, pattern string, request Req) (Res, Error) {
+	var response Res
+
+	value := reflect.ValueOf(request)
+	for value.Kind() == reflect.Pointer && !value.IsNil() {
+		value = value.Elem()
+	}
+
+	pathParams := map[string]string{}
+	if value.Kind() == reflect.Struct {
+		binder := newRequestBinder(value.Type())
+		for _, field := range binder.fields {
+			fieldValue := value.FieldByIndex(field.index)
+			// Path param is always sent, zero value of query and header is skipped only by omitempty
+			if field.omitEmpty && field.source != BINDING_TAG_PATH && fieldValue.IsZero() {
+				continue
+			}
+
+			values := formatBindingValue(fieldValue, field.timeFormat)
+			if len(values) == 0 {
+				continue
+			}
+
+			switch field.source {
+			case BINDING_TAG_PATH:
+				pathParams[field.name] = values[0]
+			case BINDING_TAG_QUERY:
+				for _, item := range values {
+					builder.AddQuery(field.name, item)
+				}
+			case BINDING_TAG_HEADER:
+				builder.AddHeader(field.name, strings.Join(values, ","))
+			}
+		}
+	}
+
+	path, err := buildRequestPath(pattern, pathParams)
+	if err != nil {
+		return response, err
+	}
+
+	builder.SetUrl(strings.TrimSuffix(baseUrl, "/") + path).SetMethod(method)
+	if method != http.MethodGet && method != http.MethodHead {
+		builder.SetBody(request)
+	}
+
+	if _, err := builder.RequestInternal(&response); err != nil {
+		return response, err
+	}
+	return response, nil
+}
+
+/*
+* buildRequestPath: replace params of url pattern by escaped values
+* Ex: /users/{id:int}/files/*path, {id: 1, path: a/b.txt} => /users/1/files/a/b.txt
+ */
+func buildRequestPath(pattern string, params map[string]string) (string, Error) {
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		var name string
+		switch {
+		case strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}"):
+			name, _, _ = strings.Cut(segment[1:len(segment)-1], ":")
+		case strings.HasPrefix(segment, "*"):
+			name = segment[1:]
+		default:
+			continue
+		}
+
+		value, ok := params[name]
+		if !ok {
+			LogError("Missing path param: pattern = %s, name = %s", pattern, name)
+			return BLANK, ERROR_MISSING_PATH_PARAM
+		}
+
+		if strings.HasPrefix(segment, "*") {
+			parts := strings.Split(strings.TrimPrefix(value, "/"), "/")
+			for j, part := range parts {
+				parts[j] = url.PathEscape(part)
+			}
+			segments[i] = strings.Join(parts, "/")
+		} else {
+			segments[i] = url.PathEscape(value)
+		}
+	}
+	return strings.Join(segments, "/"), nil
+}
@@ -0,0 +1,87 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+type testInternalRequest struct {
+	Id      int64    `path:"id"`
+	Tags    []string `query:"tags"`
+	TraceId string   `header:"X-Trace-Id"`
+	Name    string   `json:"name"`
+}
+
+type testInternalResponse struct {
+	Id      int64    `json:"id"`
+	Tags    []string `json:"tags"`
+	TraceId string   `json:"traceId"`
+	Name    string   `json:"name"`
+}
+
+func TestCallInternalAPI(t *testing.T) {
+	RegisterTypedAPI("/test/internal/users/{id:int}", http.MethodPut, func(ctx *HttpContext, request testInternalRequest) (testInternalResponse, HttpError) {
+		return testInternalResponse(request), nil
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(dispatchRequest))
+	defer server.Close()
+
+	request := testInternalRequest{Id: 3, Tags: []string{"a", "b"}, TraceId: "trace", Name: "core"}
+	response, err := CallInternalAPI[testInternalRequest, testInternalResponse](NewClient(), server.URL, http.MethodPut, "/test/internal/users/{id:int}", request)
+	if err != nil {
+		t.Fatalf("Call internal api fail: %v", err)
+	}
+
+	if response.Id != 3 || len(response.Tags) != 2 || response.TraceId != "trace" || response.Name != "core" {
+		t.Errorf("Expected response equal to request, got %+v", response)
+	}
+}
+
+func TestBuildRequestPath(t *testing.T) {
+	path, err := buildRequestPath("/files/{id:int}/*path", map[string]string{"id": "1", "path": "a b/c.txt"})
+	if err != nil || path != "/files/1/a%20b/c.txt" {
+		t.Errorf("Expected /files/1/a%%20b/c.txt, got %s, error = %v", path, err)
+	}
+
+	if _, err := buildRequestPath("/files/{id}", map[string]string{}); err != ERROR_MISSING_PATH_PARAM {
+		t.Errorf("Expected missing path param error, got %v", err)
+	}
+}
+
+type testInternalZeroRequest struct {
+	Id    int64  `path:"id"`
+	Name  string `path:"name"`
+	Page  int    `query:"page"`
+	Limit int    `query:"limit,omitempty"`
+}
+
+func TestCallInternalAPI_ZeroValues(t *testing.T) {
+	var query url.Values
+	RegisterTypedAPI("/test/internal/zero/{id:int}/{name}", http.MethodGet, func(ctx *HttpContext, request testInternalZeroRequest) (testInternalZeroRequest, HttpError) {
+		query = ctx.URL.Query()
+		return request, nil
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(dispatchRequest))
+	defer server.Close()
+
+	// Path params are sent even if they are zero, name is an empty segment
+	response, err := CallInternalAPI[testInternalZeroRequest, testInternalZeroRequest](NewClient(), server.URL, http.MethodGet, "/test/internal/zero/{id:int}/{name}", testInternalZeroRequest{})
+	if err == ERROR_MISSING_PATH_PARAM {
+		t.Fatalf("Expected zero path params to be formatted, got %v", err)
+	}
+	if err == nil && response.Id != 0 {
+		t.Errorf("Expected id 0, got %+v", response)
+	}
+
+	response, err = CallInternalAPI[testInternalZeroRequest, testInternalZeroRequest](NewClient(), server.URL, http.MethodGet, "/test/internal/zero/{id:int}/{name}", testInternalZeroRequest{Name: "core"})
+	if err != nil || response.Id != 0 || response.Name != "core" {
+		t.Fatalf("Expected call with id 0 to succeed, got %+v, error = %v", response, err)
+	}
+	if !query.Has("page") || query.Has("limit") {
+		t.Errorf("Expected page=0 without limit, got %v", query)
+	}
+}
@@ -0,0 +1,243 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Jwks is fetched again after this time
+const DEFAULT_JWKS_CACHE_TIME = 3600
+
+// Unknown kid refreshes jwks at most once in this interval, so invalid tokens cannot flood jwks url
+const JWKS_MIN_REFRESH_INTERVAL = time.Minute
+
+// Timeout of fetching jwks url
+const JWKS_REQUEST_TIMEOUT = 10 * time.Second
+
+/*
+* jsonWebKey: public key in jwks, only signing keys of RSA and EC are used
+ */
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+/*
+* jwksKeySet: keys of jwks file or url which are cached by kid
+* Keys are loaded again when cache time is over or token has unknown kid (key rotation)
+ */
+type jwksKeySet struct {
+	mu          sync.RWMutex
+	file        string
+	url         string
+	cacheTime   time.Duration
+	keys        map[string]any
+	loadedAt    time.Time
+	refreshedAt time.Time
+	loading     chan struct{} // closed when running refresh of getKey is done
+	client      *http.Client
+}
+
+func newJwksKeySet(file string, url string, cacheTime time.Duration) *jwksKeySet {
+	return &jwksKeySet{
+		file:      file,
+		url:       url,
+		cacheTime: cacheTime,
+		keys:      make(map[string]any),
+		client:    &http.Client{Timeout: JWKS_REQUEST_TIMEOUT},
+	}
+}
+
+/*
+* getKey: find key by kid, token without kid uses the only key of jwks
+ */
+func (set *jwksKeySet) getKey(kid string) (any, error) {
+	set.mu.Lock()
+	key, found := set.findKey(kid)
+	expired := set.url != BLANK && time.Since(set.loadedAt) > set.cacheTime
+	loading := set.loading
+	refresh := loading == nil && (!found || expired) && time.Since(set.refreshedAt) > JWKS_MIN_REFRESH_INTERVAL
+	if refresh {
+		// Refresh is claimed in the same lock, so concurrent requests with unknown kid fetch jwks once
+		set.refreshedAt = time.Now()
+		set.loading = make(chan struct{})
+	}
+	set.mu.Unlock()
+
+	if loading != nil && !found {
+		// Key of rotation may be in running refresh
+		<-loading
+		set.mu.RLock()
+		key, found = set.findKey(kid)
+		set.mu.RUnlock()
+	}
+	if !refresh {
+		if found {
+			return key, nil
+		}
+		return nil, fmt.Errorf("jwks has no key: kid = %s", kid)
+	}
+
+	err := set.load()
+	set.mu.Lock()
+	close(set.loading)
+	set.loading = nil
+	set.mu.Unlock()
+
+	if err != nil {
+		LogError("Refresh jwks fail: %v", err)
+		// Cached key is still used when jwks url is not available
+		if found {
+			return key, nil
+		}
+		return nil, err
+	}
+
+	set.mu.RLock()
+	defer set.mu.RUnlock()
+	if key, found = set.findKey(kid); !found {
+		return nil, fmt.Errorf("jwks has no key: kid = %s", kid)
+	}
+	return key, nil
+}
+
+func (set *jwksKeySet) findKey(kid string) (any, bool) {
+	if kid == BLANK && len(set.keys) == 1 {
+		for _, key := range set.keys {
+			return key, true
+		}
+	}
+	key, ok := set.keys[kid]
+	return key, ok
+}
+
+/*
+* refresh: load keys now and restart min refresh interval
+ */
+func (set *jwksKeySet) refresh() error {
+	set.mu.Lock()
+	set.refreshedAt = time.Now()
+	set.mu.Unlock()
+	return set.load()
+}
+
+/*
+* load: load keys from jwks file or url and replace cached keys
+ */
+func (set *jwksKeySet) load() error {
+	data, err := set.read()
+	if err != nil {
+		return err
+	}
+
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return err
+	}
+
+	keys := make(map[string]any)
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != BLANK && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			LogError("Skip jwks key: kid = %s, error = %v", jwk.Kid, err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		return errors.New("jwks has no signing key")
+	}
+
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	set.keys = keys
+	set.loadedAt = time.Now()
+	LogInfo("Load jwks success: %d keys", len(keys))
+	return nil
+}
+
+func (set *jwksKeySet) read() ([]byte, error) {
+	if set.url == BLANK {
+		return os.ReadFile(set.file)
+	}
+
+	response, err := set.client.Get(set.url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch jwks fail: status = %d", response.StatusCode)
+	}
+	return io.ReadAll(response.Body)
+}
+
+/*
+* publicKey: decode rsa or ecdsa public key of jwk
+ */
+func (jwk jsonWebKey) publicKey() (any, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeJwkNumber(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeJwkNumber(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve: %s", jwk.Crv)
+		}
+		x, err := decodeJwkNumber(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeJwkNumber(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type: %s", jwk.Kty)
+}
+
+func decodeJwkNumber(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
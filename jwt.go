@@ -0,0 +1,322 @@
+package core
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Header keys of bearer authentication
+const (
+	AUTHORIZATION_KEY    = "Authorization"
+	WWW_AUTHENTICATE_KEY = "WWW-Authenticate"
+	BEARER_PREFIX        = "Bearer "
+)
+
+// Signing algorithms of jwt
+const (
+	JWT_ALGORITHM_HS256 = "HS256"
+	JWT_ALGORITHM_RS256 = "RS256"
+	JWT_ALGORITHM_ES256 = "ES256"
+)
+
+// Key of claims in temp data of context
+const JWT_CLAIMS_KEY = "core.jwt.claims"
+
+// Query param of token in websocket handshake, browser cannot set header of websocket
+const JWT_QUERY_PARAM = "access_token"
+
+/*
+* JwtClaims: registered claims and account of token
+* Typed claims of application embed JwtClaims, so page request is filled from claims
+* Ex: type UserClaims struct { core.JwtClaims; Scopes []string `json:"scopes"` }
+ */
+type JwtClaims struct {
+	jwt.RegisteredClaims
+	AccountID int64  `json:"account_id,omitempty"`
+	RoleID    int64  `json:"role_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+}
+
+func (claims JwtClaims) GetAccountID() int64 {
+	return claims.AccountID
+}
+
+func (claims JwtClaims) GetRoleID() int64 {
+	return claims.RoleID
+}
+
+func (claims JwtClaims) GetUsername() string {
+	return claims.Username
+}
+
+/*
+* jwtAccountClaims: claims which has account of page request
+ */
+type jwtAccountClaims interface {
+	GetAccountID() int64
+	GetRoleID() int64
+	GetUsername() string
+}
+
+/*
+* JwtAuth: verify bearer tokens and inject claims T into context
+* Same JwtAuth is used by api, page and websocket middlewares
+ */
+type JwtAuth[T any] struct {
+	config    JwtConfig
+	secret    []byte
+	publicKey any
+	jwks      *jwksKeySet
+	parser    *jwt.Parser
+}
+
+/*
+* NewJwtAuth: create jwt authentication from config
+* Keys are secret (HS256), public key file (RS256, ES256) and jwks file or url (RS256, ES256)
+* @param config: jwt config, usually Config.Jwt
+* @return *JwtAuth[T], Error: error if T is not claims or keys cannot be loaded
+ */
+func NewJwtAuth[T any](config JwtConfig) (*JwtAuth[T], Error) {
+	if _, ok := any(new(T)).(jwt.Claims); !ok {
+		LogError("Jwt claims %s must embed core.JwtClaims or implement jwt.Claims", reflect.TypeOf((*T)(nil)).Elem())
+		return nil, ERROR_INVALID_JWT_CONFIG
+	}
+
+	auth := &JwtAuth[T]{config: config}
+	if config.Secret != BLANK {
+		auth.secret = []byte(config.Secret)
+	}
+
+	if config.PublicKeyFile != BLANK {
+		publicKey, err := loadPublicKeyFile(config.PublicKeyFile)
+		if err != nil {
+			LogError("Load jwt public key %s fail: %v", config.PublicKeyFile, err)
+			return nil, ERROR_INVALID_JWT_CONFIG
+		}
+		auth.publicKey = publicKey
+	}
+
+	if config.JwksFile != BLANK || config.JwksUrl != BLANK {
+		auth.jwks = newJwksKeySet(config.JwksFile, config.JwksUrl, config.GetJwksCacheTime())
+		// Jwks file is loaded now, so wrong file is found at start. Jwks url is loaded at first request
+		if config.JwksFile != BLANK {
+			if err := auth.jwks.refresh(); err != nil {
+				LogError("Load jwks file %s fail: %v", config.JwksFile, err)
+				return nil, ERROR_INVALID_JWT_CONFIG
+			}
+		}
+	}
+
+	algorithms := config.Algorithms
+	if len(algorithms) == 0 {
+		if auth.secret != nil {
+			algorithms = append(algorithms, JWT_ALGORITHM_HS256)
+		}
+		if auth.publicKey != nil || auth.jwks != nil {
+			algorithms = append(algorithms, JWT_ALGORITHM_RS256, JWT_ALGORITHM_ES256)
+		}
+	}
+	if len(algorithms) == 0 {
+		LogError("Jwt config has no secret, public key or jwks")
+		return nil, ERROR_INVALID_JWT_CONFIG
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(algorithms),
+		jwt.WithLeeway(config.GetClockSkew()),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+	if config.Issuer != BLANK {
+		options = append(options, jwt.WithIssuer(config.Issuer))
+	}
+	if config.Audience != BLANK {
+		options = append(options, jwt.WithAudience(config.Audience))
+	}
+	auth.parser = jwt.NewParser(options...)
+	return auth, nil
+}
+
+/*
+* Parse: verify signature, expiry, issuer and audience of token
+* @param token: token without "Bearer " prefix
+* @return *T, Error: ERROR_EXPIRED_JWT_TOKEN or ERROR_INVALID_JWT_TOKEN if token is not valid
+ */
+func (auth *JwtAuth[T]) Parse(token string) (*T, Error) {
+	claims := new(T)
+	if _, err := auth.parser.ParseWithClaims(token, any(claims).(jwt.Claims), auth.keyFunc); err != nil {
+		LogInfo("Parse jwt token fail: %v", err)
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ERROR_EXPIRED_JWT_TOKEN
+		}
+		return nil, ERROR_INVALID_JWT_TOKEN
+	}
+	return claims, nil
+}
+
+/*
+* Middleware: api middleware which answers 401 if request has no valid bearer token
+* Claims are retrieved by GetJwtClaims[T](ctx)
+ */
+func (auth *JwtAuth[T]) Middleware() ApiMiddleware {
+	return func(ctx *HttpContext) HttpError {
+		claims, err := auth.parse(ctx.request, false)
+		if err != nil {
+			ctx.LogInfo("Jwt authentication fail: url = %s, error = %s", ctx.URL, err.GetMessage())
+			return newJwtHttpError(ctx.rw, err)
+		}
+
+		ctx.SetTempData(JWT_CLAIMS_KEY, claims)
+		ctx.Next()
+		return nil
+	}
+}
+
+/*
+* PageMiddleware: page middleware which fills AccountID, RoleID and Username of page request from claims
+* Request without valid token is redirected to login_url, or answered with 401 if login_url is not set
+ */
+func (auth *JwtAuth[T]) PageMiddleware() PageMiddleware {
+	return func(ctx *HttpContext, request *PageRequest) Error {
+		claims, err := auth.parse(ctx.request, false)
+		if err != nil {
+			if auth.config.LoginUrl != BLANK {
+				ctx.RedirectURL(auth.config.LoginUrl)
+			} else {
+				ctx.writeError(newJwtHttpError(ctx.rw, err))
+			}
+			return err
+		}
+
+		ctx.SetTempData(JWT_CLAIMS_KEY, claims)
+		if account, ok := any(claims).(jwtAccountClaims); ok {
+			request.AccountID = account.GetAccountID()
+			request.RoleID = account.GetRoleID()
+			request.Username = account.GetUsername()
+		}
+		return nil
+	}
+}
+
+/*
+* WebsocketMiddleware: websocket middleware which rejects handshake without valid token
+* Token is also read from access_token query param, because browser cannot set header of websocket
+ */
+func (auth *JwtAuth[T]) WebsocketMiddleware() WebsocketMiddleware {
+	return func(ctx WebsocketContext, w http.ResponseWriter, r *http.Request) HttpError {
+		claims, err := auth.parse(r, true)
+		if err != nil {
+			ctx.LogInfo("Jwt authentication of websocket fail: url = %s, error = %s", r.URL, err.GetMessage())
+			return newJwtHttpError(w, err)
+		}
+
+		ctx.SetTempData(JWT_CLAIMS_KEY, claims)
+		return nil
+	}
+}
+
+/*
+* GetJwtClaims: claims which are injected by jwt middleware
+* @param ctx: *HttpContext or WebsocketContext
+* @return *T, bool: false if request is not authenticated by JwtAuth[T]
+ */
+func GetJwtClaims[T any](ctx interface{ GetTempData(key string) any }) (*T, bool) {
+	claims, ok := ctx.GetTempData(JWT_CLAIMS_KEY).(*T)
+	return claims, ok
+}
+
+/*
+* parse: find token in Authorization header, cookie and query param (websocket only), then parse it
+ */
+func (auth *JwtAuth[T]) parse(request *http.Request, allowQuery bool) (*T, Error) {
+	token := BLANK
+	if header := request.Header.Get(AUTHORIZATION_KEY); len(header) > len(BEARER_PREFIX) && strings.EqualFold(header[:len(BEARER_PREFIX)], BEARER_PREFIX) {
+		token = strings.TrimSpace(header[len(BEARER_PREFIX):])
+	} else if auth.config.CookieName != BLANK {
+		if cookie, err := request.Cookie(auth.config.CookieName); err == nil {
+			token = cookie.Value
+		}
+	}
+	if token == BLANK && allowQuery {
+		token = request.URL.Query().Get(JWT_QUERY_PARAM)
+	}
+
+	if token == BLANK {
+		return nil, ERROR_MISSING_JWT_TOKEN
+	}
+	return auth.Parse(token)
+}
+
+/*
+* keyFunc: choose key by algorithm and kid of token
+ */
+func (auth *JwtAuth[T]) keyFunc(token *jwt.Token) (any, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if auth.secret == nil {
+			return nil, errors.New("jwt secret is not configured")
+		}
+		return auth.secret, nil
+	}
+
+	if auth.jwks != nil {
+		kid, _ := token.Header["kid"].(string)
+		key, err := auth.jwks.getKey(kid)
+		if err == nil || auth.publicKey == nil {
+			return key, err
+		}
+	}
+
+	if auth.publicKey == nil {
+		return nil, errors.New("jwt public key is not configured")
+	}
+	return auth.publicKey, nil
+}
+
+/*
+* newJwtHttpError: 401 error with WWW-Authenticate header
+ */
+func newJwtHttpError(writer http.ResponseWriter, err Error) HttpError {
+	challenge := "Bearer"
+	if !err.Equal(ERROR_MISSING_JWT_TOKEN) {
+		challenge += fmt.Sprintf(` error="invalid_token", error_description=%s`, strconv.Quote(err.GetMessage()))
+	}
+	writer.Header().Set(WWW_AUTHENTICATE_KEY, challenge)
+	return NewHttpError(http.StatusUnauthorized, ERROR_CODE_UNAUTHORIZED, err.GetMessage(), nil)
+}
+
+/*
+* loadPublicKeyFile: load rsa or ecdsa public key from pem file of public key or certificate
+ */
+func loadPublicKeyFile(path string) (any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no pem block in file")
+	}
+
+	switch block.Type {
+	case "CERTIFICATE":
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return certificate.PublicKey, nil
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	}
+}
@@ -0,0 +1,234 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type testJwtClaims struct {
+	JwtClaims
+	Scopes []string `json:"scopes"`
+}
+
+func newTestJwtClaims(expiresAt time.Time) testJwtClaims {
+	return testJwtClaims{
+		JwtClaims: JwtClaims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    "core-test",
+				Audience:  jwt.ClaimStrings{"api"},
+				ExpiresAt: jwt.NewNumericDate(expiresAt),
+			},
+			AccountID: 10,
+			RoleID:    2,
+			Username:  "core",
+		},
+		Scopes: []string{"read"},
+	}
+}
+
+func signTestJwt(t *testing.T, method jwt.SigningMethod, kid string, key any, claims jwt.Claims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestJwtAuth_Middleware(t *testing.T) {
+	auth, err := NewJwtAuth[testJwtClaims](JwtConfig{Secret: "secret", Issuer: "core-test", Audience: "api", ClockSkew: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	RegisterAPI("/test/jwt/profile", http.MethodGet, func(ctx *HttpContext, request testDispatchRequest) (HttpResponse, HttpError) {
+		claims, ok := GetJwtClaims[testJwtClaims](ctx)
+		if !ok {
+			return nil, HTTP_ERROR_INTERNAL_SERVER_ERROR
+		}
+		return NewDefaultHttpResponse(claims.Username + ":" + strings.Join(claims.Scopes, ",")), nil
+	}, auth.Middleware())
+
+	wrongAudience := newTestJwtClaims(time.Now().Add(time.Hour))
+	wrongAudience.Audience = jwt.ClaimStrings{"other"}
+	testCases := []struct {
+		name          string
+		authorization string
+		status        int
+	}{
+		{"valid", "Bearer " + signTestJwt(t, jwt.SigningMethodHS256, "", []byte("secret"), newTestJwtClaims(time.Now().Add(time.Hour))), http.StatusOK},
+		{"expired in clock skew", "Bearer " + signTestJwt(t, jwt.SigningMethodHS256, "", []byte("secret"), newTestJwtClaims(time.Now().Add(-10*time.Second))), http.StatusOK},
+		{"expired", "Bearer " + signTestJwt(t, jwt.SigningMethodHS256, "", []byte("secret"), newTestJwtClaims(time.Now().Add(-time.Minute))), http.StatusUnauthorized},
+		{"wrong secret", "Bearer " + signTestJwt(t, jwt.SigningMethodHS256, "", []byte("other"), newTestJwtClaims(time.Now().Add(time.Hour))), http.StatusUnauthorized},
+		{"wrong audience", "Bearer " + signTestJwt(t, jwt.SigningMethodHS256, "", []byte("secret"), wrongAudience), http.StatusUnauthorized},
+		{"missing", "", http.StatusUnauthorized},
+	}
+
+	for _, testCase := range testCases {
+		request := httptest.NewRequest(http.MethodGet, "/test/jwt/profile", nil)
+		if testCase.authorization != "" {
+			request.Header.Set(AUTHORIZATION_KEY, testCase.authorization)
+		}
+		recorder := httptest.NewRecorder()
+		dispatchRequest(recorder, request)
+
+		if recorder.Code != testCase.status {
+			t.Errorf("%s: expected status %d, got %d, body = %s", testCase.name, testCase.status, recorder.Code, recorder.Body.String())
+			continue
+		}
+		if testCase.status == http.StatusOK && !strings.Contains(recorder.Body.String(), "core:read") {
+			t.Errorf("%s: expected claims in response, got %s", testCase.name, recorder.Body.String())
+		}
+		if testCase.status == http.StatusUnauthorized && !strings.HasPrefix(recorder.Header().Get(WWW_AUTHENTICATE_KEY), "Bearer") {
+			t.Errorf("%s: expected WWW-Authenticate header, got %v", testCase.name, recorder.Header())
+		}
+	}
+}
+
+func TestJwtAuth_JwksUrlRotation(t *testing.T) {
+	oldKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	newKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	var rotated atomic.Bool
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		keys := []map[string]string{{
+			"kty": "RSA",
+			"kid": "old",
+			"n":   base64.RawURLEncoding.EncodeToString(oldKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(oldKey.E)).Bytes()),
+		}}
+		if rotated.Load() {
+			keys = append(keys, map[string]string{
+				"kty": "EC",
+				"kid": "new",
+				"crv": "P-256",
+				"x":   base64.RawURLEncoding.EncodeToString(newKey.X.FillBytes(make([]byte, 32))),
+				"y":   base64.RawURLEncoding.EncodeToString(newKey.Y.FillBytes(make([]byte, 32))),
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+	}))
+	defer server.Close()
+
+	auth, err := NewJwtAuth[testJwtClaims](JwtConfig{JwksUrl: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := newTestJwtClaims(time.Now().Add(time.Hour))
+	if result, err := auth.Parse(signTestJwt(t, jwt.SigningMethodRS256, "old", oldKey, claims)); err != nil || result.AccountID != 10 {
+		t.Fatalf("Expected RS256 token to be valid, got %v, error = %v", result, err)
+	}
+
+	// Unknown kid does not refresh jwks again in min refresh interval
+	rotated.Store(true)
+	newToken := signTestJwt(t, jwt.SigningMethodES256, "new", newKey, claims)
+	if _, err := auth.Parse(newToken); err == nil || fetches.Load() != 1 {
+		t.Fatalf("Expected unknown kid to be rejected without fetching, got error = %v, fetches = %d", err, fetches.Load())
+	}
+
+	auth.jwks.refreshedAt = time.Now().Add(-2 * JWKS_MIN_REFRESH_INTERVAL)
+	if _, err := auth.Parse(newToken); err != nil || fetches.Load() != 2 {
+		t.Errorf("Expected rotated key to be fetched, got error = %v, fetches = %d", err, fetches.Load())
+	}
+}
+
+func TestJwksKeySet_ConcurrentRefresh(t *testing.T) {
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte(`{"keys":[{"kty":"RSA","kid":"old","n":"AQAB","e":"AQAB"}]}`))
+	}))
+	defer server.Close()
+
+	// Requests at the same time fetch jwks once and all of them get key of the fetch
+	set := newJwksKeySet(BLANK, server.URL, time.Hour)
+	start := make(chan struct{})
+	var errs atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if _, err := set.getKey("old"); err != nil {
+				errs.Add(1)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if fetches.Load() != 1 || errs.Load() != 0 {
+		t.Errorf("Expected jwks to be fetched once without error, got fetches = %d, errors = %d", fetches.Load(), errs.Load())
+	}
+}
+
+func TestJwtAuth_PublicKeyFile(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	keyFile := filepath.Join(t.TempDir(), "public.pem")
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600)
+
+	auth, err := NewJwtAuth[testJwtClaims](JwtConfig{PublicKeyFile: keyFile, CookieName: "token", LoginUrl: "/login"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := signTestJwt(t, jwt.SigningMethodES256, "", key, newTestJwtClaims(time.Now().Add(time.Hour)))
+
+	// Hmac token which uses public key as secret is rejected
+	if _, err := auth.Parse(signTestJwt(t, jwt.SigningMethodHS256, "", der, newTestJwtClaims(time.Now().Add(time.Hour)))); err == nil {
+		t.Error("Expected HS256 token to be rejected")
+	}
+
+	// Page request is filled from token in cookie
+	ctx := getHttpContext()
+	defer putHttpContext(ctx)
+	request := httptest.NewRequest(http.MethodGet, "/test/jwt/page", nil)
+	request.AddCookie(&http.Cookie{Name: "token", Value: token})
+	buildContext(ctx, httptest.NewRecorder(), request)
+	pageRequest := PageRequest{}
+	if err := auth.PageMiddleware()(ctx, &pageRequest); err != nil || pageRequest.AccountID != 10 || pageRequest.RoleID != 2 || pageRequest.Username != "core" {
+		t.Errorf("Expected page request from claims, got %+v, error = %v", pageRequest, err)
+	}
+
+	// Page request without token is redirected to login url
+	recorder := httptest.NewRecorder()
+	buildContext(ctx, recorder, httptest.NewRequest(http.MethodGet, "/test/jwt/page", nil))
+	if err := auth.PageMiddleware()(ctx, &PageRequest{}); err == nil || recorder.Code != http.StatusSeeOther || recorder.Header().Get("Location") != "/login" {
+		t.Errorf("Expected redirect to login, got %d %v", recorder.Code, recorder.Header())
+	}
+
+	// Websocket handshake reads token from query
+	websocketCtx := getWebsocketContext()
+	defer putWebsocketContext(websocketCtx)
+	request = httptest.NewRequest(http.MethodGet, "/test/jwt/ws?"+JWT_QUERY_PARAM+"="+token, nil)
+	if err := auth.WebsocketMiddleware()(websocketCtx, httptest.NewRecorder(), request); err != nil {
+		t.Fatalf("Expected websocket handshake to be authenticated, got %v", err)
+	}
+	if claims, ok := GetJwtClaims[testJwtClaims](websocketCtx); !ok || claims.AccountID != 10 {
+		t.Errorf("Expected claims in websocket context, got %v", claims)
+	}
+}
@@ -0,0 +1,60 @@
+package core
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Name of built-in metrics
+const (
+	METRIC_HTTP_PANIC              = "http_panic_total"
+	METRIC_WEBSOCKET_PANIC         = "websocket_panic_total"
+	METRIC_TLS_CERT_RELOAD_SUCCESS = "tls_certificate_reload_success_total"
+	METRIC_TLS_CERT_RELOAD_FAIL    = "tls_certificate_reload_fail_total"
+)
+
+var metrics sync.Map
+
+/*
+* IncreaseMetric: increase counter of metric by 1
+* @param name: name of metric
+ */
+func IncreaseMetric(name string) {
+	AddMetric(name, 1)
+}
+
+/*
+* AddMetric: add delta to counter of metric
+* @param name: name of metric
+* @param delta: value which is added to counter
+ */
+func AddMetric(name string, delta int64) {
+	counter, _ := metrics.LoadOrStore(name, new(atomic.Int64))
+	counter.(*atomic.Int64).Add(delta)
+}
+
+/*
+* GetMetric: get current value of metric, 0 if metric is never increased
+* @param name: name of metric
+* @return int64
+ */
+func GetMetric(name string) int64 {
+	counter, ok := metrics.Load(name)
+	if !ok {
+		return 0
+	}
+	return counter.(*atomic.Int64).Load()
+}
+
+/*
+* GetMetrics: get snapshot of all metrics
+* @return map[string]int64: name -> value
+ */
+func GetMetrics() map[string]int64 {
+	result := make(map[string]int64)
+	metrics.Range(func(key, value any) bool {
+		result[key.(string)] = value.(*atomic.Int64).Load()
+		return true
+	})
+	return result
+}
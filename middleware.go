@@ -1,23 +1,30 @@
 package core
 
-import "net/http"
-
-func corsMiddleware(ctx *HttpContext) HttpError {
-	ctx.rw.Header().Set("Access-Control-Allow-Origin", "*")
-	ctx.rw.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
-	ctx.rw.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
-
-	if ctx.Method == http.MethodOptions {
-		ctx.rw.WriteHeader(http.StatusOK)
-		return nil
+/*
+* executeApiMiddlewares: call middlewares in order
+* @return bool: true if a middleware ended the request (error response is written)
+ */
+func executeApiMiddlewares(ctx *HttpContext, middlewares []ApiMiddleware) bool {
+	for _, middleware := range middlewares {
+		ctx.isRequestEnd = true
+		if err := middleware(ctx); ctx.isRequestEnd {
+			if err != nil {
+				ctx.writeError(err)
+			}
+			return true
+		}
 	}
 
-	ctx.Next()
-	return nil
+	return false
 }
 
+/*
+* UseCorsMiddleware: allow requests from all origins without credentials
+* Origin of websocket handshake is not changed
+* Deprecated: use UseCors or cors section of config, which supports credentials and origin allow-list
+ */
 func UseCorsMiddleware() {
-	UseMiddleware(corsMiddleware)
+	UseMiddleware(newCorsPolicy(CorsConfig{AllowedOrigins: []string{"*"}}).middleware)
 }
 
 func UseMiddleware(middleware ApiMiddleware) {
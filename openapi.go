@@ -0,0 +1,681 @@
+package core
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const OPEN_API_VERSION = "3.1.0"
+
+// Default url of api document and swagger ui page
+const (
+	DEFAULT_OPEN_API_PATH    = "/openapi.json"
+	DEFAULT_OPEN_API_UI_PATH = "/swagger"
+)
+
+// Swagger ui assets are loaded from this exact version when they are not embedded and ui_assets_path is not set
+const (
+	SWAGGER_UI_VERSION            = "5.17.14"
+	DEFAULT_SWAGGER_UI_ASSETS_URL = "https://unpkg.com/swagger-ui-dist@" + SWAGGER_UI_VERSION
+)
+
+// Files of swagger-ui-dist package which are used by swagger ui page
+const (
+	SWAGGER_UI_CSS_FILE = "swagger-ui.css"
+	SWAGGER_UI_JS_FILE  = "swagger-ui-bundle.js"
+)
+
+// Version of go generate must be the same as SWAGGER_UI_VERSION
+//go:generate go run ./cmd/swagger-ui-dist -version 5.17.14 -out swagger-ui
+
+//go:embed swagger-ui
+var embeddedSwaggerUi embed.FS
+
+// Embedded assets folder, swagger ui page uses it when it has css and js file
+var swaggerUiAssets, _ = fs.Sub(embeddedSwaggerUi, "swagger-ui")
+
+type openApiDocument struct {
+	OpenApi    string                                  `json:"openapi"`
+	Info       openApiInfo                             `json:"info"`
+	Paths      map[string]map[string]*openApiOperation `json:"paths"`
+	Components openApiComponents                       `json:"components"`
+}
+
+type openApiInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type openApiComponents struct {
+	Schemas map[string]*openApiSchema `json:"schemas"`
+}
+
+type openApiOperation struct {
+	Summary     string                     `json:"summary,omitempty"`
+	Description string                     `json:"description,omitempty"`
+	Tags        []string                   `json:"tags,omitempty"`
+	Parameters  []openApiParameter         `json:"parameters,omitempty"`
+	RequestBody *openApiRequestBody        `json:"requestBody,omitempty"`
+	Deprecated  bool                       `json:"deprecated,omitempty"`
+	Responses   map[string]openApiResponse `json:"responses"`
+}
+
+type openApiParameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required,omitempty"`
+	Schema   *openApiSchema `json:"schema"`
+}
+
+type openApiRequestBody struct {
+	Required bool                        `json:"required,omitempty"`
+	Content  map[string]openApiMediaType `json:"content"`
+}
+
+type openApiResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]openApiMediaType `json:"content,omitempty"`
+}
+
+type openApiMediaType struct {
+	Schema *openApiSchema `json:"schema"`
+}
+
+type openApiSchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Properties           map[string]*openApiSchema `json:"properties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	Items                *openApiSchema            `json:"items,omitempty"`
+	AdditionalProperties *openApiSchema            `json:"additionalProperties,omitempty"`
+	Enum                 []any                     `json:"enum,omitempty"`
+	Minimum              *float64                  `json:"minimum,omitempty"`
+	Maximum              *float64                  `json:"maximum,omitempty"`
+	ExclusiveMinimum     *float64                  `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     *float64                  `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int                      `json:"minLength,omitempty"`
+	MaxLength            *int                      `json:"maxLength,omitempty"`
+	MinItems             *int                      `json:"minItems,omitempty"`
+	MaxItems             *int                      `json:"maxItems,omitempty"`
+}
+
+var openApiDocumentOnce sync.Once
+var openApiDocumentBytes []byte
+
+/*
+* GenerateOpenApiDocument: build OpenAPI 3.1 document from all registered apis
+* @return []byte: json document
+* @return Error
+ */
+func GenerateOpenApiDocument() ([]byte, Error) {
+	builder := newOpenApiBuilder()
+	router.walk(func(entry *routeEntry) {
+		for _, route := range entry.routes {
+			if route.config == nil || route.config.hidden {
+				continue
+			}
+			builder.addRoute(entry, route)
+		}
+	})
+
+	document, err := json.Marshal(builder.document)
+	if err != nil {
+		LogError("Marshal open api document fail: %v", err)
+		return nil, NewError(ERROR_FROM_LIBRARY, err.Error())
+	}
+
+	return document, nil
+}
+
+/*
+* registerOpenApi: serve api document and swagger ui page
+* Document is generated at the first request, when all apis are registered
+ */
+func registerOpenApi() {
+	path := Config.OpenApi.Path
+	if path == BLANK {
+		path = DEFAULT_OPEN_API_PATH
+	}
+
+	uiPath := Config.OpenApi.UiPath
+	if uiPath == BLANK {
+		uiPath = DEFAULT_OPEN_API_UI_PATH
+	}
+
+	LogInfo("Register open api document: %s, swagger ui: %s", path, uiPath)
+	documentEntry := router.insert(path)
+	documentEntry.routes = append(documentEntry.routes, Route{
+		Method: http.MethodGet,
+		URL:    Url{Path: path},
+		handler: func(writer http.ResponseWriter, request *http.Request, optional optionalParams) {
+			openApiDocumentOnce.Do(func() {
+				openApiDocumentBytes, _ = GenerateOpenApiDocument()
+			})
+
+			if openApiDocumentBytes == nil {
+				http.Error(writer, "Generate open api document fail", http.StatusInternalServerError)
+				return
+			}
+
+			writer.Header().Set(CONTENT_TYPE_KEY, JSON_CONTENT_TYPE)
+			writer.Write(openApiDocumentBytes)
+		},
+	})
+
+	// Assets in local folder or embedded assets are served with ui page, so page loads no script of other origin
+	page := swaggerUiPageData{
+		Title:        Config.OpenApi.GetTitle(),
+		DocumentUrl:  path,
+		AssetsUrl:    DEFAULT_SWAGGER_UI_ASSETS_URL,
+		CssIntegrity: Config.OpenApi.UiCssIntegrity,
+		JsIntegrity:  Config.OpenApi.UiJsIntegrity,
+		CrossOrigin:  true,
+	}
+	var serveAsset func(writer http.ResponseWriter, request *http.Request, file string)
+	if assetsPath := Config.OpenApi.UiAssetsPath; assetsPath != BLANK {
+		serveAsset = func(writer http.ResponseWriter, request *http.Request, file string) {
+			http.ServeFile(writer, request, filepath.Join(assetsPath, file))
+		}
+	} else if hasSwaggerUiAssets(swaggerUiAssets) {
+		serveAsset = func(writer http.ResponseWriter, request *http.Request, file string) {
+			http.ServeFileFS(writer, request, swaggerUiAssets, file)
+		}
+	} else if page.CssIntegrity == BLANK || page.JsIntegrity == BLANK {
+		LogWarning("Swagger ui assets are not embedded, they are loaded from %s without integrity check. Run go generate in core module or set ui_assets_path", DEFAULT_SWAGGER_UI_ASSETS_URL)
+	}
+
+	if serveAsset != nil {
+		page.AssetsUrl = strings.TrimSuffix(uiPath, "/") + "/assets"
+		page.CrossOrigin = false
+		assetsPattern := page.AssetsUrl + "/{file}"
+		assetsEntry := router.insert(assetsPattern)
+		assetsEntry.routes = append(assetsEntry.routes, Route{
+			Method: http.MethodGet,
+			URL:    Url{Path: assetsPattern},
+			handler: func(writer http.ResponseWriter, request *http.Request, optional optionalParams) {
+				file := optional.urlParams["file"]
+				if file != SWAGGER_UI_CSS_FILE && file != SWAGGER_UI_JS_FILE {
+					http.NotFound(writer, request)
+					return
+				}
+				serveAsset(writer, request, file)
+			},
+		})
+	}
+
+	uiEntry := router.insert(uiPath)
+	uiEntry.routes = append(uiEntry.routes, Route{
+		Method: http.MethodGet,
+		URL:    Url{Path: uiPath},
+		handler: func(writer http.ResponseWriter, request *http.Request, optional optionalParams) {
+			writer.Header().Set(CONTENT_TYPE_KEY, TEXT_HTML_CONTENT_TYPE)
+			if err := swaggerUiTemplate.Execute(writer, page); err != nil {
+				LogError("Render swagger ui page fail: %v", err)
+			}
+		},
+	})
+}
+
+/*
+* hasSwaggerUiAssets: check if folder has css and js file of swagger ui
+ */
+func hasSwaggerUiAssets(assets fs.FS) bool {
+	for _, file := range []string{SWAGGER_UI_CSS_FILE, SWAGGER_UI_JS_FILE} {
+		if _, err := fs.Stat(assets, file); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+type openApiBuilder struct {
+	document   openApiDocument
+	typeNames  map[reflect.Type]string
+	namesCount map[string]int
+}
+
+func newOpenApiBuilder() *openApiBuilder {
+	return &openApiBuilder{
+		document: openApiDocument{
+			OpenApi: OPEN_API_VERSION,
+			Info: openApiInfo{
+				Title:       Config.OpenApi.GetTitle(),
+				Version:     Config.OpenApi.GetVersion(),
+				Description: Config.OpenApi.Description,
+			},
+			Paths: make(map[string]map[string]*openApiOperation),
+			Components: openApiComponents{
+				Schemas: make(map[string]*openApiSchema),
+			},
+		},
+		typeNames:  make(map[reflect.Type]string),
+		namesCount: make(map[string]int),
+	}
+}
+
+func (builder *openApiBuilder) addRoute(entry *routeEntry, route Route) {
+	path, parameters := openApiPath(entry.pattern)
+	operation := &openApiOperation{
+		Summary:     route.config.summary,
+		Description: route.config.description,
+		Tags:        route.config.tags,
+		Parameters:  parameters,
+		Responses:   builder.responses(route.config),
+		Deprecated:  route.config.version != nil && route.config.version.deprecated,
+	}
+
+	if route.requestType != nil {
+		binder := newRequestBinder(route.requestType)
+		for _, field := range binder.fields {
+			if field.source == BINDING_TAG_PATH || field.source == BINDING_TAG_FORM {
+				continue
+			}
+
+			structField := route.requestType.FieldByIndex(field.index)
+			parameter := openApiParameter{
+				Name:     field.name,
+				In:       field.source,
+				Required: hasValidateRule(structField, "required"),
+				Schema:   builder.schema(structField.Type),
+			}
+			applyValidateRules(parameter.Schema, structField)
+			operation.Parameters = append(operation.Parameters, parameter)
+		}
+
+		if route.Method != http.MethodGet && route.Method != http.MethodDelete && route.Method != http.MethodHead && hasJsonBody(route.requestType) {
+			operation.RequestBody = &openApiRequestBody{
+				Required: true,
+				Content: map[string]openApiMediaType{
+					JSON_CONTENT_TYPE: {Schema: builder.schema(route.requestType)},
+				},
+			}
+		}
+	}
+
+	if _, ok := builder.document.Paths[path]; !ok {
+		builder.document.Paths[path] = make(map[string]*openApiOperation)
+	}
+	builder.document.Paths[path][strings.ToLower(route.Method)] = operation
+}
+
+/*
+* responses: success response is wrapped in standard response body {code, message, data}
+* Event stream and stream response have their own content type, data of each item has schema of response model
+ */
+func (builder *openApiBuilder) responses(config *RouteConfig) map[string]openApiResponse {
+	dataSchema := &openApiSchema{}
+	if config.responseType != nil {
+		dataSchema = builder.schema(config.responseType)
+	}
+
+	success := openApiResponse{
+		Description: "Success",
+		Content: map[string]openApiMediaType{
+			JSON_CONTENT_TYPE: {Schema: responseBodySchema(dataSchema)},
+		},
+	}
+	if config.eventStream {
+		success = openApiResponse{
+			Description: "Server sent events, data of each event is json of response model",
+			Content: map[string]openApiMediaType{
+				EVENT_STREAM_CONTENT_TYPE: {Schema: &openApiSchema{Type: "string"}},
+			},
+		}
+	} else if len(config.streamFormats) != 0 {
+		success = openApiResponse{Description: "Stream of items", Content: map[string]openApiMediaType{}}
+		for _, format := range config.streamFormats {
+			schema := dataSchema
+			switch format {
+			case STREAM_FORMAT_JSON_ARRAY:
+				schema = &openApiSchema{Type: "array", Items: dataSchema}
+			case STREAM_FORMAT_CSV:
+				schema = &openApiSchema{Type: "string"}
+			}
+			// Each line of ndjson is an item
+			success.Content[string(streamContentType(format))] = openApiMediaType{Schema: schema}
+		}
+	}
+
+	errorSchema := responseBodySchema(&openApiSchema{})
+	return map[string]openApiResponse{
+		"200": success,
+		"default": {
+			Description: "Error",
+			Content: map[string]openApiMediaType{
+				JSON_CONTENT_TYPE: {Schema: errorSchema},
+			},
+		},
+	}
+}
+
+func responseBodySchema(data *openApiSchema) *openApiSchema {
+	return &openApiSchema{
+		Type: "object",
+		Properties: map[string]*openApiSchema{
+			"code":    {Type: "integer"},
+			"message": {Type: "string"},
+			"data":    data,
+		},
+		Required: []string{"code", "data"},
+	}
+}
+
+/*
+* schema: convert go type to json schema, named struct is put in components
+ */
+func (builder *openApiBuilder) schema(t reflect.Type) *openApiSchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return &openApiSchema{Type: "string", Format: "date-time"}
+	case durationType:
+		return &openApiSchema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &openApiSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &openApiSchema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &openApiSchema{Type: "integer", Format: "int32"}
+	case reflect.Float32:
+		return &openApiSchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &openApiSchema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &openApiSchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &openApiSchema{Type: "string", Format: "byte"}
+		}
+		return &openApiSchema{Type: "array", Items: builder.schema(t.Elem())}
+	case reflect.Map:
+		return &openApiSchema{Type: "object", AdditionalProperties: builder.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == BLANK {
+			return builder.structSchema(t)
+		}
+
+		name, ok := builder.typeNames[t]
+		if !ok {
+			name = builder.componentName(t)
+			builder.typeNames[t] = name
+			// Register name before building properties to support recursive type
+			builder.document.Components.Schemas[name] = &openApiSchema{}
+			*builder.document.Components.Schemas[name] = *builder.structSchema(t)
+		}
+		return &openApiSchema{Ref: "#/components/schemas/" + name}
+	default:
+		return &openApiSchema{}
+	}
+}
+
+func (builder *openApiBuilder) structSchema(t reflect.Type) *openApiSchema {
+	schema := &openApiSchema{
+		Type:       "object",
+		Properties: make(map[string]*openApiSchema),
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, isJsonField := jsonFieldName(field)
+		if name == "-" {
+			continue
+		}
+
+		// Embedded struct without json name: its fields are promoted
+		if field.Anonymous && !isJsonField && field.Type.Kind() == reflect.Struct {
+			embedded := builder.structSchema(field.Type)
+			for key, value := range embedded.Properties {
+				schema.Properties[key] = value
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+
+		// Field which is bound from query, path, header or form is not a part of body
+		if !isJsonField && hasBindingTag(field) {
+			continue
+		}
+
+		propertySchema := builder.schema(field.Type)
+		if propertySchema.Ref == BLANK {
+			applyValidateRules(propertySchema, field)
+		}
+		schema.Properties[name] = propertySchema
+		if hasValidateRule(field, "required") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	sort.Strings(schema.Required)
+	return schema
+}
+
+func (builder *openApiBuilder) componentName(t reflect.Type) string {
+	name := t.Name()
+	// Generic type name contains package path of type arguments
+	if index := strings.Index(name, "["); index >= 0 {
+		name = name[:index]
+	}
+
+	builder.namesCount[name]++
+	if count := builder.namesCount[name]; count > 1 {
+		name = fmt.Sprintf("%s%d", name, count)
+	}
+	return name
+}
+
+/*
+* openApiPath: convert route pattern to open api path. Ex: /users/{id:int}/*path -> /users/{id}/{path}
+ */
+func openApiPath(pattern string) (string, []openApiParameter) {
+	segments := splitRoutePath(pattern)
+	parameters := []openApiParameter{}
+	for i, segment := range segments {
+		var name, paramType string
+		switch {
+		case strings.HasPrefix(segment, "*"):
+			name = segment[1:]
+		case strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}"):
+			name, paramType = parseRouteParam(segment)
+		default:
+			continue
+		}
+
+		schema := &openApiSchema{Type: "string"}
+		switch paramType {
+		case ROUTE_PARAM_TYPE_INT:
+			schema = &openApiSchema{Type: "integer", Format: "int64"}
+		case ROUTE_PARAM_TYPE_UUID:
+			schema.Format = "uuid"
+		}
+
+		segments[i] = "{" + name + "}"
+		parameters = append(parameters, openApiParameter{
+			Name:     name,
+			In:       BINDING_TAG_PATH,
+			Required: true,
+			Schema:   schema,
+		})
+	}
+
+	return "/" + strings.Join(segments, "/"), parameters
+}
+
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	tag, ok := field.Tag.Lookup("json")
+	if !ok {
+		return field.Name, false
+	}
+
+	name := strings.Split(tag, ",")[0]
+	if name == BLANK {
+		return field.Name, true
+	}
+	return name, true
+}
+
+func hasBindingTag(field reflect.StructField) bool {
+	for _, tag := range bindingTags {
+		if _, ok := field.Tag.Lookup(tag); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func hasJsonBody(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		if name, isJsonField := jsonFieldName(field); name != "-" && (isJsonField || !hasBindingTag(field)) {
+			return true
+		}
+	}
+	return false
+}
+
+func validateRules(field reflect.StructField) map[string]string {
+	rules := make(map[string]string)
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		if key != BLANK {
+			rules[key] = value
+		}
+	}
+	return rules
+}
+
+func hasValidateRule(field reflect.StructField, rule string) bool {
+	_, ok := validateRules(field)[rule]
+	return ok
+}
+
+/*
+* applyValidateRules: convert validate tag to constraints of json schema
+ */
+func applyValidateRules(schema *openApiSchema, field reflect.StructField) {
+	for rule, value := range validateRules(field) {
+		switch rule {
+		case "email":
+			schema.Format = "email"
+		case "uuid":
+			schema.Format = "uuid"
+		case "url":
+			schema.Format = "uri"
+		case "oneof":
+			for _, item := range strings.Fields(value) {
+				if schema.Type == "integer" || schema.Type == "number" {
+					if number, err := strconv.ParseFloat(item, 64); err == nil {
+						schema.Enum = append(schema.Enum, number)
+						continue
+					}
+				}
+				schema.Enum = append(schema.Enum, item)
+			}
+		case "min", "max", "len", "gte", "lte", "gt", "lt":
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			applyValidateLimit(schema, rule, number)
+		}
+	}
+}
+
+func applyValidateLimit(schema *openApiSchema, rule string, number float64) {
+	length := int(number)
+	switch schema.Type {
+	case "string":
+		switch rule {
+		case "min", "gte":
+			schema.MinLength = &length
+		case "max", "lte":
+			schema.MaxLength = &length
+		case "len":
+			schema.MinLength = &length
+			schema.MaxLength = &length
+		}
+	case "array":
+		switch rule {
+		case "min", "gte":
+			schema.MinItems = &length
+		case "max", "lte":
+			schema.MaxItems = &length
+		case "len":
+			schema.MinItems = &length
+			schema.MaxItems = &length
+		}
+	case "integer", "number":
+		switch rule {
+		case "min", "gte":
+			schema.Minimum = &number
+		case "max", "lte":
+			schema.Maximum = &number
+		case "gt":
+			schema.ExclusiveMinimum = &number
+		case "lt":
+			schema.ExclusiveMaximum = &number
+		case "len":
+			schema.Minimum = &number
+			schema.Maximum = &number
+		}
+	}
+}
+
+/*
+* swaggerUiPageData: values of swagger ui page, they are escaped by html template
+* Integrity is set only if it is configured, it is the hash of asset file of SWAGGER_UI_VERSION
+ */
+type swaggerUiPageData struct {
+	Title        string
+	DocumentUrl  string
+	AssetsUrl    string
+	CssIntegrity string
+	JsIntegrity  string
+	CrossOrigin  bool
+}
+
+var swaggerUiTemplate = template.Must(template.New("swagger-ui").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+	<meta charset="utf-8" />
+	<meta name="viewport" content="width=device-width, initial-scale=1" />
+	<title>{{ .Title }}</title>
+	<link rel="stylesheet" href="{{ .AssetsUrl }}/swagger-ui.css"{{ if .CssIntegrity }} integrity="{{ .CssIntegrity }}"{{ end }}{{ if .CrossOrigin }} crossorigin="anonymous"{{ end }} />
+</head>
+<body>
+	<div id="swagger-ui"></div>
+	<script src="{{ .AssetsUrl }}/swagger-ui-bundle.js"{{ if .JsIntegrity }} integrity="{{ .JsIntegrity }}"{{ end }}{{ if .CrossOrigin }} crossorigin="anonymous"{{ end }}></script>
+	<script>
+		window.onload = function () {
+			window.ui = SwaggerUIBundle({ url: {{ .DocumentUrl }}, dom_id: "#swagger-ui" });
+		};
+	</script>
+</body>
+</html>
+`))
@@ -0,0 +1,183 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+type testOpenApiRequest struct {
+	Id     int64  `path:"id"`
+	Fields string `query:"fields"`
+	Name   string `json:"name" validate:"required,max=50"`
+	Role   string `json:"role" validate:"oneof=admin member"`
+}
+
+type testOpenApiResponse struct {
+	Id   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestGenerateOpenApiDocument(t *testing.T) {
+	RegisterAPI("/test/openapi/users/{id:int}", http.MethodPut, func(ctx *HttpContext, request testOpenApiRequest) (HttpResponse, HttpError) {
+		return nil, nil
+	}).SetSummary("Update user").SetTags("user").SetResponseModel(testOpenApiResponse{})
+
+	RegisterAPI("/test/openapi/hidden", http.MethodGet, testDispatchHandler).Hide()
+
+	data, err := GenerateOpenApiDocument()
+	if err != nil {
+		t.Fatalf("Generate document fail: %v", err)
+	}
+
+	var document map[string]any
+	if err := json.Unmarshal(data, &document); err != nil {
+		t.Fatalf("Unmarshal document fail: %v", err)
+	}
+
+	paths := document["paths"].(map[string]any)
+	if _, ok := paths["/test/openapi/hidden"]; ok {
+		t.Errorf("Expected hidden api is not in document")
+	}
+
+	path, ok := paths["/test/openapi/users/{id}"].(map[string]any)
+	if !ok {
+		t.Fatalf("Expected /test/openapi/users/{id} in document, got %v", paths)
+	}
+
+	operation := path["put"].(map[string]any)
+	if operation["summary"] != "Update user" {
+		t.Errorf("Expected summary Update user, got %v", operation["summary"])
+	}
+
+	parameters := operation["parameters"].([]any)
+	if len(parameters) != 2 {
+		t.Fatalf("Expected 2 parameters, got %v", parameters)
+	}
+
+	idParameter := parameters[0].(map[string]any)
+	if idParameter["in"] != "path" || idParameter["schema"].(map[string]any)["type"] != "integer" {
+		t.Errorf("Expected integer path param, got %v", idParameter)
+	}
+
+	schemas := document["components"].(map[string]any)["schemas"].(map[string]any)
+	request := schemas["testOpenApiRequest"].(map[string]any)
+	properties := request["properties"].(map[string]any)
+	if _, ok := properties["fields"]; ok {
+		t.Errorf("Expected query field is not in body schema")
+	}
+
+	name := properties["name"].(map[string]any)
+	if name["maxLength"] != float64(50) {
+		t.Errorf("Expected maxLength 50, got %v", name["maxLength"])
+	}
+
+	role := properties["role"].(map[string]any)
+	if enum, _ := role["enum"].([]any); len(enum) != 2 {
+		t.Errorf("Expected enum [admin member], got %v", role["enum"])
+	}
+
+	if _, ok := schemas["testOpenApiResponse"]; !ok {
+		t.Errorf("Expected response schema in components")
+	}
+}
+
+func TestGenerateOpenApiDocument_Streams(t *testing.T) {
+	RegisterSSE("/test/openapi/events", func(ctx *HttpContext, stream *SSEStream[testOpenApiResponse]) HttpError {
+		return nil
+	})
+	RegisterAPI("/test/openapi/export", http.MethodGet, func(ctx *HttpContext, request testStreamRequest) (HttpResponse, HttpError) {
+		return nil, nil
+	}).SetStreamResponse(testOpenApiResponse{}, STREAM_FORMAT_JSON_ARRAY, STREAM_FORMAT_NDJSON)
+
+	data, _ := GenerateOpenApiDocument()
+	var document struct {
+		Paths map[string]map[string]openApiOperation `json:"paths"`
+	}
+	json.Unmarshal(data, &document)
+
+	// Stream routes have their content type, not standard response body
+	events := document.Paths["/test/openapi/events"]["get"].Responses["200"].Content
+	if _, ok := events[EVENT_STREAM_CONTENT_TYPE]; !ok || len(events) != 1 {
+		t.Errorf("Expected event stream response, got %v", events)
+	}
+
+	export := document.Paths["/test/openapi/export"]["get"].Responses["200"].Content
+	if len(export) != 2 || export[JSON_CONTENT_TYPE].Schema.Type != "array" || export[JSON_CONTENT_TYPE].Schema.Items.Ref != "#/components/schemas/testOpenApiResponse" {
+		t.Errorf("Expected json array of items, got %v", export[JSON_CONTENT_TYPE].Schema)
+	}
+	if export[NDJSON_CONTENT_TYPE].Schema == nil || export[NDJSON_CONTENT_TYPE].Schema.Ref != "#/components/schemas/testOpenApiResponse" {
+		t.Errorf("Expected ndjson item schema, got %v", export[NDJSON_CONTENT_TYPE].Schema)
+	}
+}
+
+func TestSwaggerUiPage(t *testing.T) {
+	assetsPath := t.TempDir()
+	os.WriteFile(filepath.Join(assetsPath, SWAGGER_UI_JS_FILE), []byte("var SwaggerUIBundle;"), 0644)
+	os.WriteFile(filepath.Join(assetsPath, "secret.txt"), []byte("secret"), 0644)
+
+	openApiConfig := Config.OpenApi
+	Config.OpenApi = OpenApiConfig{
+		Path:         "/test/openapi/document.json",
+		UiPath:       "/test/openapi/ui",
+		Title:        "<script>alert(1)</script>",
+		UiAssetsPath: assetsPath,
+	}
+	defer func() { Config.OpenApi = openApiConfig }()
+	registerOpenApi()
+
+	recorder := httptest.NewRecorder()
+	dispatchRequest(recorder, httptest.NewRequest(http.MethodGet, "/test/openapi/ui", nil))
+	body := recorder.Body.String()
+	if strings.Contains(body, "<script>alert(1)</script>") || !strings.Contains(body, "&lt;script&gt;alert(1)&lt;/script&gt;") {
+		t.Errorf("Expected escaped title, got %s", body)
+	}
+	if strings.Contains(body, "unpkg.com") || !strings.Contains(body, `src="/test/openapi/ui/assets/swagger-ui-bundle.js"`) {
+		t.Errorf("Expected local assets, got %s", body)
+	}
+
+	recorder = httptest.NewRecorder()
+	dispatchRequest(recorder, httptest.NewRequest(http.MethodGet, "/test/openapi/ui/assets/swagger-ui-bundle.js", nil))
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "var SwaggerUIBundle;" {
+		t.Errorf("Expected local asset to be served, got %d %s", recorder.Code, recorder.Body.String())
+	}
+
+	recorder = httptest.NewRecorder()
+	dispatchRequest(recorder, httptest.NewRequest(http.MethodGet, "/test/openapi/ui/assets/secret.txt", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected only swagger ui files to be served, got %d", recorder.Code)
+	}
+}
+
+func TestSwaggerUiPage_EmbeddedAssets(t *testing.T) {
+	assets := swaggerUiAssets
+	swaggerUiAssets = fstest.MapFS{
+		SWAGGER_UI_CSS_FILE: {Data: []byte("body {}")},
+		SWAGGER_UI_JS_FILE:  {Data: []byte("var SwaggerUIBundle;")},
+	}
+	openApiConfig := Config.OpenApi
+	Config.OpenApi = OpenApiConfig{Path: "/test/openapi/embedded/document.json", UiPath: "/test/openapi/embedded/ui"}
+	defer func() {
+		swaggerUiAssets = assets
+		Config.OpenApi = openApiConfig
+	}()
+	registerOpenApi()
+
+	// Embedded assets are served from the same origin without cdn
+	recorder := httptest.NewRecorder()
+	dispatchRequest(recorder, httptest.NewRequest(http.MethodGet, "/test/openapi/embedded/ui", nil))
+	if body := recorder.Body.String(); strings.Contains(body, "unpkg.com") || !strings.Contains(body, `href="/test/openapi/embedded/ui/assets/swagger-ui.css"`) {
+		t.Errorf("Expected embedded assets, got %s", body)
+	}
+
+	recorder = httptest.NewRecorder()
+	dispatchRequest(recorder, httptest.NewRequest(http.MethodGet, "/test/openapi/embedded/ui/assets/swagger-ui.css", nil))
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "body {}" {
+		t.Errorf("Expected embedded asset to be served, got %d %s", recorder.Code, recorder.Body.String())
+	}
+}
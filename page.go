@@ -1,6 +1,7 @@
 package core
 
 import (
+	"bytes"
 	"html/template"
 	"net/http"
 	"os"
@@ -56,7 +57,7 @@ func RegisterPage(url string, handler PageHandler, middleware ...PageMiddleware)
 		htmlTemplateMap[url] = tmpl
 	}
 
-	pageMap[url] = pageInfo
+	router.insert(url).page = &pageInfo
 }
 
 /*
@@ -65,15 +66,20 @@ func RegisterPage(url string, handler PageHandler, middleware ...PageMiddleware)
 * If middleware return error, page will not be rendered
 * If middleware return nil, page will be rendered
  */
-func pageHandler(pageInfo pageInfo, w http.ResponseWriter, r *http.Request) {
+func pageHandler(pageInfo pageInfo, w http.ResponseWriter, r *http.Request, optional optionalParams) {
 	// Get http context
 	ctx := getHttpContext()
 	defer putHttpContext(ctx)
+	defer recoverHttpPanic(ctx)
 	ctx.LogInfo("Handle page: %s, requestID = %s", r.URL.String(), ctx.requestID)
 
 	ctx.request = r
 	ctx.rw = w
 	ctx.URL = r.URL
+	ctx.Method = r.Method
+	if optional.urlParams != nil {
+		ctx.urlParams = optional.urlParams
+	}
 	// Implement common page middleware
 	// Check if middleware is not nil
 	request := PageRequest{}
@@ -121,15 +127,29 @@ func pageHandler(pageInfo pageInfo, w http.ResponseWriter, r *http.Request) {
 		htmlTemplateMap[pageInfo.url] = tmpl
 	}
 
+	// Cached template is not executed when csrf is used, so its copy with token of request can be created
+	if csrf != nil {
+		var cloneError error
+		if tmpl, cloneError = csrf.templateWithToken(ctx, tmpl); cloneError != nil {
+			ctx.LogError("Error when clone template: %s", cloneError)
+			http.Error(w, cloneError.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
 	w.Header().Set("Request-ID", ctx.requestID)
 
-	// Execute template
-	if originError := tmpl.Execute(w, pageInfo.data); originError != nil {
+	// Execute template into buffer, so ETag can be computed from the whole page
+	var buffer bytes.Buffer
+	if originError := tmpl.Execute(&buffer, pageInfo.data); originError != nil {
 		ctx.LogError("Error when execute template: %s", originError)
 		http.Error(w, originError.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	w.Header().Set(CONTENT_TYPE_KEY, TEXT_HTML_CONTENT_TYPE+"; charset=utf-8")
+	ctx.endCachedResponse(http.StatusOK, buffer.String())
+
 	ctx.LogInfo("Render page successfully: %s, requestID = %s", pageInfo.url, ctx.requestID)
 }
 
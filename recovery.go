@@ -0,0 +1,81 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gorilla/websocket"
+)
+
+/*
+* recoverHttpPanic: recover panic of page, upload and sse handler in goroutine of net/http
+* It must be deferred after putHttpContext, so the response is written before context is put back to pool
+* Stack is logged with request ID and 500 error is written if response is not written yet
+* http.ErrAbortHandler is panicked again, so net/http aborts the response without logging
+ */
+func recoverHttpPanic(ctx *HttpContext) {
+	p := recover()
+	if p == nil {
+		return
+	}
+
+	if isAbortPanic(p) {
+		ctx.LogInfo("Handler is aborted: Url = %v", ctx.URL)
+		panic(p)
+	}
+	handleHttpPanic(ctx, p)
+}
+
+/*
+* isAbortPanic: client is gone, net/http uses this panic to abort response silently
+ */
+func isAbortPanic(p any) bool {
+	err, ok := p.(error)
+	return ok && errors.Is(err, http.ErrAbortHandler)
+}
+
+/*
+* handleHttpPanic: log stack, increase metric and write 500 error
+ */
+func handleHttpPanic(ctx *HttpContext, p any) {
+	IncreaseMetric(METRIC_HTTP_PANIC)
+	ctx.LogError("Panic: Url = %v, method = %s, panic = %v\n%s", ctx.URL, ctx.Method, p, debug.Stack())
+	if ctx.rw != nil {
+		ctx.writeError(HTTP_ERROR_INTERNAL_SERVER_ERROR)
+	}
+}
+
+/*
+* recoverWebsocketPanic: recover panic of websocket handler and middlewares
+* Before upgrade, 500 error is written as http response
+* After upgrade, error is sent as the last message and connection is closed with internal error code
+ */
+func recoverWebsocketPanic(ctx *websocketContext, w http.ResponseWriter, r *http.Request, connection **websocket.Conn) {
+	p := recover()
+	if p == nil {
+		return
+	}
+
+	IncreaseMetric(METRIC_WEBSOCKET_PANIC)
+	ctx.LogError("Panic: Url = %v, panic = %v\n%s", r.URL, p, debug.Stack())
+
+	if *connection == nil {
+		handshakeContext := getHttpContext()
+		defer putHttpContext(handshakeContext)
+		handshakeContext.rw = w
+		handshakeContext.request = r
+		handshakeContext.requestID = ctx.GetContextID()
+		handshakeContext.writeError(HTTP_ERROR_INTERNAL_SERVER_ERROR)
+		return
+	}
+
+	message, _ := json.Marshal(responseBody{
+		Code:    HTTP_ERROR_INTERNAL_SERVER_ERROR.GetCode(),
+		Message: HTTP_ERROR_INTERNAL_SERVER_ERROR.GetMessage() + " (RequestID: " + ctx.GetContextID() + ")",
+	})
+	(*connection).WriteMessage(websocket.TextMessage, message)
+	(*connection).WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, BLANK))
+	(*connection).Close()
+}
@@ -0,0 +1,139 @@
+package core
+
+import (
+	"reflect"
+	"time"
+)
+
+/*
+* RouteConfig: optional settings of an api, it is returned when api is registered
+* Ex: RegisterAPI("/users/{id}", http.MethodGet, getUser).SetSummary("Get user").SetResponseModel(User{})
+ */
+type RouteConfig struct {
+	summary      string
+	description  string
+	tags         []string
+	responseType reflect.Type
+	hidden       bool
+	timeout      time.Duration
+	maxBodySize  int64
+	streamBody   bool
+	heartbeat    time.Duration
+	version      *ApiVersion
+
+	// Response is event stream of RegisterSSE or stream response in these formats, they are shown in api document
+	eventStream   bool
+	streamFormats []StreamFormat
+}
+
+func newRouteConfig() *RouteConfig {
+	return &RouteConfig{}
+}
+
+/*
+* SetSummary: short summary of api, it is shown in api document
+ */
+func (config *RouteConfig) SetSummary(summary string) *RouteConfig {
+	config.summary = summary
+	return config
+}
+
+/*
+* SetDescription: long description of api, it is shown in api document
+ */
+func (config *RouteConfig) SetDescription(description string) *RouteConfig {
+	config.description = description
+	return config
+}
+
+/*
+* SetTags: tags which are used to group api in api document
+ */
+func (config *RouteConfig) SetTags(tags ...string) *RouteConfig {
+	config.tags = tags
+	return config
+}
+
+/*
+* SetResponseModel: declare type of data in success response
+* @param model: zero value of response type. Ex: User{}, []User{}
+ */
+func (config *RouteConfig) SetResponseModel(model any) *RouteConfig {
+	config.responseType = reflect.TypeOf(model)
+	return config
+}
+
+/*
+* SetStreamResponse: declare that api returns stream response instead of standard response body
+* Ex: RegisterAPI("/users/export", http.MethodGet, exportUsers).SetStreamResponse(User{}, STREAM_FORMAT_CSV)
+* @param model: zero value of item type
+* @param formats: formats which api can return, all formats if it is empty
+ */
+func (config *RouteConfig) SetStreamResponse(model any, formats ...StreamFormat) *RouteConfig {
+	if len(formats) == 0 {
+		formats = []StreamFormat{STREAM_FORMAT_JSON_ARRAY, STREAM_FORMAT_NDJSON, STREAM_FORMAT_CSV}
+	}
+	config.responseType = reflect.TypeOf(model)
+	config.streamFormats = formats
+	return config
+}
+
+/*
+* SetTimeout: timeout of api, it overrides timeout in context config
+* When timeout is reached, 504 error is responded and later writes of handler are discarded
+ */
+func (config *RouteConfig) SetTimeout(timeout time.Duration) *RouteConfig {
+	config.timeout = timeout
+	return config
+}
+
+/*
+* SetMaxBodySize: max size of request body in bytes, it overrides max body size in server config
+* Request which has bigger body is responded with 413 error. Negative value means no limit
+ */
+func (config *RouteConfig) SetMaxBodySize(size int64) *RouteConfig {
+	config.maxBodySize = size
+	return config
+}
+
+/*
+* SetStreamBody: request body is not buffered, handler reads it by ctx.GetBodyReader()
+* Request body is not unmarshalled into request struct, only query, path and header values are bound
+ */
+func (config *RouteConfig) SetStreamBody() *RouteConfig {
+	config.streamBody = true
+	return config
+}
+
+/*
+* getMaxBodySize: max body size of route, max body size in server config is used if it is not set
+ */
+func (config *RouteConfig) getMaxBodySize() int64 {
+	if config.maxBodySize != 0 {
+		return config.maxBodySize
+	}
+	return Config.GetMaxBodySize()
+}
+
+/*
+* SetHeartbeatInterval: interval of heartbeat comment of event stream which is registered by RegisterSSE
+ */
+func (config *RouteConfig) SetHeartbeatInterval(interval time.Duration) *RouteConfig {
+	config.heartbeat = interval
+	return config
+}
+
+func (config *RouteConfig) getHeartbeatInterval() time.Duration {
+	if config.heartbeat > 0 {
+		return config.heartbeat
+	}
+	return DEFAULT_SSE_HEARTBEAT_INTERVAL
+}
+
+/*
+* Hide: do not show api in api document
+ */
+func (config *RouteConfig) Hide() *RouteConfig {
+	config.hidden = true
+	return config
+}
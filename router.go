@@ -0,0 +1,325 @@
+package core
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+/*
+* routeTree: segment based radix tree which holds every registered handler
+* (api, page, websocket and upload). A request path is looked up one segment
+* at a time with a deterministic priority: static > param > wildcard
+ */
+type routeTree struct {
+	root *routeNode
+}
+
+type routeNode struct {
+	staticChildren map[string]*routeNode
+	paramChildren  []*routeNode
+	wildcardChild  *routeNode
+	paramType      string
+	entry          *routeEntry
+}
+
+/*
+* routeEntry: all handlers registered on the same url pattern
+ */
+type routeEntry struct {
+	pattern   string
+	paramKeys []string
+	routes    []Route
+	page      *pageInfo
+	websocket *websocketRoute
+	upload    *UploadFileHandler
+}
+
+// Type of url param: {id:int}, {id:uuid}. A param without type matches any non empty segment
+const (
+	ROUTE_PARAM_TYPE_ANY  = ""
+	ROUTE_PARAM_TYPE_INT  = "int"
+	ROUTE_PARAM_TYPE_UUID = "uuid"
+)
+
+// Priority of typed param when many params are registered at the same position
+var routeParamTypePriority = map[string]int{
+	ROUTE_PARAM_TYPE_INT:  0,
+	ROUTE_PARAM_TYPE_UUID: 1,
+	ROUTE_PARAM_TYPE_ANY:  2,
+}
+
+func newRouteTree() *routeTree {
+	return &routeTree{
+		root: newRouteNode(ROUTE_PARAM_TYPE_ANY),
+	}
+}
+
+func newRouteNode(paramType string) *routeNode {
+	return &routeNode{
+		staticChildren: make(map[string]*routeNode),
+		paramType:      paramType,
+	}
+}
+
+/*
+* insert: get the entry of url pattern, create it if it does not exist
+* @param pattern: url pattern. Ex: /users/{id:int}/files/*path
+* @return *routeEntry
+ */
+func (tree *routeTree) insert(pattern string) *routeEntry {
+	node := tree.root
+	paramKeys := []string{}
+	segments := splitRoutePath(pattern)
+
+	for i, segment := range segments {
+		switch {
+		case strings.HasPrefix(segment, "*"):
+			if i != len(segments)-1 || len(segment) == 1 {
+				LogFatal("Invalid route pattern: %s, wildcard must be the last segment and have a name", pattern)
+			}
+			paramKeys = append(paramKeys, segment[1:])
+			if node.wildcardChild == nil {
+				node.wildcardChild = newRouteNode(ROUTE_PARAM_TYPE_ANY)
+			}
+			node = node.wildcardChild
+		case strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}"):
+			name, paramType := parseRouteParam(segment)
+			if name == BLANK {
+				LogFatal("Invalid route pattern: %s, param name is empty", pattern)
+			}
+			if _, ok := routeParamTypePriority[paramType]; !ok {
+				LogFatal("Invalid route pattern: %s, unknown param type: %s", pattern, paramType)
+			}
+			paramKeys = append(paramKeys, name)
+			node = node.paramChild(paramType)
+		default:
+			if strings.ContainsAny(segment, "{}") {
+				LogFatal("Invalid route pattern: %s, param must fill the whole segment", pattern)
+			}
+			child, ok := node.staticChildren[segment]
+			if !ok {
+				child = newRouteNode(ROUTE_PARAM_TYPE_ANY)
+				node.staticChildren[segment] = child
+			}
+			node = child
+		}
+	}
+
+	if node.entry == nil {
+		node.entry = &routeEntry{
+			pattern:   pattern,
+			paramKeys: paramKeys,
+		}
+	} else if !slices.Equal(node.entry.paramKeys, paramKeys) {
+		// Handlers of one entry share param names, so {uid} cannot reuse entry of {id}
+		LogFatal("Invalid route pattern: %s, param names differ from registered pattern %s", pattern, node.entry.pattern)
+	}
+
+	return node.entry
+}
+
+/*
+* lookup: find the entry which matches the request path
+* @param path: request path
+* @return *routeEntry, map[string]string: entry and its url params (nil if entry has no param)
+ */
+func (tree *routeTree) lookup(path string) (*routeEntry, map[string]string) {
+	values := make([]string, 0, 4)
+	entry, values := tree.root.match(splitRoutePath(path), values)
+	if entry == nil {
+		return nil, nil
+	}
+
+	if len(entry.paramKeys) == 0 {
+		return entry, nil
+	}
+
+	params := make(map[string]string, len(entry.paramKeys))
+	for i, key := range entry.paramKeys {
+		params[key] = values[i]
+	}
+	return entry, params
+}
+
+/*
+* findRoute: find api route of method. HEAD request is served by GET route if HEAD is not registered
+ */
+func (entry *routeEntry) findRoute(method string) (Route, bool) {
+	for _, route := range entry.routes {
+		if route.Method == method {
+			return route, true
+		}
+	}
+
+	if method == http.MethodHead {
+		return entry.findRoute(http.MethodGet)
+	}
+
+	return Route{}, false
+}
+
+/*
+* allowedMethods: list all methods which are registered on the entry
+* HEAD is added for every GET route and OPTIONS is always answered
+ */
+func (entry *routeEntry) allowedMethods() []string {
+	methods := []string{}
+	addMethod := func(method string) {
+		if !slices.Contains(methods, method) {
+			methods = append(methods, method)
+		}
+	}
+
+	if entry.page != nil || entry.websocket != nil {
+		addMethod(http.MethodGet)
+	}
+
+	for _, route := range entry.routes {
+		addMethod(route.Method)
+	}
+
+	if entry.upload != nil {
+		addMethod(entry.upload.Method)
+	}
+
+	if slices.Contains(methods, http.MethodGet) {
+		addMethod(http.MethodHead)
+	}
+	addMethod(http.MethodOptions)
+
+	return methods
+}
+
+/*
+* walk: visit every entry of the tree, static segments are visited in sorted order
+ */
+func (tree *routeTree) walk(fn func(entry *routeEntry)) {
+	tree.root.walk(fn)
+}
+
+func (node *routeNode) walk(fn func(entry *routeEntry)) {
+	if node.entry != nil {
+		fn(node.entry)
+	}
+
+	keys := make([]string, 0, len(node.staticChildren))
+	for key := range node.staticChildren {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		node.staticChildren[key].walk(fn)
+	}
+
+	for _, child := range node.paramChildren {
+		child.walk(fn)
+	}
+
+	if node.wildcardChild != nil {
+		node.wildcardChild.walk(fn)
+	}
+}
+
+func (node *routeNode) paramChild(paramType string) *routeNode {
+	for _, child := range node.paramChildren {
+		if child.paramType == paramType {
+			return child
+		}
+	}
+
+	child := newRouteNode(paramType)
+	// Keep param children sorted by priority so the match order is deterministic
+	index := len(node.paramChildren)
+	for i, existed := range node.paramChildren {
+		if routeParamTypePriority[paramType] < routeParamTypePriority[existed.paramType] {
+			index = i
+			break
+		}
+	}
+	node.paramChildren = append(node.paramChildren, nil)
+	copy(node.paramChildren[index+1:], node.paramChildren[index:])
+	node.paramChildren[index] = child
+	return child
+}
+
+/*
+* match: match the segments with the sub tree, static > param > wildcard
+* If a branch fails deeper in the tree, the next branch with lower priority is tried
+ */
+func (node *routeNode) match(segments []string, values []string) (*routeEntry, []string) {
+	if len(segments) == 0 {
+		return node.entry, values
+	}
+
+	segment := segments[0]
+	if child, ok := node.staticChildren[segment]; ok {
+		if entry, result := child.match(segments[1:], values); entry != nil {
+			return entry, result
+		}
+	}
+
+	if segment != BLANK {
+		for _, child := range node.paramChildren {
+			if !matchRouteParamType(child.paramType, segment) {
+				continue
+			}
+
+			if entry, result := child.match(segments[1:], append(values, segment)); entry != nil {
+				return entry, result
+			}
+		}
+	}
+
+	if node.wildcardChild != nil && node.wildcardChild.entry != nil {
+		return node.wildcardChild.entry, append(values, strings.Join(segments, "/"))
+	}
+
+	return nil, values
+}
+
+func splitRoutePath(path string) []string {
+	return strings.Split(strings.TrimPrefix(path, "/"), "/")
+}
+
+func parseRouteParam(segment string) (string, string) {
+	param := segment[1 : len(segment)-1]
+	name, paramType, _ := strings.Cut(param, ":")
+	return strings.TrimSpace(name), strings.TrimSpace(paramType)
+}
+
+func matchRouteParamType(paramType string, value string) bool {
+	switch paramType {
+	case ROUTE_PARAM_TYPE_INT:
+		if strings.HasPrefix(value, "-") {
+			value = value[1:]
+		}
+		if value == BLANK {
+			return false
+		}
+		for _, c := range value {
+			if c < '0' || c > '9' {
+				return false
+			}
+		}
+		return true
+	case ROUTE_PARAM_TYPE_UUID:
+		if len(value) != 36 {
+			return false
+		}
+		for i, c := range value {
+			if i == 8 || i == 13 || i == 18 || i == 23 {
+				if c != '-' {
+					return false
+				}
+				continue
+			}
+			if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+				return false
+			}
+		}
+		return true
+	default:
+		return true
+	}
+}
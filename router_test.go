@@ -0,0 +1,120 @@
+package core
+
+import "testing"
+
+func TestRouteTree_StaticHasPriorityOverParam(t *testing.T) {
+	tree := newRouteTree()
+	tree.insert("/users/{id}")
+	tree.insert("/users/me")
+
+	entry, params := tree.lookup("/users/me")
+	if entry == nil || entry.pattern != "/users/me" {
+		t.Errorf("Expected /users/me, got %v", entry)
+	}
+
+	if params != nil {
+		t.Errorf("Expected no param, got %v", params)
+	}
+
+	entry, params = tree.lookup("/users/123")
+	if entry == nil || entry.pattern != "/users/{id}" {
+		t.Errorf("Expected /users/{id}, got %v", entry)
+		return
+	}
+
+	if params["id"] != "123" {
+		t.Errorf("Expected id = 123, got %s", params["id"])
+	}
+}
+
+func TestRouteTree_TypedParam(t *testing.T) {
+	tree := newRouteTree()
+	tree.insert("/orders/{code}")
+	tree.insert("/orders/{id:int}")
+	tree.insert("/orders/{uid:uuid}")
+
+	testCases := map[string]string{
+		"/orders/42":  "/orders/{id:int}",
+		"/orders/-42": "/orders/{id:int}",
+		"/orders/abc": "/orders/{code}",
+		"/orders/1b4e28ba-2fa1-11d2-883f-0016d3cca427": "/orders/{uid:uuid}",
+	}
+
+	for path, pattern := range testCases {
+		entry, _ := tree.lookup(path)
+		if entry == nil || entry.pattern != pattern {
+			t.Errorf("Path %s: expected %s, got %v", path, pattern, entry)
+		}
+	}
+}
+
+func TestRouteTree_BacktrackToParam(t *testing.T) {
+	tree := newRouteTree()
+	tree.insert("/files/static/info")
+	tree.insert("/files/{name}/download")
+
+	entry, params := tree.lookup("/files/static/download")
+	if entry == nil || entry.pattern != "/files/{name}/download" {
+		t.Errorf("Expected /files/{name}/download, got %v", entry)
+		return
+	}
+
+	if params["name"] != "static" {
+		t.Errorf("Expected name = static, got %s", params["name"])
+	}
+}
+
+func TestRouteTree_Wildcard(t *testing.T) {
+	tree := newRouteTree()
+	tree.insert("/assets/*path")
+	tree.insert("/assets/{name}")
+
+	entry, params := tree.lookup("/assets/css/main.css")
+	if entry == nil || entry.pattern != "/assets/*path" {
+		t.Errorf("Expected /assets/*path, got %v", entry)
+		return
+	}
+
+	if params["path"] != "css/main.css" {
+		t.Errorf("Expected path = css/main.css, got %s", params["path"])
+	}
+
+	entry, _ = tree.lookup("/assets/logo.png")
+	if entry == nil || entry.pattern != "/assets/{name}" {
+		t.Errorf("Expected /assets/{name}, got %v", entry)
+	}
+
+	entry, _ = tree.lookup("/assets")
+	if entry != nil {
+		t.Errorf("Expected no entry, got %v", entry)
+	}
+}
+
+func TestRouteTree_SameParamPositionDifferentName(t *testing.T) {
+	tree := newRouteTree()
+	tree.insert("/users/{id}")
+	tree.insert("/users/{userId}/posts/{postId}")
+
+	_, params := tree.lookup("/users/7/posts/9")
+	if params["userId"] != "7" || params["postId"] != "9" {
+		t.Errorf("Expected userId = 7, postId = 9, got %v", params)
+	}
+
+	_, params = tree.lookup("/users/7")
+	if params["id"] != "7" {
+		t.Errorf("Expected id = 7, got %v", params)
+	}
+}
+
+func TestRouteTree_TrailingSlash(t *testing.T) {
+	tree := newRouteTree()
+	tree.insert("/api/items")
+
+	if entry, _ := tree.lookup("/api/items/"); entry != nil {
+		t.Errorf("Expected no entry for trailing slash, got %v", entry)
+	}
+
+	if entry, _ := tree.lookup("/api/items"); entry == nil {
+		t.Errorf("Expected entry for /api/items")
+	}
+}
@@ -26,7 +26,13 @@ func startScheduler(interval time.Duration) {
 }
 
 func stopScheduler() {
+	// Scheduler is not started
+	if w == nil {
+		return
+	}
+
 	done <- true
+	w = nil
 }
 
 func GetBucket(time time.Time) int64 {
@@ -0,0 +1,210 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+var httpServer *http.Server
+var secureServer *http.Server
+
+var shutdownOnce sync.Once
+var shutdownChan = make(chan struct{})
+var shutdownError error
+
+// In-flight handlers: api, page, upload and websocket loops
+var activeHandlers sync.WaitGroup
+var websocketConnections sync.Map
+
+// Long running responses (event streams) which are cancelled when server is shut down
+var streamContexts sync.Map
+
+/*
+* newHttpServer: create http server from config
+* If h2c is used, HTTP/2 without TLS is served beside HTTP/1.1
+ */
+func newHttpServer(config ServerConfig, handler http.Handler) *http.Server {
+	server := &http.Server{
+		Addr:    config.GetAddress(),
+		Handler: handler,
+	}
+	config.ServerTimeoutsConfig.apply(server)
+
+	if config.H2C {
+		http2Server := &http2.Server{IdleTimeout: server.IdleTimeout}
+		// Connections of HTTP/2 are closed gracefully when server is shut down
+		if err := http2.ConfigureServer(server, http2Server); err != nil {
+			LogError("Configure http2 server fail: %v", err)
+		}
+		server.Handler = h2c.NewHandler(handler, http2Server)
+	}
+	return server
+}
+
+/*
+* newSecureServer: create https server from config, HTTP/2 is negotiated by TLS
+* Certificate of each handshake is taken from certificate store, so it can be reloaded
+* @return *http.Server, *certificateStore, error: error if tls settings or certificates in config are invalid
+ */
+func newSecureServer(config SecureServerConfig, handler http.Handler) (*http.Server, *certificateStore, error) {
+	tlsConfig, err := buildTLSConfig(config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certificates, err := newCertificateStore(config.GetCertificates())
+	if err != nil {
+		return nil, nil, err
+	}
+	tlsConfig.GetCertificate = certificates.getCertificate
+
+	server := &http.Server{
+		Addr:      config.GetAddress(),
+		Handler:   handler,
+		TLSConfig: tlsConfig,
+	}
+	config.ServerTimeoutsConfig.apply(server)
+	return server, certificates, nil
+}
+
+func (config ServerTimeoutsConfig) apply(server *http.Server) {
+	server.ReadTimeout = time.Second * time.Duration(config.ReadTimeout)
+	server.ReadHeaderTimeout = config.GetReadHeaderTimeout()
+	server.WriteTimeout = time.Second * time.Duration(config.WriteTimeout)
+	server.IdleTimeout = time.Second * time.Duration(config.IdleTimeout)
+	server.MaxHeaderBytes = config.MaxHeaderBytes
+}
+
+/*
+* waitForShutdown: block until SIGINT/SIGTERM is received or Shutdown is called
+ */
+func waitForShutdown() {
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signalChan)
+
+	select {
+	case sig := <-signalChan:
+		LogInfo("Receive signal: %s, shutdown server", sig)
+		ctx, cancel := context.WithTimeout(context.Background(), Config.GetShutdownTimeout())
+		defer cancel()
+		Shutdown(ctx)
+	case <-shutdownChan:
+	}
+}
+
+/*
+* Shutdown: stop server gracefully
+* Stop accepting new connections, wait for in-flight handlers and websocket loops
+* until ctx is done, then release all resources
+* Only the first call shuts down server, other calls wait for it
+* @param ctx: deadline of connection draining
+* @return error: error of http server shutdown
+ */
+func Shutdown(ctx context.Context) error {
+	shutdownOnce.Do(func() {
+		LogInfo("Shutdown server")
+		// Ask websocket clients to close connection and stop event streams
+		closeWebsocketConnections(false)
+		cancelStreams()
+
+		// Stop listeners and wait for idle connections
+		var wg sync.WaitGroup
+		for _, server := range []*http.Server{httpServer, secureServer} {
+			if server == nil {
+				continue
+			}
+
+			wg.Add(1)
+			go func(server *http.Server) {
+				defer wg.Done()
+				if err := server.Shutdown(ctx); err != nil {
+					LogError("Shutdown server %s fail: %v", server.Addr, err)
+					shutdownError = err
+				}
+			}(server)
+		}
+		wg.Wait()
+
+		// Wait for handlers which are not tracked by http server: hijacked websocket connections
+		handlerDone := make(chan struct{})
+		go func() {
+			activeHandlers.Wait()
+			close(handlerDone)
+		}()
+
+		select {
+		case <-handlerDone:
+			LogInfo("All handlers are finished")
+		case <-ctx.Done():
+			LogError("Shutdown deadline is exceeded, close remain connections")
+			closeWebsocketConnections(true)
+		}
+
+		// Notify all contexts which are derived from core context
+		coreContext.GetCancelFunc()()
+
+		Release()
+		LogInfo("Server is shut down")
+		close(shutdownChan)
+	})
+
+	<-shutdownChan
+	return shutdownError
+}
+
+func trackWebsocketConnection(connection *websocket.Conn) {
+	websocketConnections.Store(connection, struct{}{})
+}
+
+func untrackWebsocketConnection(connection *websocket.Conn) {
+	websocketConnections.Delete(connection)
+}
+
+func trackStream(ctx *HttpContext) {
+	streamContexts.Store(ctx, ctx.cancelFunc)
+}
+
+func untrackStream(ctx *HttpContext) {
+	streamContexts.Delete(ctx)
+}
+
+/*
+* cancelStreams: cancel context of all streams, so their handlers return and connections become idle
+ */
+func cancelStreams() {
+	streamContexts.Range(func(key, value any) bool {
+		value.(context.CancelFunc)()
+		return true
+	})
+}
+
+/*
+* closeWebsocketConnections: send close message to all websocket connections
+* @param force: close underlying connection immediately
+ */
+func closeWebsocketConnections(force bool) {
+	deadline := time.Now().Add(time.Second)
+	websocketConnections.Range(func(key, value any) bool {
+		connection := key.(*websocket.Conn)
+		if force {
+			connection.Close()
+			return true
+		}
+
+		message := websocket.FormatCloseMessage(websocket.CloseGoingAway, "Server is shutting down")
+		if err := connection.WriteControl(websocket.CloseMessage, message, deadline); err != nil {
+			connection.Close()
+		}
+		return true
+	})
+}
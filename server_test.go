@@ -0,0 +1,67 @@
+package core
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+func TestNewHttpServer_Timeouts(t *testing.T) {
+	server := newHttpServer(ServerConfig{
+		Port: 8080,
+		Host: "127.0.0.1",
+		ServerTimeoutsConfig: ServerTimeoutsConfig{
+			ReadTimeout:    5,
+			WriteTimeout:   10,
+			IdleTimeout:    60,
+			MaxHeaderBytes: 4096,
+		},
+	}, http.NotFoundHandler())
+
+	if server.Addr != "127.0.0.1:8080" {
+		t.Errorf("Expected address 127.0.0.1:8080, got %s", server.Addr)
+	}
+	if server.ReadTimeout != 5*time.Second || server.WriteTimeout != 10*time.Second || server.IdleTimeout != time.Minute || server.MaxHeaderBytes != 4096 {
+		t.Errorf("Expected timeouts from config, got %+v", server)
+	}
+	if server.ReadHeaderTimeout != DEFAULT_READ_HEADER_TIMEOUT*time.Second {
+		t.Errorf("Expected default read header timeout, got %v", server.ReadHeaderTimeout)
+	}
+}
+
+func TestNewHttpServer_H2C(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Proto)
+	})
+	server := newHttpServer(ServerConfig{H2C: true}, handler)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(listener)
+	defer server.Close()
+
+	// Client sends HTTP/2 with prior knowledge over plain tcp
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+	response, err := client.Get("http://" + listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Request fail: %v", err)
+	}
+	defer response.Body.Close()
+
+	if response.ProtoMajor != 2 {
+		t.Errorf("Expected HTTP/2 response, got %s", response.Proto)
+	}
+}
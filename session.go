@@ -0,0 +1,412 @@
+package core
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Stores of session
+const (
+	SESSION_STORE_MEMORY   = "memory"
+	SESSION_STORE_REDIS    = "redis"
+	SESSION_STORE_DATABASE = "database"
+)
+
+// Default settings of session
+const (
+	DEFAULT_SESSION_COOKIE_NAME      = "session_id"
+	DEFAULT_SESSION_IDLE_TIMEOUT     = 1800
+	DEFAULT_SESSION_ABSOLUTE_TIMEOUT = 86400
+)
+
+// Last access time of session which is only read is saved at most once in this interval
+const SESSION_TOUCH_INTERVAL = time.Minute
+
+// Key of flash messages in session values
+const SESSION_FLASH_KEY = "_flashes"
+
+const SESSION_ID_SIZE = 32
+
+/*
+* SessionData: values and times of session which are saved in store
+* Values are encoded as json, so every store saves the same data
+ */
+type SessionData struct {
+	Values       map[string]json.RawMessage `json:"values"`
+	CreatedAt    time.Time                  `json:"createdAt"`
+	LastAccessAt time.Time                  `json:"lastAccessAt"`
+}
+
+/*
+* SessionStore: storage of session data by session id
+* Load returns nil data without error if session is not found or expired
+ */
+type SessionStore interface {
+	Load(ctx Context, id string) (*SessionData, Error)
+	Save(ctx Context, id string, data SessionData, ttl time.Duration) Error
+	Delete(ctx Context, id string) Error
+}
+
+/*
+* Session: session of request, it is saved before response is written
+* Ex: ctx.Session().Set("accountID", account.ID)
+ */
+type Session struct {
+	mu        sync.Mutex
+	id        string
+	oldID     string
+	data      SessionData
+	isNew     bool
+	modified  bool
+	destroyed bool
+	committed bool
+}
+
+/*
+* sessionManager: cookie and store of sessions
+ */
+type sessionManager struct {
+	config SessionConfig
+	store  SessionStore
+	secret []byte
+}
+
+var sessions *sessionManager
+
+/*
+* UseSessions: enable sessions with store in config
+* Redis store uses redis client and database store uses sessions table of main database
+* @param config: session config, usually Config.Session
+ */
+func UseSessions(config SessionConfig) {
+	var store SessionStore
+	switch config.Store {
+	case SESSION_STORE_REDIS:
+		if redisClient.Client == nil {
+			LogFatal("Session store is redis but redis is not used")
+		}
+		store = redisSessionStore{}
+	case SESSION_STORE_DATABASE:
+		if mainDbSession == nil {
+			LogFatal("Session store is database but database is not used")
+		}
+		store = &dbSessionStore{}
+	case SESSION_STORE_MEMORY, BLANK:
+		store = newMemorySessionStore()
+	default:
+		LogFatal("Unsupported session store: %s", config.Store)
+	}
+
+	secret := []byte(config.Secret)
+	if len(secret) == 0 {
+		// Cookies which are signed by random secret are invalid after restart
+		LogError("Session secret is not set, random secret is used")
+		secret = make([]byte, 32)
+		rand.Read(secret)
+	}
+
+	LogInfo("Use sessions: store = %s, cookie = %s", config.Store, config.GetCookieName())
+	sessions = &sessionManager{
+		config: config,
+		store:  store,
+		secret: secret,
+	}
+}
+
+/*
+* SetSessionStore: replace store of sessions, UseSessions must be called before
+ */
+func SetSessionStore(store SessionStore) {
+	if sessions == nil {
+		LogFatal("Sessions are not used, call UseSessions before SetSessionStore")
+	}
+	sessions.store = store
+}
+
+/*
+* Session: session of request, it is loaded from cookie at the first call
+* Session is not saved and no cookie is set until it is modified
+ */
+func (ctx *HttpContext) Session() *Session {
+	if ctx.session == nil {
+		if sessions == nil {
+			ctx.LogError("Sessions are not used, session of request is not saved")
+			ctx.session = newSession()
+			ctx.session.committed = true
+		} else {
+			ctx.session = sessions.load(ctx)
+		}
+	}
+	return ctx.session
+}
+
+/*
+* commitSession: save session of request and set cookie of new session
+* It is called before response header is written
+ */
+func (ctx *HttpContext) commitSession() {
+	if ctx.session != nil && sessions != nil {
+		sessions.commit(ctx, ctx.session)
+	}
+}
+
+/*
+* GetSessionValue: typed value of session
+* Ex: accountID, ok := GetSessionValue[int64](ctx, "accountID")
+* @return T, bool: false if key is not found or value is not T
+ */
+func GetSessionValue[T any](ctx *HttpContext, key string) (T, bool) {
+	var value T
+	ok := ctx.Session().Get(key, &value)
+	return value, ok
+}
+
+func newSession() *Session {
+	now := time.Now()
+	return &Session{
+		id:    newSessionID(),
+		isNew: true,
+		data: SessionData{
+			Values:       make(map[string]json.RawMessage),
+			CreatedAt:    now,
+			LastAccessAt: now,
+		},
+	}
+}
+
+func newSessionID() string {
+	id := make([]byte, SESSION_ID_SIZE)
+	rand.Read(id)
+	return base64.RawURLEncoding.EncodeToString(id)
+}
+
+func (session *Session) GetID() string {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.id
+}
+
+/*
+* Get: decode value of key into dest
+* @param dest: pointer of value
+* @return bool: false if key is not found or value cannot be decoded into dest
+ */
+func (session *Session) Get(key string, dest any) bool {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	value, ok := session.data.Values[key]
+	return ok && json.Unmarshal(value, dest) == nil
+}
+
+/*
+* Set: set value of key, value is encoded as json
+ */
+func (session *Session) Set(key string, value any) Error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return NewError(ERROR_FROM_LIBRARY, err.Error())
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.data.Values[key] = encoded
+	session.modified = true
+	return nil
+}
+
+func (session *Session) Delete(key string) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if _, ok := session.data.Values[key]; ok {
+		delete(session.data.Values, key)
+		session.modified = true
+	}
+}
+
+/*
+* Regenerate: change id of session and keep its values
+* It is called after login, so id which is known before login cannot be used (session fixation)
+ */
+func (session *Session) Regenerate() {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if !session.isNew && session.oldID == BLANK {
+		session.oldID = session.id
+	}
+	session.id = newSessionID()
+	session.data.CreatedAt = time.Now()
+	session.modified = true
+}
+
+/*
+* Destroy: delete session from store and cookie, it is called at logout
+ */
+func (session *Session) Destroy() {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.destroyed = true
+	session.data.Values = make(map[string]json.RawMessage)
+}
+
+/*
+* AddFlash: add message which is shown in the next page request
+ */
+func (session *Session) AddFlash(message string) {
+	flashes := []string{}
+	session.Get(SESSION_FLASH_KEY, &flashes)
+	session.Set(SESSION_FLASH_KEY, append(flashes, message))
+}
+
+/*
+* Flashes: messages which are added by AddFlash, they are removed after reading
+ */
+func (session *Session) Flashes() []string {
+	flashes := []string{}
+	if session.Get(SESSION_FLASH_KEY, &flashes) {
+		session.Delete(SESSION_FLASH_KEY)
+	}
+	return flashes
+}
+
+/*
+* load: load session of signed id in cookie, new session is created if it is not found or expired
+ */
+func (manager *sessionManager) load(ctx *HttpContext) *Session {
+	cookie, err := ctx.request.Cookie(manager.config.GetCookieName())
+	if err != nil {
+		return newSession()
+	}
+
+	id, ok := manager.verify(cookie.Value)
+	if !ok {
+		ctx.LogInfo("Session cookie has invalid signature")
+		return newSession()
+	}
+
+	data, loadErr := manager.store.Load(ctx, id)
+	if loadErr != nil {
+		ctx.LogError("Load session fail: %v", loadErr)
+		return newSession()
+	}
+	if data == nil {
+		return newSession()
+	}
+	if manager.isExpired(*data) {
+		ctx.LogInfo("Session is expired: createdAt = %v, lastAccessAt = %v", data.CreatedAt, data.LastAccessAt)
+		manager.store.Delete(ctx, id)
+		return newSession()
+	}
+
+	if data.Values == nil {
+		data.Values = make(map[string]json.RawMessage)
+	}
+	return &Session{id: id, data: *data}
+}
+
+/*
+* commit: save modified session, or only last access time if it is older than SESSION_TOUCH_INTERVAL
+ */
+func (manager *sessionManager) commit(ctx *HttpContext, session *Session) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if session.committed {
+		return
+	}
+	session.committed = true
+
+	if session.destroyed {
+		for _, id := range []string{session.id, session.oldID} {
+			if id != BLANK && !(session.isNew && id == session.id) {
+				manager.store.Delete(ctx, id)
+			}
+		}
+		manager.setCookie(ctx, BLANK, -1)
+		return
+	}
+
+	now := time.Now()
+	touched := !session.isNew && now.Sub(session.data.LastAccessAt) > SESSION_TOUCH_INTERVAL
+	if !session.modified && !touched {
+		return
+	}
+
+	if session.oldID != BLANK {
+		manager.store.Delete(ctx, session.oldID)
+	}
+	session.data.LastAccessAt = now
+	if err := manager.store.Save(ctx, session.id, session.data, manager.ttl(session.data)); err != nil {
+		ctx.LogError("Save session fail: %v", err)
+		return
+	}
+
+	if session.isNew || session.oldID != BLANK {
+		maxAge := 0
+		if absolute := manager.config.GetAbsoluteTimeout(); absolute > 0 {
+			maxAge = int(absolute / time.Second)
+		}
+		manager.setCookie(ctx, manager.sign(session.id), maxAge)
+	}
+}
+
+/*
+* ttl: time until session expires by idle or absolute timeout, zero if session never expires
+ */
+func (manager *sessionManager) ttl(data SessionData) time.Duration {
+	ttl := manager.config.GetIdleTimeout()
+	if absolute := manager.config.GetAbsoluteTimeout(); absolute > 0 {
+		remaining := time.Until(data.CreatedAt.Add(absolute))
+		if ttl == 0 || remaining < ttl {
+			ttl = remaining
+		}
+	}
+	return ttl
+}
+
+func (manager *sessionManager) isExpired(data SessionData) bool {
+	now := time.Now()
+	if idle := manager.config.GetIdleTimeout(); idle > 0 && now.Sub(data.LastAccessAt) > idle {
+		return true
+	}
+	if absolute := manager.config.GetAbsoluteTimeout(); absolute > 0 && now.Sub(data.CreatedAt) > absolute {
+		return true
+	}
+	return false
+}
+
+func (manager *sessionManager) setCookie(ctx *HttpContext, value string, maxAge int) {
+	http.SetCookie(ctx.rw, &http.Cookie{
+		Name:     manager.config.GetCookieName(),
+		Value:    value,
+		MaxAge:   maxAge,
+		Path:     "/",
+		Domain:   manager.config.Domain,
+		Secure:   manager.config.Secure,
+		HttpOnly: true,
+		SameSite: manager.config.GetSameSite(),
+	})
+}
+
+/*
+* sign: cookie value is id and hmac of id, so client cannot choose id of session
+ */
+func (manager *sessionManager) sign(id string) string {
+	mac := hmac.New(sha256.New, manager.secret)
+	mac.Write([]byte(id))
+	return id + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (manager *sessionManager) verify(value string) (string, bool) {
+	id, _, found := strings.Cut(value, ".")
+	if !found || id == BLANK {
+		return BLANK, false
+	}
+	return id, subtle.ConstantTimeCompare([]byte(manager.sign(id)), []byte(value)) == 1
+}
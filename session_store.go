@@ -0,0 +1,194 @@
+package core
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Prefix of session keys in redis
+const SESSION_REDIS_KEY_PREFIX = "session:"
+
+// Expired sessions of memory and database store are removed at most once in this interval
+const SESSION_CLEANUP_INTERVAL = 5 * time.Minute
+
+/*
+* memorySessionStore: sessions in memory of server, they are lost after restart
+ */
+type memorySessionStore struct {
+	mu        sync.Mutex
+	sessions  map[string]memorySession
+	cleanedAt time.Time
+}
+
+type memorySession struct {
+	data      []byte
+	expiresAt time.Time
+}
+
+func newMemorySessionStore() *memorySessionStore {
+	return &memorySessionStore{
+		sessions:  make(map[string]memorySession),
+		cleanedAt: time.Now(),
+	}
+}
+
+func (store *memorySessionStore) Load(ctx Context, id string) (*SessionData, Error) {
+	store.mu.Lock()
+	session, ok := store.sessions[id]
+	store.mu.Unlock()
+	if !ok || (!session.expiresAt.IsZero() && time.Now().After(session.expiresAt)) {
+		return nil, nil
+	}
+
+	// Data is kept encoded, so request cannot change values of other requests
+	data := &SessionData{}
+	if err := json.Unmarshal(session.data, data); err != nil {
+		return nil, NewError(ERROR_FROM_LIBRARY, err.Error())
+	}
+	return data, nil
+}
+
+func (store *memorySessionStore) Save(ctx Context, id string, data SessionData, ttl time.Duration) Error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return NewError(ERROR_FROM_LIBRARY, err.Error())
+	}
+
+	session := memorySession{data: encoded}
+	if ttl > 0 {
+		session.expiresAt = time.Now().Add(ttl)
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.sessions[id] = session
+	if time.Since(store.cleanedAt) > SESSION_CLEANUP_INTERVAL {
+		store.cleanedAt = time.Now()
+		for key, value := range store.sessions {
+			if !value.expiresAt.IsZero() && store.cleanedAt.After(value.expiresAt) {
+				delete(store.sessions, key)
+			}
+		}
+	}
+	return nil
+}
+
+func (store *memorySessionStore) Delete(ctx Context, id string) Error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	delete(store.sessions, id)
+	return nil
+}
+
+/*
+* redisSessionStore: sessions in redis, they are expired by ttl of redis
+ */
+type redisSessionStore struct{}
+
+func (redisSessionStore) Load(ctx Context, id string) (*SessionData, Error) {
+	value, err := redisClient.Get(ctx, SESSION_REDIS_KEY_PREFIX+id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, NewError(ERROR_FROM_LIBRARY, err.Error())
+	}
+
+	data := &SessionData{}
+	if err := json.Unmarshal(value, data); err != nil {
+		return nil, NewError(ERROR_FROM_LIBRARY, err.Error())
+	}
+	return data, nil
+}
+
+func (redisSessionStore) Save(ctx Context, id string, data SessionData, ttl time.Duration) Error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return NewError(ERROR_FROM_LIBRARY, err.Error())
+	}
+
+	if err := redisClient.Set(ctx, SESSION_REDIS_KEY_PREFIX+id, encoded, ttl).Err(); err != nil {
+		return NewError(ERROR_FROM_LIBRARY, err.Error())
+	}
+	return nil
+}
+
+func (redisSessionStore) Delete(ctx Context, id string) Error {
+	if err := redisClient.Del(ctx, SESSION_REDIS_KEY_PREFIX+id).Err(); err != nil {
+		return NewError(ERROR_FROM_LIBRARY, err.Error())
+	}
+	return nil
+}
+
+/*
+* dbSessionStore: sessions in sessions table of main database
+* expires_at is unix time in seconds, zero if session never expires
+ */
+type dbSessionStore struct {
+	mu        sync.Mutex
+	cleanedAt time.Time
+}
+
+func (store *dbSessionStore) Load(ctx Context, id string) (*SessionData, Error) {
+	var value string
+	var expiresAt int64
+	err := DBSession().QueryRowContext(ctx, "SELECT data, expires_at FROM sessions WHERE id = $1", id).Scan(&value, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		ctx.LogError("Select session fail: %v", err)
+		return nil, ERROR_DB_ERROR
+	}
+	if expiresAt > 0 && time.Now().Unix() > expiresAt {
+		return nil, nil
+	}
+
+	data := &SessionData{}
+	if err := json.Unmarshal([]byte(value), data); err != nil {
+		return nil, NewError(ERROR_FROM_LIBRARY, err.Error())
+	}
+	return data, nil
+}
+
+func (store *dbSessionStore) Save(ctx Context, id string, data SessionData, ttl time.Duration) Error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return NewError(ERROR_FROM_LIBRARY, err.Error())
+	}
+
+	expiresAt := int64(0)
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl).Unix()
+	}
+	if _, err := DBSession().ExecContext(ctx, "INSERT INTO sessions(id, data, expires_at) VALUES ($1, $2, $3) ON CONFLICT (id) DO UPDATE SET data = EXCLUDED.data, expires_at = EXCLUDED.expires_at", id, string(encoded), expiresAt); err != nil {
+		ctx.LogError("Save session fail: %v", err)
+		return ERROR_DB_ERROR
+	}
+
+	store.mu.Lock()
+	cleanup := time.Since(store.cleanedAt) > SESSION_CLEANUP_INTERVAL
+	if cleanup {
+		store.cleanedAt = time.Now()
+	}
+	store.mu.Unlock()
+	if cleanup {
+		if _, err := DBSession().ExecContext(ctx, "DELETE FROM sessions WHERE expires_at > 0 AND expires_at < $1", time.Now().Unix()); err != nil {
+			ctx.LogError("Delete expired sessions fail: %v", err)
+		}
+	}
+	return nil
+}
+
+func (store *dbSessionStore) Delete(ctx Context, id string) Error {
+	if _, err := DBSession().ExecContext(ctx, "DELETE FROM sessions WHERE id = $1", id); err != nil {
+		ctx.LogError("Delete session fail: %v", err)
+		return ERROR_DB_ERROR
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func sendSessionRequest(url string, cookie *http.Cookie) (*httptest.ResponseRecorder, *http.Cookie) {
+	request := httptest.NewRequest(http.MethodGet, url, nil)
+	if cookie != nil {
+		request.AddCookie(cookie)
+	}
+	recorder := httptest.NewRecorder()
+	dispatchRequest(recorder, request)
+
+	for _, responseCookie := range recorder.Result().Cookies() {
+		if responseCookie.Name == DEFAULT_SESSION_COOKIE_NAME {
+			return recorder, responseCookie
+		}
+	}
+	return recorder, nil
+}
+
+func TestSession(t *testing.T) {
+	UseSessions(SessionConfig{Secret: "secret", IdleTimeout: 60})
+	defer func() { sessions = nil }()
+
+	RegisterAPI("/test/session/visit", http.MethodGet, func(ctx *HttpContext, request testDispatchRequest) (HttpResponse, HttpError) {
+		ctx.Session().Set("cart", []string{"book"})
+		return NewDefaultHttpResponse("ok"), nil
+	})
+	RegisterAPI("/test/session/login", http.MethodGet, func(ctx *HttpContext, request testDispatchRequest) (HttpResponse, HttpError) {
+		session := ctx.Session()
+		session.Regenerate()
+		session.Set("accountID", int64(10))
+		session.AddFlash("Welcome")
+		return NewDefaultHttpResponse("ok"), nil
+	})
+	RegisterAPI("/test/session/profile", http.MethodGet, func(ctx *HttpContext, request testDispatchRequest) (HttpResponse, HttpError) {
+		accountID, _ := GetSessionValue[int64](ctx, "accountID")
+		cart, _ := GetSessionValue[[]string](ctx, "cart")
+		flashes := ctx.Session().Flashes()
+		return NewDefaultHttpResponse(map[string]any{"accountID": accountID, "cart": cart, "flashes": flashes}), nil
+	})
+	RegisterAPI("/test/session/logout", http.MethodGet, func(ctx *HttpContext, request testDispatchRequest) (HttpResponse, HttpError) {
+		ctx.Session().Destroy()
+		return NewDefaultHttpResponse("ok"), nil
+	})
+
+	// Session which is only read has no cookie
+	if _, cookie := sendSessionRequest("/test/session/profile", nil); cookie != nil {
+		t.Errorf("Expected no cookie for unmodified session, got %v", cookie)
+	}
+
+	_, anonymous := sendSessionRequest("/test/session/visit", nil)
+	if anonymous == nil || !anonymous.HttpOnly || anonymous.MaxAge != DEFAULT_SESSION_ABSOLUTE_TIMEOUT {
+		t.Fatalf("Expected signed http only cookie, got %v", anonymous)
+	}
+
+	// Login keeps values and changes id
+	_, login := sendSessionRequest("/test/session/login", anonymous)
+	if login == nil || login.Value == anonymous.Value {
+		t.Fatalf("Expected regenerated session cookie, got %v", login)
+	}
+	if recorder, _ := sendSessionRequest("/test/session/profile", anonymous); strings.Contains(recorder.Body.String(), "book") {
+		t.Errorf("Expected old session id to be removed, got %s", recorder.Body.String())
+	}
+
+	recorder, _ := sendSessionRequest("/test/session/profile", login)
+	if body := recorder.Body.String(); !strings.Contains(body, `"accountID":10`) || !strings.Contains(body, `"cart":["book"]`) || !strings.Contains(body, `"flashes":["Welcome"]`) {
+		t.Errorf("Expected session values and flash, got %s", body)
+	}
+	if recorder, _ = sendSessionRequest("/test/session/profile", login); !strings.Contains(recorder.Body.String(), `"flashes":[]`) {
+		t.Errorf("Expected flash to be removed after reading, got %s", recorder.Body.String())
+	}
+
+	// Cookie with wrong signature is ignored
+	forged := &http.Cookie{Name: DEFAULT_SESSION_COOKIE_NAME, Value: strings.Split(login.Value, ".")[0] + ".forged"}
+	if recorder, _ = sendSessionRequest("/test/session/profile", forged); strings.Contains(recorder.Body.String(), `"accountID":10`) {
+		t.Errorf("Expected forged cookie to be rejected, got %s", recorder.Body.String())
+	}
+
+	// Session expires after idle timeout
+	id, _ := sessions.verify(login.Value)
+	data, _ := sessions.store.Load(coreContext, id)
+	data.LastAccessAt = time.Now().Add(-2 * time.Minute)
+	sessions.store.Save(coreContext, id, *data, time.Hour)
+	if recorder, _ = sendSessionRequest("/test/session/profile", login); strings.Contains(recorder.Body.String(), `"accountID":10`) {
+		t.Errorf("Expected idle session to be expired, got %s", recorder.Body.String())
+	}
+
+	// Logout deletes session and cookie
+	_, login = sendSessionRequest("/test/session/login", nil)
+	_, logout := sendSessionRequest("/test/session/logout", login)
+	if logout == nil || logout.MaxAge >= 0 {
+		t.Errorf("Expected session cookie to be deleted, got %v", logout)
+	}
+	if recorder, _ = sendSessionRequest("/test/session/profile", login); strings.Contains(recorder.Body.String(), `"accountID":10`) {
+		t.Errorf("Expected destroyed session, got %s", recorder.Body.String())
+	}
+}
+
+func TestSession_Streams(t *testing.T) {
+	UseSessions(SessionConfig{Secret: "secret"})
+	defer func() { sessions = nil }()
+
+	// Session of streams is committed before header is written
+	login := func(ctx *HttpContext) HttpError {
+		ctx.Session().Set("accountID", int64(10))
+		ctx.Next()
+		return nil
+	}
+	RegisterSSE("/test/session/events", func(ctx *HttpContext, stream *SSEStream[testSSEEvent]) HttpError {
+		return nil
+	}, login)
+	RegisterAPI("/test/session/export", http.MethodGet, func(ctx *HttpContext, request testStreamRequest) (HttpResponse, HttpError) {
+		ctx.Session().Set("accountID", int64(10))
+		items := make(chan testStreamItem)
+		close(items)
+		return NewChannelStreamResponse(request.Format, items), nil
+	})
+
+	for _, url := range []string{"/test/session/events", "/test/session/export"} {
+		if _, cookie := sendSessionRequest(url, nil); cookie == nil {
+			t.Errorf("%s: expected session cookie", url)
+		}
+	}
+}
+
+func TestSession_WebsocketHandshake(t *testing.T) {
+	UseSessions(SessionConfig{Secret: "secret"})
+	defer func() { sessions = nil }()
+
+	group := Group("/test/session/websocket", func(ctx *HttpContext) HttpError {
+		ctx.Session().Set("accountID", int64(10))
+		ctx.Next()
+		return nil
+	})
+	RegisterGroupWebsocket(group, "/chat", func(ctx WebsocketContext, request testDispatchRequest) (*WebsocketResponse, Error) { return nil, nil })
+
+	server := httptest.NewServer(http.HandlerFunc(dispatchRequest))
+	defer server.Close()
+
+	connection, response, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/test/session/websocket/chat", nil)
+	if err != nil {
+		t.Fatalf("Dial websocket fail: %v", err)
+	}
+	defer connection.Close()
+
+	// Session of handshake middlewares is committed with upgrade response
+	if !strings.Contains(response.Header.Get("Set-Cookie"), DEFAULT_SESSION_COOKIE_NAME+"=") {
+		t.Errorf("Expected session cookie in upgrade response, got %v", response.Header)
+	}
+}
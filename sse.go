@@ -0,0 +1,263 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Header keys of server sent events
+const (
+	LAST_EVENT_ID_KEY         = "Last-Event-ID"
+	EVENT_STREAM_CONTENT_TYPE = "text/event-stream"
+)
+
+// Interval of comment which keeps connection alive through proxies
+const DEFAULT_SSE_HEARTBEAT_INTERVAL = 15 * time.Second
+
+// Name of event which is sent when handler returns error
+const SSE_ERROR_EVENT = "error"
+
+/*
+* SSEHandler: handler of server sent events, it sends events until it returns or client disconnects
+* ctx.Done() is closed when client disconnects or server is shut down
+ */
+type SSEHandler[T any] func(ctx *HttpContext, stream *SSEStream[T]) HttpError
+
+/*
+* SSEStream: stream of events which data is T
+* Data is encoded as json, string data is sent as it is
+ */
+type SSEStream[T any] struct {
+	mu          sync.Mutex
+	ctx         *HttpContext
+	writer      http.ResponseWriter
+	flusher     http.Flusher
+	lastEventID string
+	nextID      int64
+	closed      bool
+}
+
+/*
+* RegisterSSE: register server sent events endpoint
+* Middlewares are executed like RegisterAPI before the stream is opened
+* @param url: url of endpoint
+* @param handler: handler which sends events to stream
+* @param middlewares: middlewares of endpoint
+* @return *RouteConfig: optional settings of endpoint
+ */
+func RegisterSSE[T any](url string, handler SSEHandler[T], middlewares ...ApiMiddleware) *RouteConfig {
+	LogInfo("Register SSE: %s", url)
+	config := newRouteConfig()
+	config.eventStream = true
+	config.responseType = reflect.TypeOf((*T)(nil)).Elem()
+
+	h := func(writer http.ResponseWriter, request *http.Request, optional optionalParams) {
+		// Stream has no timeout, it is stopped when client disconnects or server is shut down
+		ctx := getHttpContextWithoutTimeout()
+		defer putHttpContext(ctx)
+		defer recoverHttpPanic(ctx)
+		stopAfterDisconnect := context.AfterFunc(request.Context(), ctx.cancelFunc)
+		defer stopAfterDisconnect()
+		trackStream(ctx)
+		defer untrackStream(ctx)
+
+		errBuild := buildContext(ctx, writer, request)
+		ctx.allowedMethods = optional.allowedMethods
+		if optional.urlParams != nil {
+			ctx.urlParams = optional.urlParams
+		}
+		if errBuild != nil {
+			ctx.writeError(errBuild)
+			return
+		}
+
+		// Append to common middleware
+		middlewareList := []ApiMiddleware{}
+		middlewareList = append(middlewareList, commonApiMiddlewares...)
+		middlewareList = append(middlewareList, middlewares...)
+
+		// Call middleware of function
+		if executeApiMiddlewares(ctx, middlewareList) {
+			return
+		}
+
+		flusher, ok := writer.(http.Flusher)
+		if !ok {
+			ctx.LogError("Response writer does not support flush, cannot open event stream")
+			ctx.writeError(HTTP_ERROR_INTERNAL_SERVER_ERROR)
+			return
+		}
+
+		stream := &SSEStream[T]{
+			ctx:         ctx,
+			writer:      writer,
+			flusher:     flusher,
+			lastEventID: request.Header.Get(LAST_EVENT_ID_KEY),
+		}
+		if stream.lastEventID == BLANK {
+			// EventSource cannot set header when it is created, so id can be sent in query
+			stream.lastEventID = request.URL.Query().Get("lastEventId")
+		}
+		if id, err := strconv.ParseInt(stream.lastEventID, 10, 64); err == nil {
+			stream.nextID = id
+		}
+
+		stream.open()
+		defer stream.close()
+		// Done channel is taken here, because context is reset and put back to pool after handler returns
+		done := ctx.Done()
+		go stream.heartbeat(done, config.getHeartbeatInterval())
+
+		ctx.LogInfo("Open event stream: Url = %s, lastEventID = %s", request.URL.String(), stream.lastEventID)
+		if err := handler(ctx, stream); err != nil {
+			ctx.LogError("Event stream error: Url = %s, error = %s", ctx.URL, err.Error())
+			stream.sendError(err)
+		}
+
+		ctx.LogInfo("Close event stream: Url = %s", ctx.URL)
+	}
+
+	addRoute(url, http.MethodGet, h, config, nil)
+	return config
+}
+
+/*
+* LastEventID: id of the last event which client received before reconnecting
+* Handler uses it to resend missed events
+ */
+func (stream *SSEStream[T]) LastEventID() string {
+	return stream.lastEventID
+}
+
+/*
+* Send: send event with sequential id, id is continued from Last-Event-ID if it is a number
+* @param event: name of event, blank for default message event
+* @param data: data of event
+* @return Error: error if client is disconnected
+ */
+func (stream *SSEStream[T]) Send(event string, data T) Error {
+	stream.mu.Lock()
+	stream.nextID++
+	id := strconv.FormatInt(stream.nextID, 10)
+	stream.mu.Unlock()
+	return stream.SendWithID(id, event, data)
+}
+
+/*
+* SendWithID: send event with id of handler
+* @param id: id of event, it is sent back in Last-Event-ID when client reconnects
+* @param event: name of event, blank for default message event
+* @param data: data of event
+* @return Error: error if client is disconnected
+ */
+func (stream *SSEStream[T]) SendWithID(id string, event string, data T) Error {
+	payload, err := encodeSSEData(data)
+	if err != nil {
+		stream.ctx.LogError("Marshal event data fail: %v", err)
+		return NewError(ERROR_FROM_LIBRARY, err.Error())
+	}
+
+	var builder strings.Builder
+	if id != BLANK {
+		builder.WriteString("id: " + id + "\n")
+	}
+	if event != BLANK {
+		builder.WriteString("event: " + event + "\n")
+	}
+	for _, line := range strings.Split(payload, "\n") {
+		builder.WriteString("data: " + line + "\n")
+	}
+	builder.WriteString("\n")
+
+	return stream.write(builder.String())
+}
+
+/*
+* SetRetry: ask client to wait before reconnecting
+ */
+func (stream *SSEStream[T]) SetRetry(retry time.Duration) Error {
+	return stream.write(fmt.Sprintf("retry: %d\n\n", retry.Milliseconds()))
+}
+
+func (stream *SSEStream[T]) open() {
+	header := stream.writer.Header()
+	header.Set(CONTENT_TYPE_KEY, EVENT_STREAM_CONTENT_TYPE)
+	header.Set(CACHE_CONTROL_KEY, "no-cache")
+	header.Set("Connection", "keep-alive")
+	// Disable response buffering of nginx
+	header.Set("X-Accel-Buffering", "no")
+	stream.ctx.setResponseHeaders()
+	stream.ctx.isResponseEnd = true
+	stream.ctx.commitSession()
+	stream.writer.WriteHeader(http.StatusOK)
+	stream.flusher.Flush()
+}
+
+func (stream *SSEStream[T]) close() {
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	stream.closed = true
+}
+
+func (stream *SSEStream[T]) write(message string) Error {
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	if stream.closed || stream.ctx.Err() != nil {
+		return ERROR_CLIENT_DISCONNECTED
+	}
+
+	if _, err := fmt.Fprint(stream.writer, message); err != nil {
+		stream.ctx.LogInfo("Write event fail: %v", err)
+		stream.ctx.cancelFunc()
+		return ERROR_CLIENT_DISCONNECTED
+	}
+	stream.flusher.Flush()
+	return nil
+}
+
+/*
+* heartbeat: send comment in interval until done is closed or stream is closed
+* Stream is closed before context is put back to pool, so write does not use context after that
+ */
+func (stream *SSEStream[T]) heartbeat(done <-chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := stream.write(": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (stream *SSEStream[T]) sendError(httpErr HttpError) {
+	body, _ := json.Marshal(responseBody{
+		Code:    httpErr.GetCode(),
+		Message: httpErr.GetMessage() + " (RequestID: " + stream.ctx.requestID + ")",
+		Data:    httpErr.GetErrorData(),
+	})
+	stream.write("event: " + SSE_ERROR_EVENT + "\ndata: " + string(body) + "\n\n")
+}
+
+func encodeSSEData(data any) (string, error) {
+	switch value := data.(type) {
+	case string:
+		return value, nil
+	case []byte:
+		return string(value), nil
+	}
+
+	payload, err := json.Marshal(data)
+	return string(payload), err
+}
@@ -0,0 +1,89 @@
+package core
+
+import (
+	"bufio"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+type testSSEEvent struct {
+	Count int `json:"count"`
+}
+
+func TestRegisterSSE(t *testing.T) {
+	disconnected := make(chan struct{})
+	RegisterSSE("/test/sse/{name}", func(ctx *HttpContext, stream *SSEStream[testSSEEvent]) HttpError {
+		for i := 1; i <= 2; i++ {
+			if err := stream.Send("count", testSSEEvent{Count: i}); err != nil {
+				return nil
+			}
+		}
+
+		<-ctx.Done()
+		close(disconnected)
+		return nil
+	}).SetHeartbeatInterval(10 * time.Millisecond)
+
+	server := httptest.NewServer(http.HandlerFunc(dispatchRequest))
+	defer server.Close()
+
+	request, _ := http.NewRequest(http.MethodGet, server.URL+"/test/sse/core", nil)
+	request.Header.Set(LAST_EVENT_ID_KEY, "5")
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("Request fail: %v", err)
+	}
+
+	if contentType := response.Header.Get(CONTENT_TYPE_KEY); contentType != EVENT_STREAM_CONTENT_TYPE {
+		t.Errorf("Expected content type %s, got %s", EVENT_STREAM_CONTENT_TYPE, contentType)
+	}
+
+	lines := []string{}
+	reader := bufio.NewReader(response.Body)
+	for len(lines) < 8 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Read event fail: %v", err)
+		}
+		lines = append(lines, strings.TrimRight(line, "\n"))
+	}
+
+	expected := []string{"id: 6", "event: count", `data: {"count":1}`, "", "id: 7", "event: count", `data: {"count":2}`, ""}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("Line %d: expected %q, got %q", i, expected[i], lines[i])
+		}
+	}
+
+	if line, _ := reader.ReadString('\n'); line != ": heartbeat\n" {
+		t.Errorf("Expected heartbeat, got %q", line)
+	}
+
+	response.Body.Close()
+	select {
+	case <-disconnected:
+	case <-time.After(time.Second):
+		t.Errorf("Expected context is done when client disconnects")
+	}
+}
+
+func TestRegisterSSE_BodyError(t *testing.T) {
+	isCalled := false
+	RegisterSSE("/test/sse/body-error", func(ctx *HttpContext, stream *SSEStream[testSSEEvent]) HttpError {
+		isCalled = true
+		return nil
+	})
+
+	recorder := httptest.NewRecorder()
+	dispatchRequest(recorder, httptest.NewRequest(http.MethodGet, "/test/sse/body-error", iotest.ErrReader(errors.New("broken body"))))
+
+	// Stream is not opened when request cannot be read
+	if status := int(HTTP_ERROR_READ_BODY_REQUEST_FAIL.GetStatusCode()); recorder.Code != status || isCalled {
+		t.Errorf("Expected status %d without opening stream, got %d", status, recorder.Code)
+	}
+}
@@ -1,9 +1,10 @@
 package core
 
 type staticFolder struct {
-	url    string
-	prefix string
-	path   string
+	url          string
+	prefix       string
+	path         string
+	cacheControl *CacheControl
 }
 
 /*
@@ -11,10 +12,11 @@ type staticFolder struct {
 * @param url string
 * @param prefix string
 * @param path string
+* @param cacheControl: optional Cache-Control policy of files in folder
 * @return void
-* @example RegisterFolder("/static/", "/static/", "./static")
+* @example RegisterFolder("/static/", "/static/", "./static", CacheControlPublic(time.Hour))
  */
-func RegisterFolder(url string, prefix string, path string) {
+func RegisterFolder(url string, prefix string, path string, cacheControl ...CacheControl) {
 	LogInfo("Register folder: url = %s, prefix = %s, path = %s", url, prefix, path)
 	staticFolder := staticFolder{
 		url:    url,
@@ -22,5 +24,9 @@ func RegisterFolder(url string, prefix string, path string) {
 		path:   path,
 	}
 
+	if len(cacheControl) > 0 {
+		staticFolder.cacheControl = &cacheControl[0]
+	}
+
 	staticFolderMap[url] = staticFolder
 }
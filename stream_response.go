@@ -0,0 +1,475 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Format of items in stream response
+type StreamFormat int
+
+const (
+	STREAM_FORMAT_JSON_ARRAY StreamFormat = iota
+	STREAM_FORMAT_NDJSON
+	STREAM_FORMAT_CSV
+)
+
+const (
+	NDJSON_CONTENT_TYPE = "application/x-ndjson"
+	CSV_CONTENT_TYPE    = "text/csv"
+)
+
+// Buffered items are flushed to client at least once in this interval
+const DEFAULT_STREAM_FLUSH_INTERVAL = time.Second
+
+// Size of buffer which items are written into before they are flushed
+const STREAM_BUFFER_SIZE = 32 << 10
+
+/*
+* StreamResponse: response which is written item by item while its source is iterated
+* Items are never loaded into memory together, so it is used to export big tables
+* Stream is stopped when client disconnects, server is shut down or timeout of api is reached,
+* use SetTimeout of api for long exports
+* Ex: return NewRowsStreamResponse(STREAM_FORMAT_CSV, rows).SetFileName("users.csv"), nil
+ */
+type StreamResponse struct {
+	format        StreamFormat
+	statusCode    int
+	message       string
+	fileName      string
+	flushInterval time.Duration
+	columns       []string
+	// produce sends items of source into items channel until source is finished or stop is closed
+	produce func(items chan<- any, stop <-chan struct{}) error
+}
+
+/*
+* NewChannelStreamResponse: stream items which are received from channel until it is closed
+* Producer of channel should stop when ctx.Done() is closed, because stream stops reading channel
+* @param format: format of response
+* @param channel: source of items
+* @return *StreamResponse
+ */
+func NewChannelStreamResponse[T any](format StreamFormat, channel <-chan T) *StreamResponse {
+	return &StreamResponse{
+		format:        format,
+		statusCode:    http.StatusOK,
+		flushInterval: DEFAULT_STREAM_FLUSH_INTERVAL,
+		columns:       csvHeader(reflect.TypeOf((*T)(nil)).Elem()),
+		produce: func(items chan<- any, stop <-chan struct{}) error {
+			for item := range channel {
+				select {
+				case items <- item:
+				case <-stop:
+					return nil
+				}
+			}
+			return nil
+		},
+	}
+}
+
+/*
+* NewRowsStreamResponse: stream rows of query, each row is an object of column name and value
+* Rows are closed when stream is finished
+* @param format: format of response
+* @param rows: result of query
+* @return *StreamResponse
+ */
+func NewRowsStreamResponse(format StreamFormat, rows *sql.Rows) *StreamResponse {
+	columns, err := rows.Columns()
+	return &StreamResponse{
+		format:        format,
+		statusCode:    http.StatusOK,
+		flushInterval: DEFAULT_STREAM_FLUSH_INTERVAL,
+		columns:       columns,
+		produce: func(items chan<- any, stop <-chan struct{}) error {
+			defer rows.Close()
+			if err != nil {
+				return err
+			}
+
+			for rows.Next() {
+				values := make([]any, len(columns))
+				pointers := make([]any, len(columns))
+				for i := range values {
+					pointers[i] = &values[i]
+				}
+				if err := rows.Scan(pointers...); err != nil {
+					return err
+				}
+
+				select {
+				case items <- streamRow{columns: columns, values: values}:
+				case <-stop:
+					return nil
+				}
+			}
+			return rows.Err()
+		},
+	}
+}
+
+/*
+* SetFileName: response is downloaded as attachment with file name
+ */
+func (resp *StreamResponse) SetFileName(fileName string) *StreamResponse {
+	resp.fileName = fileName
+	return resp
+}
+
+/*
+* SetFlushInterval: change interval which buffered items are flushed to client
+ */
+func (resp *StreamResponse) SetFlushInterval(interval time.Duration) *StreamResponse {
+	if interval > 0 {
+		resp.flushInterval = interval
+	}
+	return resp
+}
+
+/*
+* SetColumns: set header of csv, it is also order of map keys in csv record
+ */
+func (resp *StreamResponse) SetColumns(columns ...string) *StreamResponse {
+	resp.columns = columns
+	return resp
+}
+
+func (resp *StreamResponse) GetStatusCode() int {
+	return resp.statusCode
+}
+
+/*
+* GetBody: body is not materialised, it is written while source is iterated
+ */
+func (resp *StreamResponse) GetBody() any {
+	return nil
+}
+
+func (resp *StreamResponse) GetReponseCode() int {
+	return API_CODE_SUCCESS
+}
+
+/*
+* SetResponseContentType: content type of stream response is decided by its format
+ */
+func (resp *StreamResponse) SetResponseContentType(ContentType) {
+}
+
+func (resp *StreamResponse) GetResponseContentType() ContentType {
+	return streamContentType(resp.format)
+}
+
+func streamContentType(format StreamFormat) ContentType {
+	switch format {
+	case STREAM_FORMAT_NDJSON:
+		return NDJSON_CONTENT_TYPE
+	case STREAM_FORMAT_CSV:
+		return CSV_CONTENT_TYPE
+	default:
+		return JSON_CONTENT_TYPE
+	}
+}
+
+func (resp *StreamResponse) GetMessage() string {
+	return resp.message
+}
+
+func (resp *StreamResponse) SetMessage(message string) {
+	resp.message = message
+}
+
+/*
+* writeStream: write items of stream response to client
+* Stream is aborted when client disconnects, writing fails or context is done
+* JSON array is not closed if stream is aborted, so client cannot take it as a complete response
+ */
+func (ctx *HttpContext) writeStream(resp *StreamResponse) {
+	stopAfterDisconnect := context.AfterFunc(ctx.request.Context(), ctx.cancelFunc)
+	defer stopAfterDisconnect()
+	trackStream(ctx)
+	defer untrackStream(ctx)
+
+	ctx.setResponseHeaders()
+	header := ctx.rw.Header()
+	contentType := string(resp.GetResponseContentType())
+	if resp.format == STREAM_FORMAT_CSV {
+		contentType += "; charset=utf-8"
+	}
+	header.Set(CONTENT_TYPE_KEY, contentType)
+	if resp.fileName != BLANK {
+		header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", resp.fileName))
+	}
+	ctx.isResponseEnd = true
+	ctx.commitSession()
+	ctx.rw.WriteHeader(resp.statusCode)
+
+	writer := bufio.NewWriterSize(ctx.rw, STREAM_BUFFER_SIZE)
+	flush := func() error {
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		if flusher, ok := ctx.rw.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		return nil
+	}
+
+	var csvWriter *csv.Writer
+	switch resp.format {
+	case STREAM_FORMAT_JSON_ARRAY:
+		writer.WriteString("[")
+	case STREAM_FORMAT_CSV:
+		csvWriter = csv.NewWriter(writer)
+		if len(resp.columns) != 0 {
+			csvWriter.Write(resp.columns)
+		}
+	}
+
+	items := make(chan any)
+	stop := make(chan struct{})
+	defer close(stop)
+	var produceErr error
+	go func() {
+		defer close(items)
+		produceErr = resp.produce(items, stop)
+	}()
+
+	ticker := time.NewTicker(resp.flushInterval)
+	defer ticker.Stop()
+	count := 0
+	for {
+		select {
+		case <-ctx.Done():
+			ctx.LogInfo("Stream is aborted: Url = %s, items = %d, error = %v", ctx.URL, count, ctx.Err())
+			return
+		case <-ticker.C:
+			if csvWriter != nil {
+				csvWriter.Flush()
+			}
+			if err := flush(); err != nil {
+				ctx.LogInfo("Flush stream fail: Url = %s, items = %d, error = %v", ctx.URL, count, err)
+				ctx.cancelFunc()
+				return
+			}
+		case item, ok := <-items:
+			if !ok {
+				if produceErr != nil {
+					ctx.LogError("Read stream source fail: Url = %s, items = %d, error = %v", ctx.URL, count, produceErr)
+					flush()
+					return
+				}
+
+				switch resp.format {
+				case STREAM_FORMAT_JSON_ARRAY:
+					writer.WriteString("]")
+				case STREAM_FORMAT_CSV:
+					csvWriter.Flush()
+				}
+				if err := flush(); err != nil {
+					ctx.LogInfo("Flush stream fail: Url = %s, items = %d, error = %v", ctx.URL, count, err)
+					return
+				}
+				ctx.LogInfo("Stream is finished: Url = %s, items = %d", ctx.URL, count)
+				return
+			}
+
+			var err error
+			switch resp.format {
+			case STREAM_FORMAT_CSV:
+				if len(resp.columns) == 0 && count == 0 {
+					// Header of map items is taken from the first item
+					resp.columns = csvHeaderOfValue(item)
+					if len(resp.columns) != 0 {
+						csvWriter.Write(resp.columns)
+					}
+				}
+				err = csvWriter.Write(csvRecord(item, resp.columns))
+			default:
+				var data []byte
+				data, err = json.Marshal(item)
+				if err == nil {
+					if resp.format == STREAM_FORMAT_JSON_ARRAY && count != 0 {
+						writer.WriteString(",")
+					}
+					writer.Write(data)
+					if resp.format == STREAM_FORMAT_NDJSON {
+						_, err = writer.WriteString("\n")
+					}
+				}
+			}
+
+			if err != nil {
+				ctx.LogError("Write stream item fail: Url = %s, items = %d, error = %v", ctx.URL, count, err)
+				ctx.cancelFunc()
+				return
+			}
+			count++
+		}
+	}
+}
+
+/*
+* streamRow: row of query, it is marshalled as an object which keeps order of columns
+ */
+type streamRow struct {
+	columns []string
+	values  []any
+}
+
+func (row streamRow) MarshalJSON() ([]byte, error) {
+	var builder strings.Builder
+	builder.WriteString("{")
+	for i, column := range row.columns {
+		if i != 0 {
+			builder.WriteString(",")
+		}
+
+		key, _ := json.Marshal(column)
+		value := row.values[i]
+		if bytes, ok := value.([]byte); ok {
+			value = string(bytes)
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		builder.Write(key)
+		builder.WriteString(":")
+		builder.Write(data)
+	}
+	builder.WriteString("}")
+	return []byte(builder.String()), nil
+}
+
+/*
+* csvHeader: header of struct type is its field names, which are taken from csv or json tag
+ */
+func csvHeader(t reflect.Type) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	header := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		if name, ok := csvFieldName(t.Field(i)); ok {
+			header = append(header, name)
+		}
+	}
+	return header
+}
+
+/*
+* csvHeaderOfValue: header of map item is its sorted keys
+ */
+func csvHeaderOfValue(item any) []string {
+	value := reflect.ValueOf(item)
+	if value.Kind() != reflect.Map || value.Type().Key().Kind() != reflect.String {
+		return nil
+	}
+
+	header := []string{}
+	for _, key := range value.MapKeys() {
+		header = append(header, key.String())
+	}
+	sort.Strings(header)
+	return header
+}
+
+func csvFieldName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return BLANK, false
+	}
+
+	for _, tagName := range []string{"csv", "json"} {
+		if tag, ok := field.Tag.Lookup(tagName); ok {
+			name, _, _ := strings.Cut(tag, ",")
+			if name == "-" {
+				return BLANK, false
+			}
+			if name != BLANK {
+				return name, true
+			}
+		}
+	}
+	return field.Name, true
+}
+
+/*
+* csvRecord: convert item into csv record
+* Struct is written by its fields, map by columns and row of query by its values
+ */
+func csvRecord(item any, columns []string) []string {
+	switch value := item.(type) {
+	case []string:
+		return value
+	case streamRow:
+		record := make([]string, len(value.values))
+		for i, v := range value.values {
+			record[i] = csvValue(v)
+		}
+		return record
+	}
+
+	value := reflect.ValueOf(item)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return make([]string, len(columns))
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		record := []string{}
+		for i := 0; i < value.NumField(); i++ {
+			if _, ok := csvFieldName(value.Type().Field(i)); ok {
+				record = append(record, csvValue(value.Field(i).Interface()))
+			}
+		}
+		return record
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			return []string{csvValue(item)}
+		}
+
+		record := make([]string, len(columns))
+		for i, column := range columns {
+			if v := value.MapIndex(reflect.ValueOf(column).Convert(value.Type().Key())); v.IsValid() {
+				record[i] = csvValue(v.Interface())
+			}
+		}
+		return record
+	default:
+		return []string{csvValue(item)}
+	}
+}
+
+func csvValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return BLANK
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,103 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type testStreamItem struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name" csv:"full_name"`
+	Password string `json:"-"`
+}
+
+type testStreamRequest struct {
+	Format StreamFormat `query:"format"`
+}
+
+func TestStreamResponse_Formats(t *testing.T) {
+	RegisterAPI("/test/stream/items", http.MethodGet, func(ctx *HttpContext, request testStreamRequest) (HttpResponse, HttpError) {
+		items := make(chan testStreamItem)
+		go func() {
+			defer close(items)
+			for i := 1; i <= 2; i++ {
+				select {
+				case items <- testStreamItem{ID: i, Name: "user, " + string(rune('a'+i-1)), Password: "secret"}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		return NewChannelStreamResponse(request.Format, items).SetFileName("items"), nil
+	})
+
+	testCases := []struct {
+		format      StreamFormat
+		contentType string
+		body        string
+	}{
+		{STREAM_FORMAT_JSON_ARRAY, JSON_CONTENT_TYPE, `[{"id":1,"name":"user, a"},{"id":2,"name":"user, b"}]`},
+		{STREAM_FORMAT_NDJSON, NDJSON_CONTENT_TYPE, "{\"id\":1,\"name\":\"user, a\"}\n{\"id\":2,\"name\":\"user, b\"}\n"},
+		{STREAM_FORMAT_CSV, CSV_CONTENT_TYPE + "; charset=utf-8", "id,full_name\n1,\"user, a\"\n2,\"user, b\"\n"},
+	}
+
+	for _, testCase := range testCases {
+		request := httptest.NewRequest(http.MethodGet, "/test/stream/items?format="+string(rune('0'+testCase.format)), nil)
+		recorder := httptest.NewRecorder()
+		dispatchRequest(recorder, request)
+
+		if contentType := recorder.Header().Get(CONTENT_TYPE_KEY); contentType != testCase.contentType {
+			t.Errorf("Format %d: expected content type %s, got %s", testCase.format, testCase.contentType, contentType)
+		}
+		if disposition := recorder.Header().Get("Content-Disposition"); disposition != `attachment; filename="items"` {
+			t.Errorf("Format %d: expected attachment, got %s", testCase.format, disposition)
+		}
+		if body := recorder.Body.String(); body != testCase.body {
+			t.Errorf("Format %d: expected body %q, got %q", testCase.format, testCase.body, body)
+		}
+	}
+}
+
+func TestStreamResponse_AbortWhenClientDisconnects(t *testing.T) {
+	stopped := make(chan struct{})
+	RegisterAPI("/test/stream/endless", http.MethodGet, func(ctx *HttpContext, request testDispatchRequest) (HttpResponse, HttpError) {
+		items := make(chan map[string]int)
+		go func() {
+			defer close(stopped)
+			defer close(items)
+			for i := 0; ; i++ {
+				select {
+				case items <- map[string]int{"count": i}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		return NewChannelStreamResponse(STREAM_FORMAT_NDJSON, items), nil
+	})
+
+	requestContext, cancel := context.WithCancel(context.Background())
+	request := httptest.NewRequest(http.MethodGet, "/test/stream/endless", nil).WithContext(requestContext)
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	finished := make(chan struct{})
+	go func() {
+		dispatchRequest(httptest.NewRecorder(), request)
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatalf("Expected stream is aborted when client disconnects")
+	}
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Errorf("Expected producer is stopped by context")
+	}
+}
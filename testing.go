@@ -1,11 +1,7 @@
 package core
 
 import (
-	"fmt"
-	"net/http"
 	"net/url"
-
-	"github.com/go-playground/validator"
 )
 
 type TestApiInfo[T any] struct {
@@ -32,14 +28,8 @@ func TestAPI[T any](apiInfo TestApiInfo[T]) (HttpResponse, HttpError) {
 	var req = apiInfo.Body.(T)
 
 	// Validate go struct with tag
-	errValidate := validate.StructCtx(ctx, req)
-	if errValidate != nil {
-		errMessage := "Request invalid: "
-		for _, err := range errValidate.(validator.ValidationErrors) {
-			errMessage = fmt.Sprintf("%s {Field: %s, Tag: %s, Value: %s}", errMessage, err.Field(), err.Tag(), err.Value())
-		}
-
-		return nil, NewHttpError(http.StatusBadRequest, ERROR_BAD_BODY_REQUEST, errMessage, nil)
+	if err := validateRequest(ctx, req); err != nil {
+		return nil, err
 	}
 
 	// Call handler
@@ -56,16 +46,38 @@ func TestAPIWithContext[T any](ctx *HttpContext, apiInfo TestApiInfo[T]) (HttpRe
 	var req = apiInfo.Body.(T)
 
 	// Validate go struct with tag
-	errValidate := validate.StructCtx(ctx, req)
-	if errValidate != nil {
-		errMessage := "Request invalid: "
-		for _, err := range errValidate.(validator.ValidationErrors) {
-			errMessage = fmt.Sprintf("%s {Field: %s, Tag: %s, Value: %s}", errMessage, err.Field(), err.Tag(), err.Value())
-		}
-
-		return nil, NewHttpError(http.StatusBadRequest, ERROR_BAD_BODY_REQUEST, errMessage, nil)
+	if err := validateRequest(ctx, req); err != nil {
+		return nil, err
 	}
 
 	// Call handler
 	return apiInfo.Handler(ctx, req)
 }
+
+type TestTypedApiInfo[Req any, Res any] struct {
+	URL     string
+	Method  string
+	Queries map[string]string
+	Body    Req
+	Handler TypedHandler[Req, Res]
+}
+
+/*
+* TestTypedAPI: call typed handler with request which is validated like RegisterTypedAPI
+* @return Res, HttpError: data of response and error of handler
+ */
+func TestTypedAPI[Req any, Res any](apiInfo TestTypedApiInfo[Req, Res]) (Res, HttpError) {
+	ctx := getHttpContext()
+	defer putHttpContext(ctx)
+
+	ctx.URL, _ = url.Parse(apiInfo.URL)
+	ctx.Method = apiInfo.Method
+	ctx.urlParams = apiInfo.Queries
+
+	if err := validateRequest(ctx, apiInfo.Body); err != nil {
+		var zero Res
+		return zero, err
+	}
+
+	return apiInfo.Handler(ctx, apiInfo.Body)
+}
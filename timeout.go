@@ -0,0 +1,210 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Headers which are lists, value of handler is appended to value which is set on real writer
+var listHeaderKeys = map[string]bool{
+	"Vary":                          true,
+	"Link":                          true,
+	"Access-Control-Expose-Headers": true,
+	"Set-Cookie":                    true,
+}
+
+/*
+* timeoutWriter: response writer which is shared between dispatcher and handler goroutine
+* After timeout, every write from handler is discarded
+ */
+type timeoutWriter struct {
+	mu          sync.Mutex
+	writer      http.ResponseWriter
+	header      http.Header
+	keptHeader  http.Header // headers which are also written with timeout error
+	wroteHeader bool
+	timedOut    bool
+}
+
+func newTimeoutWriter(writer http.ResponseWriter) *timeoutWriter {
+	return &timeoutWriter{
+		writer:     writer,
+		header:     make(http.Header),
+		keptHeader: make(http.Header),
+	}
+}
+
+/*
+* Header: handler writes header into its own map, it is merged into real writer when header is written
+ */
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(statusCode int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.writeHeader(statusCode)
+}
+
+func (tw *timeoutWriter) writeHeader(statusCode int) {
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+
+	tw.wroteHeader = true
+	mergeHeader(tw.writer.Header(), tw.header)
+	tw.writer.WriteHeader(statusCode)
+}
+
+/*
+* mergeHeader: copy header of handler to header of real writer
+* List headers (Vary, Link, ...) keep values of outer writers, other headers are overwritten
+ */
+func mergeHeader(dst http.Header, src http.Header) {
+	for key, values := range src {
+		if !listHeaderKeys[key] {
+			dst[key] = values
+			continue
+		}
+
+		for _, value := range values {
+			if !slices.Contains(dst[key], value) {
+				dst[key] = append(dst[key], value)
+			}
+		}
+	}
+}
+
+func (tw *timeoutWriter) Write(data []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+
+	tw.writeHeader(http.StatusOK)
+	return tw.writer.Write(data)
+}
+
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return
+	}
+
+	if flusher, ok := tw.writer.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+/*
+* timeout: stop handler from writing and write timeout error with requestID
+* Nothing is written if handler has already written header
+ */
+func (tw *timeoutWriter) timeout(requestID string) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return
+	}
+
+	tw.timedOut = true
+	if tw.wroteHeader {
+		return
+	}
+
+	// Header of handler is not read, because handler goroutine can still write it
+	mergeHeader(tw.writer.Header(), tw.keptHeader)
+	// Handler goroutine is still using its context, so error is written by a separated context
+	ctx := &HttpContext{
+		rw:             tw.writer,
+		requestID:      requestID,
+		responseHeader: make(map[string][]string),
+	}
+	ctx.writeError(HTTP_ERROR_TIMEOUT)
+}
+
+/*
+* keepHeadersOnTimeout: copy headers of keys which are also written with timeout error, like cors headers
+* It is called by handler goroutine after headers are set
+ */
+func (ctx *HttpContext) keepHeadersOnTimeout(keys ...string) {
+	tw, ok := ctx.rw.(*timeoutWriter)
+	if !ok {
+		return
+	}
+
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	for _, key := range keys {
+		if values := tw.header.Values(key); len(values) != 0 {
+			tw.keptHeader[key] = slices.Clone(values)
+		}
+	}
+}
+
+/*
+* serveWithTimeout: run serve function in a goroutine and answer 504 when timeout is reached
+* Context is put back to pool only after serve function returns
+* @param writer: response writer of request
+* @param timeout: timeout of request, contextTimeout is used if it is zero
+* @param serve: function which handles request with context and response writer
+ */
+func serveWithTimeout(writer http.ResponseWriter, timeout time.Duration, serve func(ctx *HttpContext, writer http.ResponseWriter)) {
+	if timeout <= 0 {
+		timeout = contextTimeout
+	}
+
+	ctx := getHttpContextWithTimeout(timeout)
+	tw := newTimeoutWriter(writer)
+	done := make(chan struct{})
+	var aborted any
+
+	go func() {
+		defer close(done)
+		defer func() {
+			// Panic in this goroutine would crash server, abort panic is passed to goroutine of net/http
+			if p := recover(); p != nil {
+				if isAbortPanic(p) {
+					ctx.LogInfo("Handler is aborted: Url = %v", ctx.URL)
+					aborted = p
+					return
+				}
+				handleHttpPanic(ctx, p)
+			}
+		}()
+		serve(ctx, tw)
+	}()
+
+	select {
+	case <-done:
+		putHttpContext(ctx)
+		if aborted != nil {
+			panic(aborted)
+		}
+	case <-ctx.Done():
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			// Core context is cancelled by shutdown, let handler finish its response
+			<-done
+			putHttpContext(ctx)
+			return
+		}
+
+		LogInfo("Request timeout: RequestId = %s, timeout = %v", ctx.requestID, timeout)
+		tw.timeout(ctx.requestID)
+
+		// Keep shutdown waiting for the handler which is still running
+		activeHandlers.Add(1)
+		go func() {
+			defer activeHandlers.Done()
+			<-done
+			putHttpContext(ctx)
+		}()
+	}
+}
@@ -0,0 +1,95 @@
+package core
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"none":               tls.NoClientCert,
+	"request":            tls.RequestClientCert,
+	"require":            tls.RequireAnyClientCert,
+	"verify_if_given":    tls.VerifyClientCertIfGiven,
+	"require_and_verify": tls.RequireAndVerifyClientCert,
+}
+
+/*
+* buildTLSConfig: create tls config of secure server from config
+* Minimum version is TLS 1.2 if it is not set
+* Client certificate is required and verified by client CA if client_ca_file is set and client_auth is not set
+* @return *tls.Config, error: error if a value in config is invalid
+ */
+func buildTLSConfig(config SecureServerConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if config.MinTLSVersion != BLANK {
+		version, ok := tlsVersions[strings.TrimPrefix(strings.ToLower(config.MinTLSVersion), "tls")]
+		if !ok {
+			return nil, fmt.Errorf("invalid min_tls_version: %s", config.MinTLSVersion)
+		}
+		tlsConfig.MinVersion = version
+	}
+
+	if len(config.CipherSuites) != 0 {
+		suites := map[string]uint16{}
+		for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+			suites[suite.Name] = suite.ID
+		}
+
+		for _, name := range config.CipherSuites {
+			id, ok := suites[strings.ToUpper(strings.TrimSpace(name))]
+			if !ok {
+				return nil, fmt.Errorf("invalid cipher suite: %s", name)
+			}
+			tlsConfig.CipherSuites = append(tlsConfig.CipherSuites, id)
+		}
+	}
+
+	if config.ClientCAFile != BLANK {
+		data, err := os.ReadFile(config.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read client_ca_file fail: %v", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificate in client_ca_file: %s", config.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	if config.ClientAuth != BLANK {
+		clientAuth, ok := clientAuthTypes[strings.ToLower(config.ClientAuth)]
+		if !ok {
+			return nil, fmt.Errorf("invalid client_auth: %s", config.ClientAuth)
+		}
+		if clientAuth >= tls.VerifyClientCertIfGiven && tlsConfig.ClientCAs == nil {
+			return nil, fmt.Errorf("client_auth %s needs client_ca_file", config.ClientAuth)
+		}
+		tlsConfig.ClientAuth = clientAuth
+	}
+
+	return tlsConfig, nil
+}
+
+/*
+* GetClientCertificate: certificate of client which is verified by secure server with mTLS
+* @return: *x509.Certificate, nil if client sends no certificate
+ */
+func (ctx *HttpContext) GetClientCertificate() *x509.Certificate {
+	if ctx.request == nil || ctx.request.TLS == nil || len(ctx.request.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+	return ctx.request.TLS.PeerCertificates[0]
+}
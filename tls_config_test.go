@@ -0,0 +1,91 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+/*
+* writeTestCertificate: write self-signed certificate and key of host into dir
+ */
+func writeTestCertificate(t *testing.T, dir string, host string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: host},
+		DNSNames:              []string{host},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := filepath.Join(dir, host+".crt")
+	keyFile := filepath.Join(dir, host+".key")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return certFile, keyFile
+}
+
+func TestBuildTLSConfig(t *testing.T) {
+	tlsConfig, err := buildTLSConfig(SecureServerConfig{})
+	if err != nil || tlsConfig.MinVersion != tls.VersionTLS12 || tlsConfig.ClientAuth != tls.NoClientCert {
+		t.Errorf("Expected TLS 1.2 without client auth by default, got %+v, error = %v", tlsConfig, err)
+	}
+
+	tlsConfig, err = buildTLSConfig(SecureServerConfig{
+		MinTLSVersion: "TLS1.3",
+		CipherSuites:  []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"},
+	})
+	if err != nil || tlsConfig.MinVersion != tls.VersionTLS13 || len(tlsConfig.CipherSuites) != 1 || tlsConfig.CipherSuites[0] != tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 {
+		t.Errorf("Expected TLS 1.3 and one cipher suite, got %+v, error = %v", tlsConfig, err)
+	}
+
+	for _, config := range []SecureServerConfig{
+		{MinTLSVersion: "1.4"},
+		{CipherSuites: []string{"UNKNOWN"}},
+		{ClientAuth: "require_and_verify"},
+	} {
+		if _, err := buildTLSConfig(config); err == nil {
+			t.Errorf("Expected error of config %+v", config)
+		}
+	}
+}
+
+func TestBuildTLSConfig_ClientCA(t *testing.T) {
+	certFile, _ := writeTestCertificate(t, t.TempDir(), "client-ca")
+
+	tlsConfig, err := buildTLSConfig(SecureServerConfig{ClientCAFile: certFile})
+	if err != nil || tlsConfig.ClientCAs == nil || tlsConfig.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Errorf("Expected client certificate is required and verified, got %+v, error = %v", tlsConfig, err)
+	}
+
+	tlsConfig, err = buildTLSConfig(SecureServerConfig{ClientCAFile: certFile, ClientAuth: "verify_if_given"})
+	if err != nil || tlsConfig.ClientAuth != tls.VerifyClientCertIfGiven {
+		t.Errorf("Expected client certificate is verified if given, got %+v, error = %v", tlsConfig, err)
+	}
+}
@@ -0,0 +1,49 @@
+package core
+
+import (
+	"reflect"
+)
+
+/*
+* TypedHandler: handler which returns data of response instead of HttpResponse
+* Data is wrapped in response body like NewDefaultHttpResponse
+ */
+type TypedHandler[Req any, Res any] func(ctx *HttpContext, request Req) (Res, HttpError)
+
+/*
+* RegisterTypedAPI: register api which response data has type Res
+* Response model of api document is taken from Res, so it is not declared again by SetResponseModel
+* @param url: url of api
+* @param method: method of api
+* @param handler: handler of api
+* @param middlewares: middlewares of api
+* @return *RouteConfig: optional settings of api
+ */
+func RegisterTypedAPI[Req any, Res any](url string, method string, handler TypedHandler[Req, Res], middlewares ...ApiMiddleware) *RouteConfig {
+	config := RegisterAPI(url, method, handler.toHandler(), middlewares...)
+	config.responseType = reflect.TypeOf((*Res)(nil)).Elem()
+	return config
+}
+
+/*
+* RegisterGroupTypedAPI: register typed api in group
+* @param group: route group
+* @param url: url of api, it is appended to prefix of group
+* @return *RouteConfig: optional settings of api
+ */
+func RegisterGroupTypedAPI[Req any, Res any](group *RouteGroup, url string, method string, handler TypedHandler[Req, Res], middlewares ...ApiMiddleware) *RouteConfig {
+	return RegisterTypedAPI(group.url(url), method, handler, group.routeMiddlewares(middlewares)...)
+}
+
+/*
+* toHandler: wrap typed handler into handler of RegisterAPI
+ */
+func (handler TypedHandler[Req, Res]) toHandler() Handler[Req] {
+	return func(ctx *HttpContext, request Req) (HttpResponse, HttpError) {
+		data, err := handler(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return NewDefaultHttpResponse(data), nil
+	}
+}
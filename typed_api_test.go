@@ -0,0 +1,77 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testTypedRequest struct {
+	Id   int64  `path:"id"`
+	Name string `json:"name" validate:"required"`
+}
+
+type testTypedResponse struct {
+	Id   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+func testTypedHandler(ctx *HttpContext, request testTypedRequest) (testTypedResponse, HttpError) {
+	if request.Id == 0 {
+		return testTypedResponse{}, NewDefaultHttpError(404, "User not found")
+	}
+	return testTypedResponse{Id: request.Id, Name: request.Name}, nil
+}
+
+func TestRegisterTypedAPI(t *testing.T) {
+	RegisterTypedAPI("/test/typed/users/{id:int}", http.MethodPut, testTypedHandler)
+
+	request := httptest.NewRequest(http.MethodPut, "/test/typed/users/7", strings.NewReader(`{"name":"core"}`))
+	request.Header.Set(CONTENT_TYPE_KEY, JSON_CONTENT_TYPE)
+	recorder := httptest.NewRecorder()
+	dispatchRequest(recorder, request)
+
+	var response struct {
+		Code int               `json:"code"`
+		Data testTypedResponse `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Unmarshal response fail: %v, body = %s", err, recorder.Body.String())
+	}
+	if response.Code != API_CODE_SUCCESS || response.Data != (testTypedResponse{Id: 7, Name: "core"}) {
+		t.Errorf("Expected user 7 core, got %+v", response)
+	}
+
+	data, _ := GenerateOpenApiDocument()
+	var document struct {
+		Components struct {
+			Schemas map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	json.Unmarshal(data, &document)
+	if _, ok := document.Components.Schemas["testTypedResponse"]; !ok {
+		t.Errorf("Expected response model in document, got %v", document.Components.Schemas)
+	}
+}
+
+func TestTypedAPI_ValidateAndCallHandler(t *testing.T) {
+	response, err := TestTypedAPI(TestTypedApiInfo[testTypedRequest, testTypedResponse]{
+		URL:     "/test/typed/users/1",
+		Method:  http.MethodPut,
+		Body:    testTypedRequest{Id: 1, Name: "core"},
+		Handler: testTypedHandler,
+	})
+	if err != nil || response.Name != "core" {
+		t.Errorf("Expected name core, got %+v, error = %v", response, err)
+	}
+
+	_, err = TestTypedAPI(TestTypedApiInfo[testTypedRequest, testTypedResponse]{
+		Body:    testTypedRequest{Id: 1},
+		Handler: testTypedHandler,
+	})
+	if err == nil || err.GetStatusCode() != http.StatusBadRequest {
+		t.Errorf("Expected validation error, got %v", err)
+	}
+}
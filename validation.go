@@ -0,0 +1,255 @@
+package core
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator"
+)
+
+// Language of validation message
+const (
+	VALIDATION_LANGUAGE_EN = "en"
+	VALIDATION_LANGUAGE_VI = "vi"
+)
+
+// Message which is used when there is no message for tag
+const VALIDATION_DEFAULT_MESSAGE_KEY = "default"
+
+/*
+* ValidationErrorDetail: detail of a field which is invalid
+* It is returned in data of bad request response
+ */
+type ValidationErrorDetail struct {
+	Field    string `json:"field"`
+	JsonPath string `json:"jsonPath"`
+	Tag      string `json:"tag"`
+	Param    string `json:"param"`
+	Message  string `json:"message"`
+}
+
+var defaultValidationLanguage = VALIDATION_LANGUAGE_EN
+
+/*
+* validationMessages: language -> tag -> message
+* Message can contain {field} and {param} placeholders
+* Tag with suffix _string and _items are used for string and slice/map field
+ */
+var validationMessages = map[string]map[string]string{
+	VALIDATION_LANGUAGE_EN: {
+		VALIDATION_DEFAULT_MESSAGE_KEY: "{field} is invalid",
+		"required":                     "{field} is required",
+		"email":                        "{field} must be a valid email address",
+		"url":                          "{field} must be a valid url",
+		"uuid":                         "{field} must be a valid UUID",
+		"numeric":                      "{field} must be a numeric value",
+		"alpha":                        "{field} can only contain alphabetic characters",
+		"alphanum":                     "{field} can only contain alphanumeric characters",
+		"len":                          "{field} must be {param}",
+		"len_string":                   "{field} must be {param} characters in length",
+		"len_items":                    "{field} must contain {param} items",
+		"min":                          "{field} must be {param} or greater",
+		"min_string":                   "{field} must be at least {param} characters in length",
+		"min_items":                    "{field} must contain at least {param} items",
+		"max":                          "{field} must be {param} or less",
+		"max_string":                   "{field} must be a maximum of {param} characters in length",
+		"max_items":                    "{field} must contain at maximum {param} items",
+		"gt":                           "{field} must be greater than {param}",
+		"gte":                          "{field} must be {param} or greater",
+		"lt":                           "{field} must be less than {param}",
+		"lte":                          "{field} must be {param} or less",
+		"oneof":                        "{field} must be one of [{param}]",
+		"eqfield":                      "{field} must be equal to {param}",
+		"nefield":                      "{field} cannot be equal to {param}",
+		"gtfield":                      "{field} must be greater than {param}",
+		"ltfield":                      "{field} must be less than {param}",
+	},
+	VALIDATION_LANGUAGE_VI: {
+		VALIDATION_DEFAULT_MESSAGE_KEY: "{field} không hợp lệ",
+		"required":                     "{field} là bắt buộc",
+		"email":                        "{field} phải là địa chỉ email hợp lệ",
+		"url":                          "{field} phải là url hợp lệ",
+		"uuid":                         "{field} phải là UUID hợp lệ",
+		"numeric":                      "{field} phải là giá trị số",
+		"alpha":                        "{field} chỉ được chứa chữ cái",
+		"alphanum":                     "{field} chỉ được chứa chữ cái và chữ số",
+		"len":                          "{field} phải bằng {param}",
+		"len_string":                   "{field} phải có độ dài {param} ký tự",
+		"len_items":                    "{field} phải chứa {param} phần tử",
+		"min":                          "{field} phải lớn hơn hoặc bằng {param}",
+		"min_string":                   "{field} phải có ít nhất {param} ký tự",
+		"min_items":                    "{field} phải chứa ít nhất {param} phần tử",
+		"max":                          "{field} phải nhỏ hơn hoặc bằng {param}",
+		"max_string":                   "{field} chỉ được có tối đa {param} ký tự",
+		"max_items":                    "{field} chỉ được chứa tối đa {param} phần tử",
+		"gt":                           "{field} phải lớn hơn {param}",
+		"gte":                          "{field} phải lớn hơn hoặc bằng {param}",
+		"lt":                           "{field} phải nhỏ hơn {param}",
+		"lte":                          "{field} phải nhỏ hơn hoặc bằng {param}",
+		"oneof":                        "{field} phải là một trong [{param}]",
+		"eqfield":                      "{field} phải bằng {param}",
+		"nefield":                      "{field} không được bằng {param}",
+		"gtfield":                      "{field} phải lớn hơn {param}",
+		"ltfield":                      "{field} phải nhỏ hơn {param}",
+	},
+}
+
+/*
+* newValidator: create validator which reports json name of field
+* Field without json tag is reported by its binding tag (query, path, header, form) or go name
+ */
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range append([]string{"json"}, bindingTags...) {
+			name := strings.Split(field.Tag.Get(tag), ",")[0]
+			if name == "-" {
+				return BLANK
+			}
+
+			if name != BLANK {
+				return name
+			}
+		}
+		return BLANK
+	})
+	return v
+}
+
+/*
+* RegisterValidation: register custom validation tag
+* @param tag: tag name which is used in validate tag. Ex: validate:"phone"
+* @param fn: validation function
+* @return Error
+ */
+func RegisterValidation(tag string, fn validator.Func) Error {
+	if err := validate.RegisterValidation(tag, fn); err != nil {
+		LogError("Register validation fail: tag = %s, error = %v", tag, err)
+		return NewError(ERROR_FROM_LIBRARY, err.Error())
+	}
+	return nil
+}
+
+/*
+* RegisterStructValidation: register cross field rule for struct types
+* Use StructLevel.ReportError(field, jsonName, goName, tag, param) to report invalid field
+* @param fn: struct level validation function
+* @param types: zero values of struct types. Ex: CreateUserRequest{}
+ */
+func RegisterStructValidation(fn validator.StructLevelFunc, types ...any) {
+	validate.RegisterStructValidation(fn, types...)
+}
+
+/*
+* RegisterValidationAlias: register alias for a group of tags. Ex: RegisterValidationAlias("password", "min=8,max=64")
+ */
+func RegisterValidationAlias(alias string, tags string) {
+	validate.RegisterAlias(alias, tags)
+}
+
+/*
+* RegisterValidationMessage: register or overwrite message of tag in language
+* @param language: language code. Ex: en, vi
+* @param tag: validation tag, tag_string and tag_items are used for string and slice/map field
+* @param message: message with {field} and {param} placeholders
+ */
+func RegisterValidationMessage(language string, tag string, message string) {
+	if _, ok := validationMessages[language]; !ok {
+		validationMessages[language] = make(map[string]string)
+	}
+	validationMessages[language][tag] = message
+}
+
+/*
+* SetDefaultValidationLanguage: language which is used when Accept-Language header has no supported language
+ */
+func SetDefaultValidationLanguage(language string) {
+	defaultValidationLanguage = language
+}
+
+/*
+* validateRequest: validate request struct with validate tag
+* @return HttpError: bad request with list of ValidationErrorDetail in data
+ */
+func validateRequest(ctx *HttpContext, request any) HttpError {
+	errValidate := validate.StructCtx(ctx, request)
+	if errValidate == nil {
+		return nil
+	}
+
+	fieldErrors, ok := errValidate.(validator.ValidationErrors)
+	if !ok {
+		ctx.LogError("Validate request fail: %v", errValidate)
+		return NewHttpError(http.StatusBadRequest, ERROR_BAD_BODY_REQUEST, "Request invalid", nil)
+	}
+
+	language := ctx.validationLanguage()
+	details := make([]ValidationErrorDetail, 0, len(fieldErrors))
+	for _, fieldError := range fieldErrors {
+		details = append(details, newValidationErrorDetail(fieldError, language))
+	}
+
+	return NewHttpError(http.StatusBadRequest, ERROR_BAD_BODY_REQUEST, "Request invalid", details)
+}
+
+func newValidationErrorDetail(fieldError validator.FieldError, language string) ValidationErrorDetail {
+	// Namespace is started with name of root struct
+	jsonPath := fieldError.Namespace()
+	if index := strings.Index(jsonPath, "."); index >= 0 {
+		jsonPath = jsonPath[index+1:]
+	}
+
+	return ValidationErrorDetail{
+		Field:    fieldError.Field(),
+		JsonPath: jsonPath,
+		Tag:      fieldError.Tag(),
+		Param:    fieldError.Param(),
+		Message:  validationMessage(fieldError, language),
+	}
+}
+
+func validationMessage(fieldError validator.FieldError, language string) string {
+	messages, ok := validationMessages[language]
+	if !ok {
+		messages = validationMessages[defaultValidationLanguage]
+	}
+
+	keys := []string{}
+	switch fieldError.Kind() {
+	case reflect.String:
+		keys = append(keys, fieldError.Tag()+"_string")
+	case reflect.Slice, reflect.Array, reflect.Map:
+		keys = append(keys, fieldError.Tag()+"_items")
+	}
+	keys = append(keys, fieldError.Tag(), VALIDATION_DEFAULT_MESSAGE_KEY)
+
+	message := "{field} is invalid"
+	for _, key := range keys {
+		if value, ok := messages[key]; ok {
+			message = value
+			break
+		}
+	}
+
+	return strings.NewReplacer("{field}", fieldError.Field(), "{param}", fieldError.Param()).Replace(message)
+}
+
+/*
+* validationLanguage: first language in Accept-Language header which has messages
+ */
+func (ctx *HttpContext) validationLanguage() string {
+	if ctx.request == nil {
+		return defaultValidationLanguage
+	}
+
+	for _, item := range strings.Split(ctx.request.Header.Get(ACCEPT_LANGUAGE_KEY), ",") {
+		language, _, _ := strings.Cut(strings.TrimSpace(item), ";")
+		language, _, _ = strings.Cut(strings.ToLower(language), "-")
+		if _, ok := validationMessages[language]; ok {
+			return language
+		}
+	}
+
+	return defaultValidationLanguage
+}
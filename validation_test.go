@@ -0,0 +1,94 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-playground/validator"
+)
+
+type testValidationAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type testValidationRequest struct {
+	Email    string                `json:"email" validate:"required,email"`
+	Name     string                `json:"name" validate:"min=3"`
+	Code     string                `json:"code" validate:"testcode"`
+	Address  testValidationAddress `json:"address"`
+	Password string                `json:"password"`
+	Confirm  string                `json:"confirmPassword"`
+}
+
+func TestValidateRequest_FieldDetails(t *testing.T) {
+	RegisterValidation("testcode", func(fl validator.FieldLevel) bool {
+		return fl.Field().String() == "core"
+	})
+	RegisterStructValidation(func(sl validator.StructLevel) {
+		request := sl.Current().Interface().(testValidationRequest)
+		if request.Password != request.Confirm {
+			sl.ReportError(request.Confirm, "confirmPassword", "Confirm", "eqfield", "password")
+		}
+	}, testValidationRequest{})
+
+	ctx := GetHttpContextForTest()
+	defer putHttpContext(ctx)
+	ctx.request = httptest.NewRequest(http.MethodPost, "/users", nil)
+	ctx.request.Header.Set(ACCEPT_LANGUAGE_KEY, "vi-VN,vi;q=0.9,en;q=0.8")
+
+	err := validateRequest(ctx, testValidationRequest{Name: "ab", Code: "x", Password: "a", Confirm: "b"})
+	if err == nil {
+		t.Errorf("Expected error, got nil")
+		return
+	}
+
+	details, ok := err.GetErrorData().([]ValidationErrorDetail)
+	if !ok {
+		t.Errorf("Expected []ValidationErrorDetail, got %T", err.GetErrorData())
+		return
+	}
+
+	expected := map[string]ValidationErrorDetail{
+		"email":           {Field: "email", Tag: "required", Message: "email là bắt buộc"},
+		"name":            {Field: "name", Tag: "min", Param: "3", Message: "name phải có ít nhất 3 ký tự"},
+		"code":            {Field: "code", Tag: "testcode", Message: "code không hợp lệ"},
+		"address.city":    {Field: "city", Tag: "required", Message: "city là bắt buộc"},
+		"confirmPassword": {Field: "confirmPassword", Tag: "eqfield", Param: "password", Message: "confirmPassword phải bằng password"},
+	}
+
+	if len(details) != len(expected) {
+		t.Errorf("Expected %d details, got %d: %+v", len(expected), len(details), details)
+	}
+
+	for _, detail := range details {
+		want, ok := expected[detail.JsonPath]
+		if !ok {
+			t.Errorf("Unexpected detail: %+v", detail)
+			continue
+		}
+
+		want.JsonPath = detail.JsonPath
+		if detail != want {
+			t.Errorf("Expected %+v, got %+v", want, detail)
+		}
+	}
+}
+
+func TestValidateRequest_DefaultLanguage(t *testing.T) {
+	ctx := GetHttpContextForTest()
+	defer putHttpContext(ctx)
+	ctx.request = httptest.NewRequest(http.MethodPost, "/users", nil)
+	ctx.request.Header.Set(ACCEPT_LANGUAGE_KEY, "fr-FR")
+
+	err := validateRequest(ctx, testValidationAddress{})
+	if err == nil || err.GetStatusCode() != http.StatusBadRequest {
+		t.Errorf("Expected bad request, got %v", err)
+		return
+	}
+
+	details := err.GetErrorData().([]ValidationErrorDetail)
+	if details[0].Message != "city is required" {
+		t.Errorf("Expected message: city is required, got %s", details[0].Message)
+	}
+}
@@ -0,0 +1,298 @@
+package core
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Header keys of api versioning
+const (
+	ACCEPT_VERSION_KEY = "Accept-Version"
+	API_VERSION_KEY    = "Api-Version"
+	DEPRECATION_KEY    = "Deprecation"
+	SUNSET_KEY         = "Sunset"
+	LINK_KEY           = "Link"
+)
+
+/*
+* ApiVersion: version of apis, it is the url prefix of its apis and value of Accept-Version header
+* Middlewares of version are executed after common middlewares and before middlewares of api
+* Ex: v1 := Version("v1").Deprecate(time.Time{}, sunset)
+ */
+type ApiVersion struct {
+	name            string
+	middlewares     []ApiMiddleware
+	deprecated      bool
+	deprecatedAt    time.Time
+	sunset          time.Time
+	deprecationLink string
+}
+
+/*
+* ApiVersionInfo: versions which a route exposes
+ */
+type ApiVersionInfo struct {
+	Method         string   `json:"method"`
+	Url            string   `json:"url"`
+	Versions       []string `json:"versions"`
+	DefaultVersion string   `json:"defaultVersion"`
+	Deprecated     []string `json:"deprecated,omitempty"`
+}
+
+/*
+* versionedRoute: all versions of the same method and url
+* Request of url without version prefix is served by version in Accept-Version header or default version
+ */
+type versionedRoute struct {
+	method   string
+	url      string
+	versions []*ApiVersion
+	handlers map[string]func(writer http.ResponseWriter, request *http.Request, optional optionalParams)
+}
+
+var defaultApiVersion string
+var versionedRoutes = map[string]*versionedRoute{}
+var versionedRouteKeys []string
+
+/*
+* Version: create api version
+* @param name: name of version. Ex: v1
+* @param middlewares: middlewares of all apis in version
+* @return *ApiVersion
+ */
+func Version(name string, middlewares ...ApiMiddleware) *ApiVersion {
+	return &ApiVersion{
+		name:        strings.Trim(name, "/"),
+		middlewares: middlewares,
+	}
+}
+
+/*
+* Deprecate: mark version as deprecated, Deprecation and Sunset headers are written in response of its apis
+* @param deprecatedAt: time of deprecation, zero time writes "Deprecation: true"
+* @param sunset: time which apis of version are removed, zero time writes no Sunset header
+* @return *ApiVersion
+ */
+func (version *ApiVersion) Deprecate(deprecatedAt time.Time, sunset time.Time) *ApiVersion {
+	version.deprecated = true
+	version.deprecatedAt = deprecatedAt
+	version.sunset = sunset
+	return version
+}
+
+/*
+* SetDeprecationLink: link of migration document, it is written in Link header of deprecated version
+ */
+func (version *ApiVersion) SetDeprecationLink(link string) *ApiVersion {
+	version.deprecationLink = link
+	return version
+}
+
+/*
+* Use: append middlewares to version
+* Middlewares only apply to apis which are registered after this call
+ */
+func (version *ApiVersion) Use(middlewares ...ApiMiddleware) {
+	version.middlewares = append(version.middlewares, middlewares...)
+}
+
+func (version *ApiVersion) GetName() string {
+	return version.name
+}
+
+func (version *ApiVersion) IsDeprecated() bool {
+	return version.deprecated
+}
+
+/*
+* SetDefaultApiVersion: version which serves request without version prefix and Accept-Version header
+* If a route has no default version, its latest registered version is used
+ */
+func SetDefaultApiVersion(name string) {
+	defaultApiVersion = strings.Trim(name, "/")
+}
+
+/*
+* RegisterVersionedAPI: register api in version
+* Api is served at /{version}{url} and at url with Accept-Version header
+* @param version: version of api
+* @param url: url of api without version prefix
+* @param method: method of api
+* @param handler: handler of api
+* @param middlewares: middlewares of api
+* @return *RouteConfig: optional settings of api
+ */
+func RegisterVersionedAPI[T any](version *ApiVersion, url string, method string, handler Handler[T], middlewares ...ApiMiddleware) *RouteConfig {
+	LogInfo("Register versioned api: %s %s, version = %s", method, url, version.name)
+
+	tType := reflect.TypeOf((*T)(nil)).Elem()
+	if tType.Kind() != reflect.Struct {
+		LogFatal("Handler request parameter must be a struct, got: %s", tType.Kind())
+	}
+
+	config := newRouteConfig()
+	config.version = version
+	routeMiddlewares := make([]ApiMiddleware, 0, len(version.middlewares)+len(middlewares))
+	routeMiddlewares = append(routeMiddlewares, version.middlewares...)
+	routeMiddlewares = append(routeMiddlewares, middlewares...)
+	h := version.handler(newApiHandler(tType, handler, routeMiddlewares, config))
+
+	addRoute("/"+version.name+"/"+strings.TrimPrefix(url, "/"), method, h, config, tType)
+	addVersionedRoute(version, url, method, h)
+	return config
+}
+
+/*
+* RegisterVersionedTypedAPI: register typed api in version
+ */
+func RegisterVersionedTypedAPI[Req any, Res any](version *ApiVersion, url string, method string, handler TypedHandler[Req, Res], middlewares ...ApiMiddleware) *RouteConfig {
+	config := RegisterVersionedAPI(version, url, method, handler.toHandler(), middlewares...)
+	config.responseType = reflect.TypeOf((*Res)(nil)).Elem()
+	return config
+}
+
+/*
+* GetApiVersions: list versions of all versioned routes
+* @return []ApiVersionInfo: sorted by url and method
+ */
+func GetApiVersions() []ApiVersionInfo {
+	result := make([]ApiVersionInfo, 0, len(versionedRouteKeys))
+	for _, key := range versionedRouteKeys {
+		route := versionedRoutes[key]
+		info := ApiVersionInfo{
+			Method:         route.method,
+			Url:            route.url,
+			DefaultVersion: route.defaultVersion().name,
+		}
+		for _, version := range route.versions {
+			info.Versions = append(info.Versions, version.name)
+			if version.deprecated {
+				info.Deprecated = append(info.Deprecated, version.name)
+			}
+		}
+		result = append(result, info)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Url != result[j].Url {
+			return result[i].Url < result[j].Url
+		}
+		return result[i].Method < result[j].Method
+	})
+	return result
+}
+
+/*
+* addVersionedRoute: add handler of version to route of url without version prefix
+* Route is added to router when its first version is registered
+ */
+func addVersionedRoute(version *ApiVersion, url string, method string, handler func(writer http.ResponseWriter, request *http.Request, optional optionalParams)) {
+	key := method + " " + url
+	route, ok := versionedRoutes[key]
+	if !ok {
+		route = &versionedRoute{
+			method:   method,
+			url:      url,
+			handlers: make(map[string]func(writer http.ResponseWriter, request *http.Request, optional optionalParams)),
+		}
+		versionedRoutes[key] = route
+		versionedRouteKeys = append(versionedRouteKeys, key)
+		// Route without version prefix is not shown in api document, each version is shown by its own url
+		addRoute(url, method, route.handle, nil, nil)
+	}
+
+	if _, exist := route.handlers[version.name]; exist {
+		LogFatal("Version %s of api %s is registered twice", version.name, key)
+	}
+	route.versions = append(route.versions, version)
+	route.handlers[version.name] = handler
+}
+
+/*
+* handle: serve request by version in Accept-Version header or default version
+* Unsupported version is answered with 400 and list of supported versions
+ */
+func (route *versionedRoute) handle(writer http.ResponseWriter, request *http.Request, optional optionalParams) {
+	// Headers are written by handler through its context, so they are not lost by timeout writer
+	optional.responseHeader = optional.responseHeader.Clone()
+	if optional.responseHeader == nil {
+		optional.responseHeader = make(http.Header)
+	}
+	optional.responseHeader.Add(VARY_KEY, ACCEPT_VERSION_KEY)
+
+	name := strings.TrimSpace(request.Header.Get(ACCEPT_VERSION_KEY))
+	version := route.defaultVersion()
+	if name != BLANK {
+		version = route.findVersion(name)
+	}
+
+	if version == nil {
+		ctx := getHttpContext()
+		defer putHttpContext(ctx)
+		buildContext(ctx, writer, request)
+		ctx.addRouterHeaders(optional.responseHeader)
+
+		supported := []string{}
+		for _, item := range route.versions {
+			supported = append(supported, item.name)
+		}
+		ctx.LogInfo("Unsupported api version: Url = %s, version = %s", request.URL.String(), name)
+		ctx.writeError(NewHttpError(http.StatusBadRequest, ERROR_CODE_UNSUPPORTED_API_VERSION, "Unsupported api version: "+name, map[string][]string{"versions": supported}))
+		return
+	}
+
+	route.handlers[version.name](writer, request, optional)
+}
+
+/*
+* findVersion: find version by name, "1" matches version "v1"
+ */
+func (route *versionedRoute) findVersion(name string) *ApiVersion {
+	for _, version := range route.versions {
+		if strings.EqualFold(version.name, name) || strings.EqualFold(version.name, "v"+name) {
+			return version
+		}
+	}
+	return nil
+}
+
+func (route *versionedRoute) defaultVersion() *ApiVersion {
+	if defaultApiVersion != BLANK {
+		if version := route.findVersion(defaultApiVersion); version != nil {
+			return version
+		}
+	}
+	return route.versions[len(route.versions)-1]
+}
+
+/*
+* handler: write version and deprecation headers before api handler
+ */
+func (version *ApiVersion) handler(handler func(writer http.ResponseWriter, request *http.Request, optional optionalParams)) func(writer http.ResponseWriter, request *http.Request, optional optionalParams) {
+	return func(writer http.ResponseWriter, request *http.Request, optional optionalParams) {
+		header := optional.responseHeader.Clone()
+		if header == nil {
+			header = make(http.Header)
+		}
+		optional.responseHeader = header
+		header.Set(API_VERSION_KEY, version.name)
+		if version.deprecated {
+			if version.deprecatedAt.IsZero() {
+				header.Set(DEPRECATION_KEY, "true")
+			} else {
+				header.Set(DEPRECATION_KEY, "@"+strconv.FormatInt(version.deprecatedAt.Unix(), 10))
+			}
+			if !version.sunset.IsZero() {
+				header.Set(SUNSET_KEY, version.sunset.UTC().Format(http.TimeFormat))
+			}
+			if version.deprecationLink != BLANK {
+				header.Add(LINK_KEY, "<"+version.deprecationLink+`>; rel="deprecation"`)
+			}
+		}
+		handler(writer, request, optional)
+	}
+}
@@ -0,0 +1,110 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegisterVersionedAPI(t *testing.T) {
+	sunset := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	v1 := Version("v1").Deprecate(time.Time{}, sunset).SetDeprecationLink("https://example.com/migrate")
+	v2 := Version("v2")
+	for _, version := range []*ApiVersion{v1, v2} {
+		name := version.GetName()
+		RegisterVersionedAPI(version, "/test/versioned/users", http.MethodGet, func(ctx *HttpContext, request testDispatchRequest) (HttpResponse, HttpError) {
+			return NewDefaultHttpResponse(name), nil
+		})
+	}
+
+	testCases := []struct {
+		url           string
+		acceptVersion string
+		status        int
+		version       string
+	}{
+		{"/v1/test/versioned/users", "", http.StatusOK, "v1"},
+		{"/v2/test/versioned/users", "", http.StatusOK, "v2"},
+		{"/test/versioned/users", "", http.StatusOK, "v2"},
+		{"/test/versioned/users", "1", http.StatusOK, "v1"},
+		{"/test/versioned/users", "v3", http.StatusBadRequest, ""},
+	}
+
+	for _, testCase := range testCases {
+		request := httptest.NewRequest(http.MethodGet, testCase.url, nil)
+		if testCase.acceptVersion != "" {
+			request.Header.Set(ACCEPT_VERSION_KEY, testCase.acceptVersion)
+		}
+		recorder := httptest.NewRecorder()
+		dispatchRequest(recorder, request)
+
+		if recorder.Code != testCase.status {
+			t.Errorf("%s (%s): expected status %d, got %d", testCase.url, testCase.acceptVersion, testCase.status, recorder.Code)
+			continue
+		}
+		if testCase.version == "" {
+			continue
+		}
+
+		if version := recorder.Header().Get(API_VERSION_KEY); version != testCase.version || !strings.Contains(recorder.Body.String(), `"`+testCase.version+`"`) {
+			t.Errorf("%s (%s): expected version %s, got %s, body = %s", testCase.url, testCase.acceptVersion, testCase.version, version, recorder.Body.String())
+		}
+
+		deprecation := recorder.Header().Get(DEPRECATION_KEY)
+		if testCase.version == "v1" && (deprecation != "true" || recorder.Header().Get(SUNSET_KEY) != "Tue, 01 Jan 2030 00:00:00 GMT") {
+			t.Errorf("%s: expected deprecation headers, got %v", testCase.url, recorder.Header())
+		}
+		if testCase.version == "v2" && deprecation != "" {
+			t.Errorf("%s: expected no deprecation header, got %s", testCase.url, deprecation)
+		}
+	}
+
+	SetDefaultApiVersion("v1")
+	defer SetDefaultApiVersion("")
+	recorder := httptest.NewRecorder()
+	dispatchRequest(recorder, httptest.NewRequest(http.MethodGet, "/test/versioned/users", nil))
+	if version := recorder.Header().Get(API_VERSION_KEY); version != "v1" {
+		t.Errorf("Expected default version v1, got %s", version)
+	}
+
+	found := false
+	for _, info := range GetApiVersions() {
+		if info.Url == "/test/versioned/users" {
+			found = true
+			if strings.Join(info.Versions, ",") != "v1,v2" || info.DefaultVersion != "v1" || strings.Join(info.Deprecated, ",") != "v1" {
+				t.Errorf("Expected versions v1,v2 with default v1, got %+v", info)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Expected /test/versioned/users in versions listing")
+	}
+}
+
+func TestVersionedAPI_VaryHeader(t *testing.T) {
+	for _, version := range []*ApiVersion{Version("v1"), Version("v2")} {
+		RegisterVersionedAPI(version, "/test/versioned/vary", http.MethodGet, testDispatchHandler)
+	}
+
+	for _, acceptVersion := range []string{"", "v1", "v3"} {
+		request := httptest.NewRequest(http.MethodGet, "/test/versioned/vary", nil)
+		if acceptVersion != "" {
+			request.Header.Set(ACCEPT_VERSION_KEY, acceptVersion)
+		}
+		recorder := httptest.NewRecorder()
+		dispatchRequest(recorder, request)
+
+		vary := []string{}
+		for _, value := range recorder.Header().Values(VARY_KEY) {
+			for _, key := range strings.Split(value, ",") {
+				vary = append(vary, strings.TrimSpace(key))
+			}
+		}
+		if !slices.Contains(vary, ACCEPT_KEY) || !slices.Contains(vary, ACCEPT_VERSION_KEY) {
+			t.Errorf("Accept-Version %q: expected Vary with Accept and Accept-Version, got %v", acceptVersion, recorder.Header().Values(VARY_KEY))
+		}
+	}
+}
@@ -16,6 +16,7 @@ type websocketRoute struct {
 var websocketUpgrader = websocket.Upgrader{
 	ReadBufferSize:  MAX_WEBSOCKET_READ_BUFFER_SIZE,
 	WriteBufferSize: MAX_WEBSOCKET_WRITE_BUFFER_SIZE,
+	CheckOrigin:     checkWebsocketOrigin,
 }
 
 type WebsocketResponse struct {
@@ -30,6 +31,16 @@ type WebsocketMiddleware func(ctx WebsocketContext, w http.ResponseWriter, r *ht
 type WebsocketHandler[T any] func(ctx WebsocketContext, data T) (*WebsocketResponse, Error)
 
 func RegisterWebsocket[T any](url string, handler WebsocketHandler[T], middlewares ...WebsocketMiddleware) {
+	registerWebsocket(url, nil, handler, middlewares...)
+}
+
+/*
+* registerWebsocket: register websocket to router
+* apiMiddlewares are executed at handshake before websocket middlewares (used by route group)
+* Common api middlewares are executed before them, the same as api, upload and sse
+* Websocket which is not in group has nil apiMiddlewares and no api middleware at handshake
+ */
+func registerWebsocket[T any](url string, apiMiddlewares []ApiMiddleware, handler WebsocketHandler[T], middlewares ...WebsocketMiddleware) {
 	LogInfo("Register Websocket: %s", url)
 
 	// Check if T is a struct
@@ -42,6 +53,37 @@ func RegisterWebsocket[T any](url string, handler WebsocketHandler[T], middlewar
 		// Get context
 		ctx := getWebsocketContext()
 		defer putWebsocketContext(ctx)
+		var connection *websocket.Conn
+		defer recoverWebsocketPanic(ctx, w, r, &connection)
+
+		// Run api middlewares at handshake
+		if apiMiddlewares != nil {
+			middlewareList := []ApiMiddleware{}
+			middlewareList = append(middlewareList, commonApiMiddlewares...)
+			middlewareList = append(middlewareList, apiMiddlewares...)
+
+			handshakeContext := getHttpContext()
+			if err := buildContext(handshakeContext, w, r); err != nil {
+				handshakeContext.writeError(err)
+				putHttpContext(handshakeContext)
+				return
+			}
+			handshakeContext.requestID = ctx.GetContextID()
+			isRequestEnd := executeApiMiddlewares(handshakeContext, middlewareList)
+			if !isRequestEnd {
+				// Headers of middlewares are written into w, they are sent with upgrade response
+				handshakeContext.setResponseHeaders()
+				handshakeContext.commitSession()
+			}
+			// Data which is set by middlewares is kept for websocket handler
+			for key, value := range handshakeContext.tempData {
+				ctx.SetTempData(key, value)
+			}
+			putHttpContext(handshakeContext)
+			if isRequestEnd {
+				return
+			}
+		}
 
 		// Run middlewares
 		for _, middleware := range middlewares {
@@ -57,11 +99,15 @@ func RegisterWebsocket[T any](url string, handler WebsocketHandler[T], middlewar
 			}
 		}
 
-		connection, err := websocketUpgrader.Upgrade(w, r, nil)
+		var err error
+		// Upgrader writes response itself, so headers of middlewares (cors, cookie, ...) are passed to it
+		connection, err = websocketUpgrader.Upgrade(w, r, w.Header().Clone())
 		if err != nil {
 			ctx.LogError("websocket upgrade failed: %v", err)
 			return
 		}
+		trackWebsocketConnection(connection)
+		defer untrackWebsocketConnection(connection)
 
 		for {
 			// Read a message
@@ -115,5 +161,5 @@ func RegisterWebsocket[T any](url string, handler WebsocketHandler[T], middlewar
 		}
 	}
 
-	websocketRouteMap[url] = websocketRoute{url: url, handler: h}
+	router.insert(url).websocket = &websocketRoute{url: url, handler: h}
 }